@@ -10,28 +10,38 @@ import (
 )
 
 type Writer[T any] struct {
-	DB             *gocql.ClusterConfig
+	DB             q.SessionProvider
 	Table          string
 	Schema         *q.Schema
 	JsonColumnMap  map[string]string
 	versionField   string
 	versionIndex   int
 	versionDBField string
+	Options        *q.QueryOptions
 }
 
 func NewWriter[T any](db *gocql.ClusterConfig, tableName string) (*Writer[T], error) {
 	return NewWriterWithVersion[T](db, tableName, "")
 }
 func NewWriterWithVersion[T any](db *gocql.ClusterConfig, tableName string, versionField string) (*Writer[T], error) {
+	return NewWriterWithVersionAndProvider[T](q.GetSessionProvider(db), tableName, versionField)
+}
+func NewWriterWithProvider[T any](db q.SessionProvider, tableName string) (*Writer[T], error) {
+	return NewWriterWithVersionAndProvider[T](db, tableName, "")
+}
+func NewWriterWithVersionAndProvider[T any](db q.SessionProvider, tableName string, versionField string) (*Writer[T], error) {
 	var t T
 	modelType := reflect.TypeOf(t)
 	if modelType.Kind() == reflect.Ptr {
 		modelType = modelType.Elem()
 	}
-	schema := q.CreateSchema(modelType)
+	if err := q.CheckSchema(db, tableName, modelType); err != nil {
+		return nil, err
+	}
+	schema := q.GetSchema(modelType)
 	jsonColumnMapT := q.MakeJsonColumnMap(modelType)
 	jsonColumnMap := q.GetWritableColumns(schema.Fields, jsonColumnMapT)
-	adapter := &Writer[T]{DB: db, Table: tableName, Schema: schema, JsonColumnMap: jsonColumnMap, versionField: "", versionIndex: -1}
+	adapter := &Writer[T]{DB: db, Options: q.GetDefaultOptions(db), Table: tableName, Schema: schema, JsonColumnMap: jsonColumnMap, versionField: "", versionIndex: -1}
 	if len(versionField) > 0 {
 		index := q.FindFieldIndex(modelType, versionField)
 		if index >= 0 {
@@ -48,55 +58,59 @@ func NewWriterWithVersion[T any](db *gocql.ClusterConfig, tableName string, vers
 }
 
 func (a *Writer[T]) Create(ctx context.Context, model T) (int64, error) {
-	query, args := q.BuildToInsertWithVersion(a.Table, model, a.versionIndex, false, a.Schema)
-	ses, err := a.DB.CreateSession()
+	ctx = q.WithDefaultOptions(ctx, a.Options)
+	query, args := q.BuildToInsertWithUsing(a.Table, model, a.versionIndex, false, q.GetUsing(ctx, a.Schema), a.Schema)
+	ses, err := a.DB.Session()
 	if err != nil {
 		return -1, err
 	}
-	defer ses.Close()
-	er2 := q.Exec(ses, query, args...)
+	if a.versionIndex >= 0 {
+		return q.ExecuteWithVersion(ctx, ses, query, args...)
+	}
+	er2 := q.ExecContext(ctx, ses, query, args...)
 	if er2 != nil {
 		return 0, er2
 	}
 	return 1, nil
 }
 func (a *Writer[T]) Update(ctx context.Context, model T) (int64, error) {
-	query, args := q.BuildToUpdateWithVersion(a.Table, model, a.versionIndex, a.Schema)
-	ses, err := a.DB.CreateSession()
+	ctx = q.WithDefaultOptions(ctx, a.Options)
+	query, args := q.BuildToUpdateWithUsing(a.Table, model, a.versionIndex, q.GetUsing(ctx, a.Schema), a.Schema)
+	ses, err := a.DB.Session()
 	if err != nil {
 		return -1, err
 	}
-	defer ses.Close()
-	er2 := q.Exec(ses, query, args...)
+	if a.versionIndex >= 0 {
+		return q.ExecuteWithVersion(ctx, ses, query, args...)
+	}
+	er2 := q.ExecContext(ctx, ses, query, args...)
 	if er2 != nil {
 		return 0, er2
 	}
 	return 1, nil
 }
 func (a *Writer[T]) Save(ctx context.Context, model T) (int64, error) {
-	query, args := q.BuildToInsertWithVersion(a.Table, model, a.versionIndex, true, a.Schema)
-	ses, err := a.DB.CreateSession()
+	ctx = q.WithDefaultOptions(ctx, a.Options)
+	query, args := q.BuildToInsertWithUsing(a.Table, model, a.versionIndex, true, q.GetUsing(ctx, a.Schema), a.Schema)
+	ses, err := a.DB.Session()
 	if err != nil {
 		return -1, err
 	}
-	defer ses.Close()
-	er2 := q.Exec(ses, query, args...)
+	er2 := q.ExecContext(ctx, ses, query, args...)
 	if er2 != nil {
 		return 0, er2
 	}
 	return 1, nil
 }
+
+// Patch updates the columns of the model. The list, set and map columns can be patched by the operators $append, $prepend, $remove, $put and $delete, see q.CollectionOp.
 func (a *Writer[T]) Patch(ctx context.Context, model map[string]interface{}) (int64, error) {
 	dbColumnMap := q.JSONToColumns(model, a.JsonColumnMap)
-	query, values := q.BuildToPatchWithVersion(a.Table, dbColumnMap, a.Schema.SKeys, a.versionDBField)
-	ses, err := a.DB.CreateSession()
+	ctx = q.WithDefaultOptions(ctx, a.Options)
+	stmts := q.BuildToPatchStatements(a.Table, dbColumnMap, a.Schema.SKeys, a.versionDBField, q.GetUsing(ctx, a.Schema))
+	ses, err := a.DB.Session()
 	if err != nil {
 		return -1, err
 	}
-	defer ses.Close()
-	er2 := q.Exec(ses, query, values...)
-	if er2 == nil {
-		return 1, er2
-	}
-	return 0, er2
+	return q.ExecutePatch(ctx, ses, q.HasVersion(dbColumnMap, a.versionDBField), stmts...)
 }
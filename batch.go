@@ -43,7 +43,7 @@ func BuildToInsertBatchWithVersion(table string, models interface{}, versionInde
 	} else {
 		first := s.Index(0).Interface()
 		modelType := reflect.TypeOf(first)
-		strt = CreateSchema(modelType)
+		strt = GetSchema(modelType)
 	}
 	slen := s.Len()
 	stmts := make([]Statement, 0)
@@ -74,12 +74,17 @@ func BuildToUpdateBatchWithVersion(table string, models interface{}, versionInde
 	} else {
 		first := s.Index(0).Interface()
 		modelType := reflect.TypeOf(first)
-		strt = CreateSchema(modelType)
+		strt = GetSchema(modelType)
 	}
 	stmts := make([]Statement, 0)
 	for j := 0; j < slen; j++ {
 		model := s.Index(j).Interface()
 		// mv := reflect.ValueOf(model)
+		if versionIndex >= 0 {
+			if _, err := GetModelVersion(model, versionIndex); err != nil {
+				return nil, err
+			}
+		}
 		query, args := BuildToUpdateWithVersion(table, model, versionIndex, strt)
 		s := Statement{Query: query, Params: args}
 		stmts = append(stmts, s)
@@ -0,0 +1,329 @@
+package batch
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	c "github.com/core-go/cassandra"
+)
+
+var ErrWriterClosed = errors.New("writer is closed")
+
+// Writer is implemented by BatchWriter, BatchInserter and BatchUpdater. AsyncWriter maps the models once by MapModels,
+// then writes and retries them by WriteMapped, so that the models are not mapped again by the retries,
+// and hands the models which still fail after the retries to the DeadLetter of GetDeadLetter once.
+type Writer[T any] interface {
+	MapModels(models []T)
+	WriteMapped(ctx context.Context, models []T) (*c.BatchResult, error)
+	GetDeadLetter() DeadLetter[T]
+}
+
+// AsyncConfig is the config of AsyncWriter. The zero values are replaced by the defaults of NewAsyncWriter.
+type AsyncConfig struct {
+	// Size is the number of the buffered models which triggers a flush.
+	Size int
+	// MaxBytes is the estimated size of the buffered models which triggers a flush, 0 to flush by Size and Interval only.
+	MaxBytes int
+	// Interval is the max time a model stays in the buffer.
+	Interval time.Duration
+	// Capacity is the number of the models which can be added while the buffer is flushed, Add blocks when it is full.
+	Capacity int
+	// Retries is the number of the retries of the failed models, 0 for no retry.
+	Retries int
+	// RetryDelay is the delay of the first retry, doubled for each next retry up to MaxRetryDelay.
+	RetryDelay    time.Duration
+	MaxRetryDelay time.Duration
+}
+
+// AsyncWriter buffers the models added by Add or Consume, and writes them by the writer in the background,
+// when the buffer reaches Size or MaxBytes, or every Interval. The failed models are retried with backoff,
+// then handed once to the DeadLetter of the writer. If the writer has no DeadLetter or it fails, they are handed to OnError,
+// which must be set before the first Add, and the error is returned by the next Flush or Close.
+//
+//	w := batch.NewAsyncWriter[User](batch.NewBatchWriter[User](cluster, "users"), batch.AsyncConfig{Size: 500, Interval: time.Second})
+//	defer w.Close(ctx)
+//	err := w.Add(ctx, user)
+type AsyncWriter[T any] struct {
+	writer  Writer[T]
+	config  AsyncConfig
+	Size    func(T) int
+	OnError func(ctx context.Context, models []T, err error)
+	items   chan T
+	flushes chan chan error
+	closing chan struct{}
+	stop    chan struct{}
+	done    chan struct{}
+	ctx     context.Context
+	cancel  context.CancelFunc
+	mu      sync.RWMutex
+	closed  bool
+	once    sync.Once
+	err     error
+}
+
+func NewAsyncWriter[T any](writer Writer[T], options ...AsyncConfig) *AsyncWriter[T] {
+	var config AsyncConfig
+	if len(options) > 0 {
+		config = options[0]
+	}
+	if config.Size <= 0 {
+		config.Size = 100
+	}
+	if config.Interval <= 0 {
+		config.Interval = time.Second
+	}
+	if config.Capacity <= 0 {
+		config.Capacity = config.Size
+	}
+	if config.Retries < 0 {
+		config.Retries = 0
+	}
+	if config.RetryDelay <= 0 {
+		config.RetryDelay = 100 * time.Millisecond
+	}
+	if config.MaxRetryDelay <= 0 {
+		config.MaxRetryDelay = 10 * time.Second
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	w := &AsyncWriter[T]{
+		writer:  writer,
+		config:  config,
+		items:   make(chan T, config.Capacity),
+		flushes: make(chan chan error),
+		closing: make(chan struct{}),
+		stop:    make(chan struct{}),
+		done:    make(chan struct{}),
+		ctx:     ctx,
+		cancel:  cancel,
+	}
+	go w.run()
+	return w
+}
+
+// Add adds the model to the buffer. It blocks while the buffer is flushed and Capacity models are already waiting,
+// until the model is accepted, ctx is done or the writer is closed.
+func (w *AsyncWriter[T]) Add(ctx context.Context, model T) error {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	if w.closed {
+		return ErrWriterClosed
+	}
+	select {
+	case w.items <- model:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-w.closing:
+		return ErrWriterClosed
+	}
+}
+
+// Consume adds the models received from ch, until ch is closed, ctx is done or the writer is closed.
+func (w *AsyncWriter[T]) Consume(ctx context.Context, ch <-chan T) error {
+	for {
+		select {
+		case model, ok := <-ch:
+			if !ok {
+				return nil
+			}
+			if err := w.Add(ctx, model); err != nil {
+				return err
+			}
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// Flush writes the models added before, and returns the first error of the models which still fail after the retries,
+// of this flush or of the flushes triggered by Size, MaxBytes and Interval since the previous Flush.
+func (w *AsyncWriter[T]) Flush(ctx context.Context) error {
+	res := make(chan error, 1)
+	select {
+	case w.flushes <- res:
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-w.done:
+		return ErrWriterClosed
+	}
+	select {
+	case err := <-res:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Close stops accepting the models, writes the buffered models and waits until they are written.
+// It returns the first error of the writes not returned by Flush.
+// If ctx is done before, the pending retries are canceled and Close returns the error of ctx.
+func (w *AsyncWriter[T]) Close(ctx context.Context) error {
+	w.once.Do(func() {
+		close(w.closing)
+		w.mu.Lock()
+		w.closed = true
+		w.mu.Unlock()
+		close(w.stop)
+	})
+	select {
+	case <-w.done:
+		return w.err
+	case <-ctx.Done():
+		w.cancel()
+		return ctx.Err()
+	}
+}
+func (w *AsyncWriter[T]) run() {
+	defer close(w.done)
+	defer w.cancel()
+	ticker := time.NewTicker(w.config.Interval)
+	defer ticker.Stop()
+	buffer := make([]T, 0, w.config.Size)
+	bytes := 0
+	// failure is the first error of the writes, until it is returned by Flush or Close
+	var failure error
+	flush := func() {
+		if len(buffer) == 0 {
+			return
+		}
+		err := w.write(buffer)
+		buffer = make([]T, 0, w.config.Size)
+		bytes = 0
+		if err != nil && failure == nil {
+			failure = err
+		}
+	}
+	add := func(model T) {
+		buffer = append(buffer, model)
+		if w.config.MaxBytes > 0 {
+			bytes = bytes + w.size(model)
+		}
+		if len(buffer) >= w.config.Size || (w.config.MaxBytes > 0 && bytes >= w.config.MaxBytes) {
+			flush()
+		}
+	}
+	drain := func() {
+		for {
+			select {
+			case model := <-w.items:
+				add(model)
+			default:
+				return
+			}
+		}
+	}
+	for {
+		select {
+		case model := <-w.items:
+			add(model)
+		case <-ticker.C:
+			flush()
+		case res := <-w.flushes:
+			drain()
+			flush()
+			res <- failure
+			failure = nil
+		case <-w.stop:
+			drain()
+			flush()
+			w.err = failure
+			return
+		}
+	}
+}
+func (w *AsyncWriter[T]) size(model T) int {
+	if w.Size != nil {
+		return w.Size(model)
+	}
+	return c.ModelSize(model)
+}
+
+// write writes the models, and retries the failed and skipped models with backoff, except the models not applied by the lightweight transactions.
+// The models which are not written after the retries are handed to the DeadLetter of the writer, the index of a failure is its index in models.
+// It returns nil if the DeadLetter handles them, otherwise the models are handed to OnError, and the error of the write or of the DeadLetter is returned.
+func (w *AsyncWriter[T]) write(models []T) error {
+	ctx := w.ctx
+	delay := w.config.RetryDelay
+	var rejected []Failure[T]
+	var failure error
+	w.writer.MapModels(models)
+	pending := make([]Failure[T], len(models))
+	for i := range models {
+		pending[i] = Failure[T]{Index: i, Model: models[i]}
+	}
+	for attempt := 0; ; attempt++ {
+		result, err := w.writer.WriteMapped(ctx, modelsOf(pending))
+		if err == nil {
+			pending = nil
+			break
+		}
+		failure = err
+		if result != nil {
+			var conflicts []Failure[T]
+			pending, conflicts = splitFailures(pending, result, err)
+			rejected = append(rejected, conflicts...)
+		} else {
+			for i := range pending {
+				pending[i].Err = err
+			}
+		}
+		if len(pending) == 0 || attempt >= w.config.Retries || ctx.Err() != nil {
+			break
+		}
+		select {
+		case <-ctx.Done():
+		case <-time.After(delay):
+		}
+		delay = delay * 2
+		if delay > w.config.MaxRetryDelay {
+			delay = w.config.MaxRetryDelay
+		}
+	}
+	rejected = append(rejected, pending...)
+	if len(rejected) == 0 {
+		return nil
+	}
+	if deadLetter := w.writer.GetDeadLetter(); deadLetter != nil {
+		er2 := deadLetter(ctx, rejected)
+		if er2 == nil {
+			return nil
+		}
+		failure = er2
+	}
+	if w.OnError != nil {
+		w.OnError(ctx, modelsOf(rejected), failure)
+	}
+	return failure
+}
+
+// splitFailures returns the failed and skipped models to retry, and the models not applied by the lightweight transactions,
+// with their errors; the skipped models have the error of the write.
+func splitFailures[T any](pending []Failure[T], result *c.BatchResult, err error) ([]Failure[T], []Failure[T]) {
+	retries := make([]Failure[T], 0, len(result.Failed)+len(result.Skipped))
+	var conflicts []Failure[T]
+	for _, f := range result.Failed {
+		p := pending[f.Index]
+		p.Err = f.Err
+		var conflict *c.ConflictError
+		if errors.As(f.Err, &conflict) {
+			conflicts = append(conflicts, p)
+		} else {
+			retries = append(retries, p)
+		}
+	}
+	for _, i := range result.Skipped {
+		p := pending[i]
+		p.Err = err
+		retries = append(retries, p)
+	}
+	return retries, conflicts
+}
+func modelsOf[T any](failures []Failure[T]) []T {
+	models := make([]T, len(failures))
+	for i, f := range failures {
+		models[i] = f.Model
+	}
+	return models
+}
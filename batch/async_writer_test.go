@@ -0,0 +1,208 @@
+package batch_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/core-go/cassandra/batch"
+	"github.com/core-go/cassandra/cqltest"
+)
+
+type user struct {
+	Id   string `cql:"id,partition_key"`
+	Name string `cql:"name"`
+}
+
+func TestAsyncWriterMapsOnce(t *testing.T) {
+	tests := []struct {
+		name    string
+		fail    bool
+		retries int
+		writes  int
+	}{
+		{"written", false, 2, 1},
+		{"retried", true, 2, 3},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ses := cqltest.NewSession()
+			if tt.fail {
+				ses.Fail("insert into users", errors.New("timeout"))
+			}
+			var mu sync.Mutex
+			calls := 0
+			writer := batch.NewBatchWriterWithProvider[user](ses.Provider(), "users", func(u *user) {
+				mu.Lock()
+				calls++
+				mu.Unlock()
+				u.Name = u.Name + "!"
+			})
+			w := batch.NewAsyncWriter[user](writer, batch.AsyncConfig{Size: 10, Interval: time.Hour, Retries: tt.retries, RetryDelay: time.Millisecond})
+			var rejected []user
+			w.OnError = func(ctx context.Context, models []user, err error) {
+				rejected = append(rejected, models...)
+			}
+			ctx := context.Background()
+			for _, id := range []string{"1", "2"} {
+				if err := w.Add(ctx, user{Id: id, Name: "a"}); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if err := w.Close(ctx); (err != nil) != tt.fail {
+				t.Fatalf("Close() error = %v", err)
+			}
+			if calls != 2 {
+				t.Fatalf("Map called %d times, want once per model", calls)
+			}
+			executed := ses.Executed()
+			if len(executed) < tt.writes {
+				t.Fatalf("%d statements executed, want %d writes at least", len(executed), tt.writes)
+			}
+			for _, e := range executed {
+				if e.Params[len(e.Params)-1] != "a!" && e.Params[0] != "a!" {
+					t.Fatalf("statement %q written with %v", e.Query, e.Params)
+				}
+			}
+			if tt.fail && len(rejected) != 2 {
+				t.Fatalf("%d models rejected, want 2", len(rejected))
+			}
+			for _, u := range rejected {
+				if u.Name != "a!" {
+					t.Fatalf("rejected model %+v", u)
+				}
+			}
+		})
+	}
+}
+
+func TestAsyncWriterErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		config batch.AsyncConfig
+		models int
+		close  bool
+	}{
+		{"flush by size", batch.AsyncConfig{Size: 2}, 2, false},
+		{"flush by bytes", batch.AsyncConfig{Size: 100, MaxBytes: 1}, 1, false},
+		{"flush by size then close", batch.AsyncConfig{Size: 2}, 2, true},
+		{"close", batch.AsyncConfig{Size: 100}, 1, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ses := cqltest.NewSession().Fail("insert into users", errors.New("timeout"))
+			tt.config.Interval = time.Hour
+			w := batch.NewAsyncWriter[user](batch.NewBatchWriterWithProvider[user](ses.Provider(), "users", nil), tt.config)
+			ctx := context.Background()
+			for i := 0; i < tt.models; i++ {
+				if err := w.Add(ctx, user{Id: string(rune('a' + i))}); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if tt.close {
+				if err := w.Close(ctx); err == nil {
+					t.Fatal("Close() must return the error of the write")
+				}
+				return
+			}
+			if err := w.Flush(ctx); err == nil {
+				t.Fatal("Flush() must return the error of the write")
+			}
+			if err := w.Flush(ctx); err != nil {
+				t.Fatalf("Flush() returns the error again: %v", err)
+			}
+			if err := w.Close(ctx); err != nil {
+				t.Fatalf("Close() returns the error again: %v", err)
+			}
+		})
+	}
+}
+
+func TestAsyncWriterRetries(t *testing.T) {
+	tests := []struct {
+		name    string
+		retries int
+		writes  int
+	}{
+		{"no retry", 0, 1},
+		{"negative", -1, 1},
+		{"retries", 2, 3},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ses := cqltest.NewSession().Fail("insert into users", errors.New("timeout"))
+			w := batch.NewAsyncWriter[user](batch.NewBatchWriterWithProvider[user](ses.Provider(), "users", nil), batch.AsyncConfig{Retries: tt.retries, RetryDelay: time.Millisecond})
+			ctx := context.Background()
+			if err := w.Add(ctx, user{Id: "1"}); err != nil {
+				t.Fatal(err)
+			}
+			if err := w.Close(ctx); err == nil {
+				t.Fatal("Close() must return the error of the write")
+			}
+			if n := len(ses.Executed()); n != tt.writes {
+				t.Fatalf("%d writes, want %d", n, tt.writes)
+			}
+		})
+	}
+}
+
+func TestAsyncWriterDeadLetter(t *testing.T) {
+	broken := errors.New("dead letter queue is down")
+	tests := []struct {
+		name       string
+		retries    int
+		deadLetter error
+		err        error
+		onError    int
+	}{
+		{"handled after the retries", 2, nil, nil, 0},
+		{"handled without retry", 0, nil, nil, 0},
+		{"error of the dead letter", 2, broken, broken, 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ses := cqltest.NewSession().Fail("insert into users", errors.New("timeout"))
+			writer := batch.NewBatchWriterWithProvider[user](ses.Provider(), "users", nil)
+			writer.Config.ContinueOnError = true
+			var calls int
+			var failures []batch.Failure[user]
+			writer.DeadLetter = func(ctx context.Context, f []batch.Failure[user]) error {
+				calls++
+				failures = append(failures, f...)
+				return tt.deadLetter
+			}
+			w := batch.NewAsyncWriter[user](writer, batch.AsyncConfig{Size: 10, Interval: time.Hour, Retries: tt.retries, RetryDelay: time.Millisecond})
+			var rejected []user
+			var reported error
+			w.OnError = func(ctx context.Context, models []user, err error) {
+				rejected = append(rejected, models...)
+				reported = err
+			}
+			ctx := context.Background()
+			for _, id := range []string{"1", "2"} {
+				if err := w.Add(ctx, user{Id: id}); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if err := w.Close(ctx); !errors.Is(err, tt.err) {
+				t.Fatalf("Close() error = %v, want %v", err, tt.err)
+			}
+			if calls != 1 || len(failures) != 2 {
+				t.Fatalf("DeadLetter called %d times with %d failures, want once with 2", calls, len(failures))
+			}
+			for i, f := range failures {
+				if f.Index != i || f.Model.Id != []string{"1", "2"}[i] || f.Err == nil {
+					t.Fatalf("failure %d = %+v", i, f)
+				}
+			}
+			if n := len(ses.Executed()); n != 2*(tt.retries+1) {
+				t.Fatalf("%d writes, want the 2 models written %d times", n, tt.retries+1)
+			}
+			if len(rejected) != tt.onError || (tt.onError > 0 && !errors.Is(reported, tt.err)) {
+				t.Fatalf("OnError got %d models and %v, want %d and %v", len(rejected), reported, tt.onError, tt.err)
+			}
+		})
+	}
+}
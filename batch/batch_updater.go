@@ -9,11 +9,13 @@ import (
 )
 
 type BatchUpdater[T any] struct {
-	db           *gocql.ClusterConfig
+	db           c.SessionProvider
 	table        string
 	Map          func(*T)
 	VersionIndex int
 	Schema       *c.Schema
+	Config       c.BatchConfig
+	DeadLetter   DeadLetter[T]
 }
 
 func NewBatchUpdater[T any](session *gocql.ClusterConfig, table string, options ...func(*T)) *BatchUpdater[T] {
@@ -24,6 +26,9 @@ func NewBatchUpdater[T any](session *gocql.ClusterConfig, table string, options
 	return NewBatchUpdaterWithVersion[T](session, table, mp)
 }
 func NewBatchUpdaterWithVersion[T any](session *gocql.ClusterConfig, table string, mp func(*T), options ...int) *BatchUpdater[T] {
+	return NewBatchUpdaterWithProvider[T](c.GetSessionProvider(session), table, mp, options...)
+}
+func NewBatchUpdaterWithProvider[T any](db c.SessionProvider, table string, mp func(*T), options ...int) *BatchUpdater[T] {
 	var t T
 	modelType := reflect.TypeOf(t)
 	if modelType.Kind() != reflect.Struct {
@@ -33,24 +38,50 @@ func NewBatchUpdaterWithVersion[T any](session *gocql.ClusterConfig, table strin
 	if len(options) > 0 && options[0] >= 0 {
 		versionIndex = options[0]
 	}
-	schema := c.CreateSchema(modelType)
-	return &BatchUpdater[T]{db: session, table: table, Schema: schema, VersionIndex: versionIndex, Map: mp}
+	schema := c.GetSchema(modelType)
+	return &BatchUpdater[T]{db: db, table: table, Schema: schema, VersionIndex: versionIndex, Map: mp, Config: c.DefaultBatchConfig}
 }
 func (w *BatchUpdater[T]) Write(ctx context.Context, models []T) error {
-	l := len(models)
-	if l == 0 {
-		return nil
-	}
+	_, err := w.WriteWithResult(ctx, models)
+	return err
+}
+
+// WriteWithResult maps the models by Map, writes them and returns the result by index of the models.
+// The failed models are handed to DeadLetter if it is set, then the error is returned only if DeadLetter fails or some models are skipped.
+func (w *BatchUpdater[T]) WriteWithResult(ctx context.Context, models []T) (*c.BatchResult, error) {
+	w.MapModels(models)
+	return w.WriteMappedWithResult(ctx, models)
+}
+
+// MapModels applies Map to the models.
+func (w *BatchUpdater[T]) MapModels(models []T) {
 	if w.Map != nil {
-		for i := 0; i < l; i++ {
+		for i := range models {
 			w.Map(&models[i])
 		}
 	}
-	session, er0 := w.db.CreateSession()
+}
+
+// WriteMappedWithResult is WriteWithResult for the models already mapped by MapModels.
+func (w *BatchUpdater[T]) WriteMappedWithResult(ctx context.Context, models []T) (*c.BatchResult, error) {
+	result, err := w.WriteMapped(ctx, models)
+	return result, HandleFailures(ctx, w.DeadLetter, models, result, err)
+}
+
+// WriteMapped writes the models already mapped by MapModels, without handing the failed models to DeadLetter,
+// so that AsyncWriter retries them, and hands them to DeadLetter once.
+func (w *BatchUpdater[T]) WriteMapped(ctx context.Context, models []T) (*c.BatchResult, error) {
+	if len(models) == 0 {
+		return &c.BatchResult{}, nil
+	}
+	session, er0 := w.db.Session()
 	if er0 != nil {
-		return er0
+		return nil, er0
 	}
-	defer session.Close()
-	_, err := c.UpdateBatchWithSizeAndVersion(ctx, session, l, w.table, models, w.VersionIndex, w.Schema)
-	return err
+	return c.UpdateBatchWithResult(ctx, session, w.Config, w.table, models, w.VersionIndex, w.Schema)
+}
+
+// GetDeadLetter returns DeadLetter.
+func (w *BatchUpdater[T]) GetDeadLetter() DeadLetter[T] {
+	return w.DeadLetter
 }
@@ -0,0 +1,37 @@
+package batch
+
+import (
+	"context"
+
+	c "github.com/core-go/cassandra"
+)
+
+// Failure is a model which failed to be written, Index is its index in the models given to Write.
+type Failure[T any] struct {
+	Index int
+	Model T
+	Err   error
+}
+
+// DeadLetter receives the failed models of a write, to store them for a later retry, such as in another table or a queue.
+// It is usually used with Config.ContinueOnError, so that the other models are written.
+type DeadLetter[T any] func(ctx context.Context, failures []Failure[T]) error
+
+// HandleFailures hands the failed models of the result to the dead letter handler.
+// It returns nil if the failed models are handled and no model is skipped, otherwise it returns err or the error of the handler.
+func HandleFailures[T any](ctx context.Context, deadLetter DeadLetter[T], models []T, result *c.BatchResult, err error) error {
+	if err == nil || deadLetter == nil || result == nil || len(result.Failed) == 0 {
+		return err
+	}
+	failures := make([]Failure[T], 0, len(result.Failed))
+	for _, f := range result.Failed {
+		failures = append(failures, Failure[T]{Index: f.Index, Model: models[f.Index], Err: f.Err})
+	}
+	if er2 := deadLetter(ctx, failures); er2 != nil {
+		return er2
+	}
+	if len(result.Skipped) > 0 {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,352 @@
+package cassandra
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sync/atomic"
+	"time"
+
+	"github.com/apache/cassandra-gocql-driver"
+)
+
+// BatchConfig limits the batches of ExecuteAllWithConfig.
+type BatchConfig struct {
+	// Size is the max number of statements of a batch.
+	Size int
+	// MaxBytes is the max estimated size of the values of a batch, keep it under batch_size_warn_threshold_in_kb of the cluster, 5KB by default.
+	MaxBytes int
+	// Concurrency is the number of the batches executed at the same time, GetConcurrency of the context if it is not set.
+	Concurrency int
+	// Logged executes the statements of different partitions in logged batches, so that they are applied all or none.
+	// The batches of a single partition are always unlogged, because they are already atomic.
+	Logged bool
+	// ContinueOnError executes the other batches if a batch fails, otherwise the batches not started yet are skipped.
+	ContinueOnError bool
+}
+
+// DefaultBatchConfig is the config of InsertBatch, UpdateBatch, SaveBatch and the writers of the batch package.
+var DefaultBatchConfig = BatchConfig{Size: 50, MaxBytes: 5 * 1024}
+
+// WithSize returns the config with the max number of statements of a batch, the config itself if size is not positive.
+func (c BatchConfig) WithSize(size int) BatchConfig {
+	if size > 0 {
+		c.Size = size
+	}
+	return c
+}
+
+// StatementBatch is a batch built by GroupStatements. Partition is empty if the statements are of different or unknown partitions.
+// Indexes are the indexes of the statements in the statements given to GroupStatements.
+type StatementBatch struct {
+	Type       gocql.BatchType
+	Partition  string
+	Statements []Statement
+	Indexes    []int
+}
+
+// BatchResult is the result of a batch write, by index of the statements, which are the indexes of the models for the model writes.
+type BatchResult struct {
+	Succeeded []int
+	Failed    []BatchFailure
+	// Skipped are the statements not executed, because a batch failed and ContinueOnError is not set, or the context is done.
+	Skipped []int
+}
+type BatchFailure struct {
+	Index int
+	Err   error
+}
+
+// Count returns the number of the succeeded statements.
+func (r *BatchResult) Count() int64 {
+	return int64(len(r.Succeeded))
+}
+
+// GroupStatements groups the statements by partition, in the order of the first statement of each partition,
+// and splits the groups by the size and the bytes of the config.
+// partitions is aligned with stmts, the statements without partition are grouped together, as statements of different partitions.
+// The batches of a single partition are unlogged; the others are typ, or logged if config.Logged is set. The counter batches keep their type.
+func GroupStatements(typ gocql.BatchType, config BatchConfig, stmts []Statement, partitions []string) []StatementBatch {
+	groups := make(map[string][]int)
+	order := make([]string, 0)
+	for i := range stmts {
+		var p string
+		if i < len(partitions) {
+			p = partitions[i]
+		}
+		if _, ok := groups[p]; !ok {
+			order = append(order, p)
+		}
+		groups[p] = append(groups[p], i)
+	}
+	batches := make([]StatementBatch, 0, len(order))
+	for _, p := range order {
+		t := typ
+		if typ != gocql.CounterBatch {
+			if len(p) > 0 {
+				t = gocql.UnloggedBatch
+			} else if config.Logged {
+				t = gocql.LoggedBatch
+			}
+		}
+		var current []Statement
+		var indexes []int
+		bytes := 0
+		for _, i := range groups[p] {
+			n := StatementSize(stmts[i])
+			full := config.Size > 0 && len(current) >= config.Size
+			tooLarge := config.MaxBytes > 0 && len(current) > 0 && bytes+n > config.MaxBytes
+			if full || tooLarge {
+				batches = append(batches, StatementBatch{Type: t, Partition: p, Statements: current, Indexes: indexes})
+				current = nil
+				indexes = nil
+				bytes = 0
+			}
+			current = append(current, stmts[i])
+			indexes = append(indexes, i)
+			bytes = bytes + n
+		}
+		if len(current) > 0 {
+			batches = append(batches, StatementBatch{Type: t, Partition: p, Statements: current, Indexes: indexes})
+		}
+	}
+	return batches
+}
+
+// ExecuteAllWithConfig groups the statements by GroupStatements, and executes the batches concurrently.
+// It returns the number of the succeeded statements, and the first error. See ExecuteAllWithResult.
+func ExecuteAllWithConfig(ctx context.Context, ses Session, typ gocql.BatchType, config BatchConfig, stmts []Statement, partitions []string) (int64, error) {
+	result, err := ExecuteAllWithResult(ctx, ses, typ, config, stmts, partitions)
+	return result.Count(), err
+}
+
+// ExecuteAllWithResult groups the statements by GroupStatements, executes the batches concurrently, and returns the result of each statement.
+// The error is the error of the first failed statement, or the error of the context if some statements are skipped.
+// The statements with an empty query, such as the increments without counter delta, have nothing to write: they are not executed, and succeed.
+func ExecuteAllWithResult(ctx context.Context, ses Session, typ gocql.BatchType, config BatchConfig, stmts []Statement, partitions []string) (*BatchResult, error) {
+	result := &BatchResult{}
+	if len(stmts) == 0 {
+		return result, nil
+	}
+	errs := make([]error, len(stmts))
+	done := make([]bool, len(stmts))
+	indexes := make([]int, 0, len(stmts))
+	for i := range stmts {
+		if len(stmts[i].Query) == 0 {
+			done[i] = true
+		} else {
+			indexes = append(indexes, i)
+		}
+	}
+	batches := GroupStatements(typ, config, selectStatements(stmts, indexes), selectPartitions(partitions, indexes))
+	for _, b := range batches {
+		for j, i := range b.Indexes {
+			b.Indexes[j] = indexes[i]
+		}
+	}
+	concurrency := config.Concurrency
+	if concurrency <= 0 {
+		concurrency = GetConcurrency(ctx)
+	}
+	var failed int32
+	er0 := runConcurrently(ctx, concurrency, len(batches), func(i int) error {
+		if !config.ContinueOnError && atomic.LoadInt32(&failed) > 0 {
+			return nil
+		}
+		err := executeStatementBatch(ctx, ses, batches[i])
+		if err != nil {
+			atomic.StoreInt32(&failed, 1)
+		}
+		for _, j := range batches[i].Indexes {
+			errs[j] = err
+			done[j] = true
+		}
+		return nil
+	})
+	for i := range stmts {
+		if !done[i] {
+			result.Skipped = append(result.Skipped, i)
+		} else if errs[i] != nil {
+			result.Failed = append(result.Failed, BatchFailure{Index: i, Err: errs[i]})
+		} else {
+			result.Succeeded = append(result.Succeeded, i)
+		}
+	}
+	if len(result.Failed) > 0 {
+		return result, result.Failed[0].Err
+	}
+	return result, er0
+}
+
+// ExecuteAllCASWithResult executes the conditional statements one by one, as ExecuteAllCAS,
+// the statements which are not applied are failed by ConflictError with the current row.
+func ExecuteAllCASWithResult(ctx context.Context, ses Session, config BatchConfig, stmts ...Statement) (*BatchResult, error) {
+	result := &BatchResult{}
+	var first error
+	for i, stmt := range stmts {
+		if first != nil && !config.ContinueOnError {
+			result.Skipped = append(result.Skipped, i)
+			continue
+		}
+		applied, current, err := ExecCAS(ctx, ses, stmt.Query, stmt.Params...)
+		if err == nil && !applied {
+			err = &ConflictError{Current: current}
+		}
+		if err != nil {
+			result.Failed = append(result.Failed, BatchFailure{Index: i, Err: err})
+			if first == nil {
+				first = err
+			}
+			continue
+		}
+		result.Succeeded = append(result.Succeeded, i)
+	}
+	return result, first
+}
+func selectStatements(stmts []Statement, indexes []int) []Statement {
+	if len(indexes) == len(stmts) {
+		return stmts
+	}
+	r := make([]Statement, 0, len(indexes))
+	for _, i := range indexes {
+		r = append(r, stmts[i])
+	}
+	return r
+}
+func selectPartitions(partitions []string, indexes []int) []string {
+	if partitions == nil {
+		return nil
+	}
+	r := make([]string, 0, len(indexes))
+	for _, i := range indexes {
+		if i < len(partitions) {
+			r = append(r, partitions[i])
+		} else {
+			r = append(r, "")
+		}
+	}
+	return r
+}
+func executeStatementBatch(ctx context.Context, ses Session, sb StatementBatch) error {
+	batch := ses.NewBatch(sb.Type).Idempotent(sb.Type != gocql.CounterBatch)
+	for _, stmt := range sb.Statements {
+		batch.Query(stmt.Query, stmt.Params...)
+	}
+	b, cancel := ApplyBatchOptions(ctx, batch)
+	defer cancel()
+	return ses.ExecuteBatch(b)
+}
+
+// GetPartitionKeys returns the keys of the partitions of the models, to group the statements of the models by GroupStatements.
+// It returns nil if the schema has no partition key; the key of a nil model is empty.
+func GetPartitionKeys(models interface{}, options ...*Schema) ([]string, error) {
+	s := reflect.Indirect(reflect.ValueOf(models))
+	if s.Kind() != reflect.Slice {
+		return nil, fmt.Errorf("models is not a slice")
+	}
+	var schema *Schema
+	if len(options) > 0 && options[0] != nil {
+		schema = options[0]
+	} else {
+		modelType := s.Type().Elem()
+		for modelType.Kind() == reflect.Ptr {
+			modelType = modelType.Elem()
+		}
+		if modelType.Kind() != reflect.Struct {
+			return nil, nil
+		}
+		schema = GetSchema(modelType)
+	}
+	if len(schema.PartitionKeys) == 0 {
+		return nil, nil
+	}
+	keys := make([]string, s.Len())
+	for i := range keys {
+		v := s.Index(i)
+		for v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface {
+			if v.IsNil() {
+				break
+			}
+			v = v.Elem()
+		}
+		if v.Kind() != reflect.Struct {
+			continue
+		}
+		values := make([]interface{}, 0, len(schema.PartitionKeys))
+		for _, k := range schema.PartitionKeys {
+			values = append(values, v.Field(k.Index).Interface())
+		}
+		keys[i] = toKey(values)
+	}
+	return keys, nil
+}
+
+// StatementSize estimates the size of the values of the statement, as they are serialized by the driver.
+func StatementSize(stmt Statement) int {
+	n := 0
+	for _, p := range stmt.Params {
+		n = n + 4 + valueSize(reflect.ValueOf(p))
+	}
+	return n
+}
+
+// ModelSize estimates the size of the values of the model, as StatementSize.
+func ModelSize(model interface{}) int {
+	return valueSize(reflect.ValueOf(model))
+}
+func valueSize(v reflect.Value) int {
+	if !v.IsValid() || (v.Kind() == reflect.Ptr && v.IsNil()) {
+		return 0
+	}
+	switch x := v.Interface().(type) {
+	case time.Time:
+		return 8
+	case gocql.UUID:
+		return 16
+	case fmt.Stringer:
+		// decimal, varint, duration
+		return len(x.String())
+	}
+	switch v.Kind() {
+	case reflect.Ptr, reflect.Interface:
+		if v.IsNil() {
+			return 0
+		}
+		return valueSize(v.Elem())
+	case reflect.String:
+		return v.Len()
+	case reflect.Bool, reflect.Int8, reflect.Uint8:
+		return 1
+	case reflect.Int16, reflect.Uint16:
+		return 2
+	case reflect.Int32, reflect.Uint32, reflect.Float32:
+		return 4
+	case reflect.Int, reflect.Uint, reflect.Int64, reflect.Uint64, reflect.Float64:
+		return 8
+	case reflect.Slice, reflect.Array:
+		if v.Type().Elem().Kind() == reflect.Uint8 {
+			return v.Len()
+		}
+		n := 4
+		for i := 0; i < v.Len(); i++ {
+			n = n + 4 + valueSize(v.Index(i))
+		}
+		return n
+	case reflect.Map:
+		n := 4
+		iter := v.MapRange()
+		for iter.Next() {
+			n = n + 8 + valueSize(iter.Key()) + valueSize(iter.Value())
+		}
+		return n
+	case reflect.Struct:
+		n := 0
+		for i := 0; i < v.NumField(); i++ {
+			if v.Type().Field(i).IsExported() {
+				n = n + 4 + valueSize(v.Field(i))
+			}
+		}
+		return n
+	}
+	return len(fmt.Sprint(v.Interface()))
+}
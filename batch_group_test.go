@@ -0,0 +1,91 @@
+package cassandra_test
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/apache/cassandra-gocql-driver"
+	c "github.com/core-go/cassandra"
+	"github.com/core-go/cassandra/cqltest"
+)
+
+func statements(ids ...string) []c.Statement {
+	stmts := make([]c.Statement, 0, len(ids))
+	for _, id := range ids {
+		stmts = append(stmts, c.Statement{Query: "update items set name=? where id='" + id + "'", Params: []interface{}{"name"}})
+	}
+	return stmts
+}
+
+func TestGroupStatements(t *testing.T) {
+	tests := []struct {
+		name       string
+		typ        gocql.BatchType
+		config     c.BatchConfig
+		stmts      int
+		partitions []string
+		types      []gocql.BatchType
+		indexes    [][]int
+	}{
+		{"by partition", gocql.LoggedBatch, c.BatchConfig{}, 4, []string{"a", "b", "a", "b"}, []gocql.BatchType{gocql.UnloggedBatch, gocql.UnloggedBatch}, [][]int{{0, 2}, {1, 3}}},
+		{"by size", gocql.LoggedBatch, c.BatchConfig{Size: 2}, 3, []string{"a", "a", "a"}, []gocql.BatchType{gocql.UnloggedBatch, gocql.UnloggedBatch}, [][]int{{0, 1}, {2}}},
+		{"by bytes", gocql.LoggedBatch, c.BatchConfig{MaxBytes: 20}, 3, []string{"a", "a", "a"}, []gocql.BatchType{gocql.UnloggedBatch, gocql.UnloggedBatch}, [][]int{{0, 1}, {2}}},
+		{"without partition", gocql.UnloggedBatch, c.BatchConfig{}, 3, nil, []gocql.BatchType{gocql.UnloggedBatch}, [][]int{{0, 1, 2}}},
+		{"logged", gocql.UnloggedBatch, c.BatchConfig{Logged: true}, 3, []string{"", "a", ""}, []gocql.BatchType{gocql.LoggedBatch, gocql.UnloggedBatch}, [][]int{{0, 2}, {1}}},
+		{"counter", gocql.CounterBatch, c.BatchConfig{Logged: true}, 2, []string{"a", ""}, []gocql.BatchType{gocql.CounterBatch, gocql.CounterBatch}, [][]int{{0}, {1}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			batches := c.GroupStatements(tt.typ, tt.config, statements("1", "2", "3", "4")[:tt.stmts], tt.partitions)
+			types := make([]gocql.BatchType, 0)
+			indexes := make([][]int, 0)
+			for _, b := range batches {
+				types = append(types, b.Type)
+				indexes = append(indexes, b.Indexes)
+			}
+			if !reflect.DeepEqual(types, tt.types) || !reflect.DeepEqual(indexes, tt.indexes) {
+				t.Fatalf("GroupStatements() = %v %v, want %v %v", types, indexes, tt.types, tt.indexes)
+			}
+		})
+	}
+}
+
+func TestExecuteAllWithResult(t *testing.T) {
+	failure := errors.New("write failed")
+	tests := []struct {
+		name            string
+		continueOnError bool
+		succeeded       []int
+		failed          []int
+		skipped         []int
+		executed        int
+	}{
+		{"stop on error", false, []int{0, 3}, []int{1}, []int{2}, 2},
+		{"continue on error", true, []int{0, 2, 3}, []int{1}, nil, 3},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ses := cqltest.NewSession()
+			ses.Fail("where id='2'", failure)
+			stmts := statements("1", "2", "3")
+			stmts = append(stmts, c.Statement{})
+			config := c.BatchConfig{Concurrency: 1, ContinueOnError: tt.continueOnError}
+			result, err := c.ExecuteAllWithResult(context.Background(), ses, gocql.UnloggedBatch, config, stmts, []string{"1", "2", "3", "4"})
+			if !errors.Is(err, failure) {
+				t.Fatalf("ExecuteAllWithResult() error = %v, want %v", err, failure)
+			}
+			failed := make([]int, 0)
+			for _, f := range result.Failed {
+				failed = append(failed, f.Index)
+			}
+			if !reflect.DeepEqual(result.Succeeded, tt.succeeded) || !reflect.DeepEqual(failed, tt.failed) || !reflect.DeepEqual(result.Skipped, tt.skipped) {
+				t.Fatalf("ExecuteAllWithResult() = %v %v %v, want %v %v %v", result.Succeeded, failed, result.Skipped, tt.succeeded, tt.failed, tt.skipped)
+			}
+			if n := len(ses.Executed()); n != tt.executed {
+				t.Fatalf("executed %d statements, want %d", n, tt.executed)
+			}
+		})
+	}
+}
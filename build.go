@@ -10,6 +10,35 @@ import (
 func BuildParam(i int) string {
 	return "?"
 }
+
+// Using holds the parameters of "using ttl ? and timestamp ?". TTL is in seconds, nil keeps the default ttl of the table, 0 means the columns never expire.
+// Timestamp is in microseconds, 0 is not written.
+type Using struct {
+	TTL       *int
+	Timestamp int64
+	// Prepared binds all values as parameters, see WithPrepared.
+	Prepared bool
+}
+
+func BuildUsing(using *Using) (string, []interface{}) {
+	if using == nil {
+		return "", nil
+	}
+	params := make([]string, 0)
+	args := make([]interface{}, 0)
+	if using.TTL != nil && *using.TTL >= 0 {
+		params = append(params, "ttl "+BuildParam(0))
+		args = append(args, *using.TTL)
+	}
+	if using.Timestamp > 0 {
+		params = append(params, "timestamp "+BuildParam(0))
+		args = append(args, using.Timestamp)
+	}
+	if len(params) == 0 {
+		return "", nil
+	}
+	return " using " + strings.Join(params, " and "), args
+}
 func BuildToInsert(table string, model interface{}, options ...*Schema) (string, []interface{}) {
 	return BuildToInsertWithVersion(table, model, -1, false, options...)
 }
@@ -17,14 +46,28 @@ func BuildToSave(table string, model interface{}, options ...*Schema) (string, [
 	return BuildToInsertWithVersion(table, model, -1, true, options...)
 }
 func BuildToInsertWithVersion(table string, model interface{}, versionIndex int, orUpdate bool, options ...*Schema) (string, []interface{}) {
+	return BuildToInsertWithUsing(table, model, versionIndex, orUpdate, nil, options...)
+}
+
+// BuildToInsertWithUsing builds "insert ... using ttl ? and timestamp ?". If using is nil, the default ttl of the schema is used.
+// Counter tables cannot be inserted, so the counters of the model are incremented instead.
+func BuildToInsertWithUsing(table string, model interface{}, versionIndex int, orUpdate bool, using *Using, options ...*Schema) (string, []interface{}) {
 	buildParam := BuildParam
 	modelType := reflect.TypeOf(model)
-	var cols []*FieldDB
+	var schema *Schema
 	if len(options) > 0 && options[0] != nil {
-		cols = options[0].Columns
+		schema = options[0]
 	} else {
-		m := CreateSchema(modelType)
-		cols = m.Columns
+		schema = GetSchema(modelType)
+	}
+	if len(schema.Counters) > 0 {
+		return BuildToIncrement(table, model, schema)
+	}
+	cols := schema.Columns
+	prepared := using != nil && using.Prepared
+	if using == nil && schema.TTL > 0 {
+		ttl := schema.TTL
+		using = &Using{TTL: &ttl}
 	}
 	mv := reflect.ValueOf(model)
 	if mv.Kind() == reflect.Ptr {
@@ -37,7 +80,13 @@ func BuildToInsertWithVersion(table string, model interface{}, versionIndex int,
 	for _, fdb := range cols {
 		if fdb.Index == versionIndex {
 			icols = append(icols, fdb.Column)
-			values = append(values, "1")
+			if prepared {
+				values = append(values, buildParam(i))
+				i = i + 1
+				args = append(args, 1)
+			} else {
+				values = append(values, "1")
+			}
 		} else {
 			f := mv.Field(fdb.Index)
 			fieldValue := f.Interface()
@@ -49,43 +98,72 @@ func BuildToInsertWithVersion(table string, model interface{}, versionIndex int,
 					fieldValue = reflect.Indirect(reflect.ValueOf(fieldValue)).Interface()
 				}
 			}
-			if fdb.Insert {
+			if fdb.Insert && !(fdb.OmitEmpty && (isNil || f.IsZero())) {
 				if isNil {
-					if orUpdate {
+					if orUpdate && !fdb.Static {
 						icols = append(icols, fdb.Column)
-						values = append(values, "null")
+						if prepared {
+							values = append(values, buildParam(i))
+							i = i + 1
+							args = append(args, nil)
+						} else {
+							values = append(values, "null")
+						}
 					}
 				} else {
 					icols = append(icols, fdb.Column)
-					v, ok := GetDBValue(fieldValue, fdb.Scale)
+					if fdb.UDT || fdb.Tuple {
+						fieldValue = ToCQL(fieldValue, fdb.Tuple)
+					}
+					v, ok := GetParamValue(fieldValue, fdb.Scale, prepared)
 					if ok {
 						values = append(values, v)
 					} else {
 						values = append(values, buildParam(i))
 						i = i + 1
-						args = append(args, fieldValue)
+						args = append(args, BindValue(fieldValue, fdb.Scale))
 					}
 				}
 			}
 		}
 	}
-	return fmt.Sprintf("insert into %v(%v) values (%v)", table, strings.Join(icols, ","), strings.Join(values, ",")), args
+	query := fmt.Sprintf("insert into %v(%v) values (%v)", table, strings.Join(icols, ","), strings.Join(values, ","))
+	if versionIndex >= 0 && !orUpdate {
+		query = query + " if not exists"
+	}
+	u, uargs := BuildUsing(using)
+	return query + u, append(args, uargs...)
 }
 func BuildToUpdate(table string, model interface{}, options ...*Schema) (string, []interface{}) {
 	return BuildToUpdateWithVersion(table, model, -1, options...)
 }
 func BuildToUpdateWithVersion(table string, model interface{}, versionIndex int, options ...*Schema) (string, []interface{}) {
+	return BuildToUpdateWithUsing(table, model, versionIndex, nil, options...)
+}
+
+// BuildToUpdateWithUsing builds "update ... using ttl ? and timestamp ? set ...". If using is nil, the default ttl of the schema is used.
+// The counters of the counter tables are incremented by the values of the model.
+// The version may be of any numeric type, as GetVersion; if it is nil, the update is conditioned on a null version,
+// use GetModelVersion to reject such models.
+func BuildToUpdateWithUsing(table string, model interface{}, versionIndex int, using *Using, options ...*Schema) (string, []interface{}) {
 	buildParam := BuildParam
 	var cols, keys []*FieldDB
 	modelType := reflect.TypeOf(model)
+	var m *Schema
 	if len(options) > 0 && options[0] != nil {
-		m := options[0]
-		cols = m.Columns
-		keys = m.Keys
+		m = options[0]
 	} else {
-		m := CreateSchema(modelType)
-		cols = m.Columns
-		keys = m.Keys
+		m = GetSchema(modelType)
+	}
+	if len(m.Counters) > 0 {
+		return BuildToIncrement(table, model, m)
+	}
+	cols = m.Columns
+	keys = m.Keys
+	prepared := using != nil && using.Prepared
+	if using == nil && m.TTL > 0 {
+		ttl := m.TTL
+		using = &Using{TTL: &ttl}
 	}
 	mv := reflect.ValueOf(model)
 	if mv.Kind() == reflect.Ptr {
@@ -95,16 +173,25 @@ func BuildToUpdateWithVersion(table string, model interface{}, versionIndex int,
 	where := make([]string, 0)
 	args := make([]interface{}, 0)
 	vw := ""
+	var version interface{}
 	i := 1
 	for _, fdb := range cols {
 		// fdb2 := schema[col]
 		if fdb.Index == versionIndex {
-			valueOfModel := reflect.Indirect(reflect.ValueOf(model))
-			currentVersion := reflect.Indirect(valueOfModel.Field(versionIndex)).Int()
-			nv := currentVersion + 1
-			values = append(values, fdb.Column+"="+strconv.FormatInt(nv, 10))
-			vw = fdb.Column + "=" + strconv.FormatInt(currentVersion, 10)
-		} else if !fdb.Key && fdb.Update {
+			nv := int64(1)
+			if currentVersion, err := GetModelVersion(model, versionIndex); err == nil {
+				nv = currentVersion + 1
+				version = currentVersion
+			}
+			if prepared {
+				values = append(values, fdb.Column+"="+buildParam(i))
+				i = i + 1
+				args = append(args, nv)
+			} else {
+				values = append(values, fdb.Column+"="+strconv.FormatInt(nv, 10))
+			}
+			vw = fdb.Column
+		} else if !fdb.Key && !fdb.Static && fdb.Update {
 			//f := reflect.Indirect(reflect.ValueOf(model))
 			f := mv.Field(fdb.Index)
 			fieldValue := f.Interface()
@@ -116,16 +203,26 @@ func BuildToUpdateWithVersion(table string, model interface{}, versionIndex int,
 					fieldValue = reflect.Indirect(reflect.ValueOf(fieldValue)).Interface()
 				}
 			}
-			if isNil {
+			if fdb.OmitEmpty && (isNil || f.IsZero()) {
+				continue
+			}
+			if isNil && prepared {
+				values = append(values, fdb.Column+"="+buildParam(i))
+				i = i + 1
+				args = append(args, nil)
+			} else if isNil {
 				values = append(values, fdb.Column+"=null")
 			} else {
-				v, ok := GetDBValue(fieldValue, fdb.Scale)
+				if fdb.UDT || fdb.Tuple {
+					fieldValue = ToCQL(fieldValue, fdb.Tuple)
+				}
+				v, ok := GetParamValue(fieldValue, fdb.Scale, prepared)
 				if ok {
 					values = append(values, fdb.Column+"="+v)
 				} else {
 					values = append(values, fdb.Column+"="+buildParam(i))
 					i = i + 1
-					args = append(args, fieldValue)
+					args = append(args, BindValue(fieldValue, fdb.Scale))
 				}
 			}
 		}
@@ -139,20 +236,22 @@ func BuildToUpdateWithVersion(table string, model interface{}, versionIndex int,
 				fieldValue = reflect.Indirect(reflect.ValueOf(fieldValue)).Interface()
 			}
 		}
-		v, ok := GetDBValue(fieldValue, fdb.Scale)
+		v, ok := GetParamValue(fieldValue, fdb.Scale, prepared)
 		if ok {
 			where = append(where, fdb.Column+"="+v)
 		} else {
 			where = append(where, fdb.Column+"="+buildParam(i))
 			i = i + 1
-			args = append(args, fieldValue)
+			args = append(args, BindValue(fieldValue, fdb.Scale))
 		}
 	}
+	u, uargs := BuildUsing(using)
+	query := fmt.Sprintf("update %v%v set %v where %v", table, u, strings.Join(values, ","), strings.Join(where, " and "))
 	if len(vw) > 0 {
-		where = append(where, vw)
+		query = query + " if " + vw + "=" + buildParam(i)
+		args = append(args, version)
 	}
-	query := fmt.Sprintf("update %v set %v where %v", table, strings.Join(values, ","), strings.Join(where, " and "))
-	return query, args
+	return query, append(uargs, args...)
 }
 func BuildToDelete(table string, ids map[string]interface{}) (string, []interface{}) {
 	var values []interface{}
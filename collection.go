@@ -0,0 +1,222 @@
+package cassandra
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/apache/cassandra-gocql-driver"
+)
+
+// The operators of the list, set and map columns in Patch.
+// In JSON, the value of the column is an object with one operator, such as:
+//
+//	{"id": "1", "tags": {"$append": ["a", "b"]}, "scores": {"$put": {"math": 9}}, "props": {"$delete": ["color"]}}
+const (
+	OpAppend  = "$append"  // col = col + ?
+	OpPrepend = "$prepend" // col = ? + col
+	OpRemove  = "$remove"  // col = col - ?
+	OpPut     = "$put"     // col[key] = ?
+	OpDelete  = "$delete"  // delete col[key]
+)
+
+type CollectionOp struct {
+	Op    string
+	Value interface{}
+}
+
+func Append(values interface{}) *CollectionOp {
+	return &CollectionOp{Op: OpAppend, Value: values}
+}
+func Prepend(values interface{}) *CollectionOp {
+	return &CollectionOp{Op: OpPrepend, Value: values}
+}
+func Remove(values interface{}) *CollectionOp {
+	return &CollectionOp{Op: OpRemove, Value: values}
+}
+
+// Put sets the entries of a map column, or the elements of a list column by index, such as Put(map[string]int{"math": 9}).
+func Put(entries interface{}) *CollectionOp {
+	return &CollectionOp{Op: OpPut, Value: entries}
+}
+
+// DeleteKeys deletes the entries of a map column by key, or the elements of a list column by index.
+func DeleteKeys(keys ...interface{}) *CollectionOp {
+	return &CollectionOp{Op: OpDelete, Value: keys}
+}
+
+// GetCollectionOp returns the operation of the patched value, which is a *CollectionOp, or a JSON object with one operator.
+func GetCollectionOp(value interface{}) (*CollectionOp, bool) {
+	switch v := value.(type) {
+	case *CollectionOp:
+		return v, v != nil
+	case CollectionOp:
+		return &v, true
+	case map[string]interface{}:
+		if len(v) != 1 {
+			return nil, false
+		}
+		for k, x := range v {
+			switch k {
+			case OpAppend, OpPrepend, OpRemove, OpPut, OpDelete:
+				return &CollectionOp{Op: k, Value: x}, true
+			}
+		}
+	}
+	return nil, false
+}
+
+// BuildToPatchStatements builds the update statement of the patch, and the "delete col[?] from ..." statement if there are $delete operations.
+func BuildToPatchStatements(table string, model map[string]interface{}, keyColumns []string, version string, using *Using) []Statement {
+	stmts := make([]Statement, 0)
+	set := make(map[string]interface{})
+	deletes := make(map[string]*CollectionOp)
+	for _, col := range sortedKeys(model) {
+		v := model[col]
+		if op, ok := GetCollectionOp(v); ok && op.Op == OpDelete {
+			deletes[col] = op
+		} else {
+			set[col] = v
+		}
+	}
+	assigned := false
+	for col := range set {
+		if !Contains(keyColumns, col) {
+			assigned = true
+		}
+	}
+	if assigned || len(deletes) == 0 {
+		query, args := BuildToPatchWithUsing(table, set, keyColumns, version, using)
+		stmts = append(stmts, Statement{Query: query, Params: args})
+	}
+	if len(deletes) > 0 {
+		query, args := BuildToDeleteElements(table, model, keyColumns, deletes, using)
+		stmts = append(stmts, Statement{Query: query, Params: args})
+	}
+	return stmts
+}
+
+// BuildToDeleteElements builds "delete col[?],col[?] from table using timestamp ? where ...".
+func BuildToDeleteElements(table string, model map[string]interface{}, keyColumns []string, deletes map[string]*CollectionOp, using *Using) (string, []interface{}) {
+	elements := make([]string, 0)
+	where := make([]string, 0)
+	args := make([]interface{}, 0)
+	for _, col := range sortedKeys(deletes) {
+		for _, key := range toSlice(deletes[col].Value) {
+			elements = append(elements, col+"["+BuildParam(0)+"]")
+			args = append(args, key)
+		}
+	}
+	u := ""
+	if using != nil && using.Timestamp > 0 {
+		var uargs []interface{}
+		u, uargs = BuildUsing(&Using{Timestamp: using.Timestamp})
+		args = append(args, uargs...)
+	}
+	for _, col := range keyColumns {
+		if v, ok := model[col]; ok {
+			where = append(where, col+"="+BuildParam(0))
+			args = append(args, v)
+		}
+	}
+	query := fmt.Sprintf("delete %v from %v%v where %v", strings.Join(elements, ","), table, u, strings.Join(where, " and "))
+	return query, args
+}
+
+// buildCollectionOp builds the assignments of the operation, such as "tags=tags+?" or "scores[?]=?".
+func buildCollectionOp(col string, op *CollectionOp) ([]string, []interface{}) {
+	param := BuildParam(0)
+	switch op.Op {
+	case OpAppend:
+		return []string{col + "=" + col + "+" + param}, []interface{}{op.Value}
+	case OpPrepend:
+		return []string{col + "=" + param + "+" + col}, []interface{}{op.Value}
+	case OpRemove:
+		return []string{col + "=" + col + "-" + param}, []interface{}{op.Value}
+	case OpPut:
+		values := make([]string, 0)
+		args := make([]interface{}, 0)
+		mv := reflect.Indirect(reflect.ValueOf(op.Value))
+		if mv.Kind() == reflect.Map {
+			for _, key := range sortMapKeys(mv.MapKeys()) {
+				values = append(values, col+"["+param+"]="+param)
+				args = append(args, key.Interface(), mv.MapIndex(key).Interface())
+			}
+		}
+		return values, args
+	}
+	return nil, nil
+}
+
+// sortMapKeys sorts the keys of a map, so that the same patch always builds the same statement.
+func sortMapKeys(keys []reflect.Value) []reflect.Value {
+	sort.Slice(keys, func(i, j int) bool {
+		a, b := keys[i], keys[j]
+		switch a.Kind() {
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			return a.Int() < b.Int()
+		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+			return a.Uint() < b.Uint()
+		case reflect.Float32, reflect.Float64:
+			return a.Float() < b.Float()
+		case reflect.String:
+			return a.String() < b.String()
+		}
+		return fmt.Sprint(a.Interface()) < fmt.Sprint(b.Interface())
+	})
+	return keys
+}
+func toSlice(value interface{}) []interface{} {
+	v := reflect.Indirect(reflect.ValueOf(value))
+	if v.Kind() != reflect.Slice && v.Kind() != reflect.Array {
+		return []interface{}{value}
+	}
+	r := make([]interface{}, 0, v.Len())
+	for i := 0; i < v.Len(); i++ {
+		r = append(r, v.Index(i).Interface())
+	}
+	return r
+}
+
+// ExecutePatch executes the statements of BuildToPatchStatements. Multiple statements are executed in a logged batch, because they update the same row.
+// If cas is true and the version condition is not applied, it returns 0 and a ConflictError with the current row.
+func ExecutePatch(ctx context.Context, ses Session, cas bool, stmts ...Statement) (int64, error) {
+	if len(stmts) == 1 {
+		if cas {
+			return ExecuteWithVersion(ctx, ses, stmts[0].Query, stmts[0].Params...)
+		}
+		err := ExecContext(ctx, ses, stmts[0].Query, stmts[0].Params...)
+		if err != nil {
+			return 0, err
+		}
+		return 1, nil
+	}
+	batch := ses.NewBatch(gocql.LoggedBatch)
+	for _, stmt := range stmts {
+		batch.Query(stmt.Query, stmt.Params...)
+	}
+	b, cancel := ApplyBatchOptions(ctx, batch)
+	defer cancel()
+	if cas {
+		current := make(map[string]interface{})
+		applied, iter, err := ses.MapExecuteBatchCAS(b, current)
+		if iter != nil {
+			if er2 := iter.Close(); er2 != nil && err == nil {
+				err = er2
+			}
+		}
+		if err != nil {
+			return -1, err
+		}
+		if !applied {
+			return 0, &ConflictError{Current: current}
+		}
+		return 1, nil
+	}
+	if err := ses.ExecuteBatch(b); err != nil {
+		return 0, err
+	}
+	return 1, nil
+}
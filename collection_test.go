@@ -0,0 +1,92 @@
+package cassandra_test
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	c "github.com/core-go/cassandra"
+	"github.com/core-go/cassandra/cqltest"
+)
+
+func TestBuildToPatchStatements(t *testing.T) {
+	keys := []string{"id"}
+	tests := []struct {
+		name  string
+		model map[string]interface{}
+		want  []c.Statement
+	}{
+		{
+			name:  "append and prepend",
+			model: map[string]interface{}{"id": 1, "tags": c.Append([]string{"a"}), "history": c.Prepend([]int{1})},
+			want:  []c.Statement{{Query: "update users set history=?+history,tags=tags+? where id=1", Params: []interface{}{[]int{1}, []string{"a"}}}},
+		},
+		{
+			name:  "json operators",
+			model: map[string]interface{}{"id": 1, "tags": map[string]interface{}{"$remove": []interface{}{"a"}}},
+			want:  []c.Statement{{Query: "update users set tags=tags-? where id=1", Params: []interface{}{[]interface{}{"a"}}}},
+		},
+		{
+			name:  "put in key order",
+			model: map[string]interface{}{"id": 1, "scores": c.Put(map[string]int{"science": 7, "art": 8, "math": 9})},
+			want:  []c.Statement{{Query: "update users set scores[?]=?,scores[?]=?,scores[?]=? where id=1", Params: []interface{}{"art", 8, "math", 9, "science", 7}}},
+		},
+		{
+			name:  "put by index",
+			model: map[string]interface{}{"id": 1, "history": c.Put(map[int]int{10: 1, 2: 3})},
+			want:  []c.Statement{{Query: "update users set history[?]=?,history[?]=? where id=1", Params: []interface{}{2, 3, 10, 1}}},
+		},
+		{
+			name:  "delete only",
+			model: map[string]interface{}{"id": 1, "scores": c.DeleteKeys("math"), "props": c.DeleteKeys("size", "color")},
+			want:  []c.Statement{{Query: "delete props[?],props[?],scores[?] from users where id=?", Params: []interface{}{"size", "color", "math", 1}}},
+		},
+		{
+			name:  "set and delete",
+			model: map[string]interface{}{"id": 1, "scores": c.DeleteKeys("math"), "tags": c.Append([]string{"a"})},
+			want: []c.Statement{
+				{Query: "update users set tags=tags+? where id=1", Params: []interface{}{[]string{"a"}}},
+				{Query: "delete scores[?] from users where id=?", Params: []interface{}{"math", 1}},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for i := 0; i < 5; i++ {
+				got := c.BuildToPatchStatements("users", tt.model, keys, "", nil)
+				if !reflect.DeepEqual(got, tt.want) {
+					t.Fatalf("BuildToPatchStatements() = %v, want %v", got, tt.want)
+				}
+			}
+		})
+	}
+}
+
+func TestPatchConflict(t *testing.T) {
+	tests := []struct {
+		name       string
+		model      map[string]interface{}
+		statements int
+	}{
+		{"update", map[string]interface{}{"id": "1", "name": "Peter", "version": 2}, 1},
+		{"update and delete in a batch", map[string]interface{}{"id": "1", "name": "Peter", "version": 2, "props": c.DeleteKeys("color")}, 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ses := cqltest.NewSession()
+			ses.On("update users", cqltest.Result{NotApplied: true, Columns: []string{"id", "version"}, Rows: [][]interface{}{{"1", 3}}})
+			res, err := newVersionedWriter(t, ses).Patch(context.Background(), tt.model)
+			var conflict *c.ConflictError
+			if res != 0 || !errors.As(err, &conflict) {
+				t.Fatalf("Patch() = %d, %v, want 0 and a ConflictError", res, err)
+			}
+			if conflict.Current["version"] != 3 {
+				t.Fatalf("current row = %v", conflict.Current)
+			}
+			if n := len(ses.Executed()); n != tt.statements {
+				t.Fatalf("executed %d statements, want %d", n, tt.statements)
+			}
+		})
+	}
+}
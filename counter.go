@@ -0,0 +1,219 @@
+package cassandra
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/apache/cassandra-gocql-driver"
+)
+
+// BuildToIncrement builds "update table set c1=c1+?,c2=c2+? where ..." for the counter columns of the model, the values of the counter fields are the deltas.
+// The nil and zero deltas are skipped; if there is no delta, the query is empty, because "update table set where ..." is invalid.
+func BuildToIncrement(table string, model interface{}, options ...*Schema) (string, []interface{}) {
+	return BuildToIncrementWithSign(table, model, 1, options...)
+}
+func BuildToDecrement(table string, model interface{}, options ...*Schema) (string, []interface{}) {
+	return BuildToIncrementWithSign(table, model, -1, options...)
+}
+func BuildToIncrementWithSign(table string, model interface{}, sign int64, options ...*Schema) (string, []interface{}) {
+	var schema *Schema
+	if len(options) > 0 && options[0] != nil {
+		schema = options[0]
+	} else {
+		schema = GetSchema(reflect.TypeOf(model))
+	}
+	mv := reflect.Indirect(reflect.ValueOf(model))
+	values := make([]string, 0)
+	where := make([]string, 0)
+	args := make([]interface{}, 0)
+	for _, fdb := range schema.Counters {
+		delta, ok := GetCounterValue(mv.Field(fdb.Index))
+		if !ok || delta == 0 {
+			continue
+		}
+		values = append(values, fdb.Column+"="+fdb.Column+"+"+BuildParam(0))
+		args = append(args, sign*delta)
+	}
+	if len(values) == 0 {
+		return "", nil
+	}
+	for _, fdb := range schema.Keys {
+		f := reflect.Indirect(mv.Field(fdb.Index))
+		where = append(where, fdb.Column+"="+BuildParam(0))
+		args = append(args, f.Interface())
+	}
+	return fmt.Sprintf("update %v set %v where %v", table, strings.Join(values, ","), strings.Join(where, " and ")), args
+}
+
+// BuildToIncrementByKey builds "update table set c1=c1+? where k1=? and k2=?", keys and deltas are mapped by column name.
+// The zero deltas are skipped; if there is no delta, the query is empty.
+func BuildToIncrementByKey(table string, keys map[string]interface{}, deltas map[string]int64) (string, []interface{}) {
+	values := make([]string, 0)
+	where := make([]string, 0)
+	args := make([]interface{}, 0)
+	for _, col := range sortedKeys(deltas) {
+		if deltas[col] == 0 {
+			continue
+		}
+		values = append(values, col+"="+col+"+"+BuildParam(0))
+		args = append(args, deltas[col])
+	}
+	if len(values) == 0 {
+		return "", nil
+	}
+	for _, col := range sortedKeys(keys) {
+		where = append(where, col+"="+BuildParam(0))
+		args = append(args, keys[col])
+	}
+	return fmt.Sprintf("update %v set %v where %v", table, strings.Join(values, ","), strings.Join(where, " and ")), args
+}
+func BuildToIncrementBatch(table string, models interface{}, options ...*Schema) ([]Statement, error) {
+	list, err := InterfaceSlice(models)
+	if err != nil {
+		return nil, err
+	}
+	stmts := make([]Statement, 0)
+	for _, model := range list {
+		query, args := BuildToIncrement(table, model, options...)
+		stmts = append(stmts, Statement{Query: query, Params: args})
+	}
+	return stmts, nil
+}
+
+// GetCounterValue returns the value of the integer field as int64, false if the field is a nil pointer or not an integer.
+func GetCounterValue(f reflect.Value) (int64, bool) {
+	if f.Kind() == reflect.Ptr {
+		if f.IsNil() {
+			return 0, false
+		}
+		f = f.Elem()
+	}
+	switch f.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return f.Int(), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return int64(f.Uint()), true
+	}
+	return 0, false
+}
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// ExecuteCounterBatch executes the counter updates in counter batches, because counter updates cannot be mixed with other statements.
+func ExecuteCounterBatch(ctx context.Context, ses Session, size int, stmts ...Statement) (int64, error) {
+	return ExecuteAllWithType(ctx, ses, gocql.CounterBatch, size, stmts...)
+}
+func IncrementBatch(ctx context.Context, ses Session, table string, models interface{}, options ...*Schema) (int64, error) {
+	return IncrementBatchWithConfig(ctx, ses, DefaultBatchConfig, table, models, options...)
+}
+func IncrementBatchWithSize(ctx context.Context, ses Session, size int, table string, models interface{}, options ...*Schema) (int64, error) {
+	return IncrementBatchWithConfig(ctx, ses, DefaultBatchConfig.WithSize(size), table, models, options...)
+}
+
+// IncrementBatchWithConfig increments the counters of the models in counter batches grouped by partition, see ExecuteAllWithConfig.
+// The models without counter delta are not written, see ExecuteAllWithResult.
+func IncrementBatchWithConfig(ctx context.Context, ses Session, config BatchConfig, table string, models interface{}, options ...*Schema) (int64, error) {
+	stmts, err := BuildToIncrementBatch(table, models, options...)
+	if err != nil {
+		return -1, err
+	}
+	partitions, err := GetPartitionKeys(models, options...)
+	if err != nil {
+		return -1, err
+	}
+	return ExecuteAllWithConfig(ctx, ses, gocql.CounterBatch, config, stmts, partitions)
+}
+
+// CounterWriter writes the counter table of the model, the fields tagged by counter are the counter columns, such as:
+//
+//	type PageView struct {
+//		Page  string `cql:"page,partition_key"`
+//		Views int64  `cql:"views,counter"`
+//	}
+type CounterWriter struct {
+	*Loader
+}
+
+func NewCounterWriter(db *gocql.ClusterConfig, tableName string, modelType reflect.Type) (*CounterWriter, error) {
+	return NewCounterWriterWithProvider(GetSessionProvider(db), tableName, modelType)
+}
+func NewCounterWriterWithProvider(db SessionProvider, tableName string, modelType reflect.Type) (*CounterWriter, error) {
+	loader, err := NewLoaderWithProvider(db, tableName, modelType)
+	if err != nil {
+		return nil, err
+	}
+	if len(loader.schema.Counters) == 0 {
+		return nil, fmt.Errorf("%v has no counter field", modelType.Name())
+	}
+	return &CounterWriter{Loader: loader}, nil
+}
+
+// Increment adds the values of the counter fields to the counters of the row.
+func (s *CounterWriter) Increment(ctx context.Context, model interface{}) (int64, error) {
+	query, args := BuildToIncrement(s.table, model, s.schema)
+	return s.exec(ctx, query, args...)
+}
+
+// Decrement subtracts the values of the counter fields from the counters of the row.
+func (s *CounterWriter) Decrement(ctx context.Context, model interface{}) (int64, error) {
+	query, args := BuildToDecrement(s.table, model, s.schema)
+	return s.exec(ctx, query, args...)
+}
+
+// IncrementBy adds the deltas to the counters of the row, keys and deltas are mapped by column name.
+func (s *CounterWriter) IncrementBy(ctx context.Context, keys map[string]interface{}, deltas map[string]int64) (int64, error) {
+	query, args := BuildToIncrementByKey(s.table, keys, deltas)
+	return s.exec(ctx, query, args...)
+}
+
+// IncrementBatch increments the counters of the models in counter batches.
+func (s *CounterWriter) IncrementBatch(ctx context.Context, models interface{}) (int64, error) {
+	ctx = WithDefaultOptions(ctx, s.Options)
+	ses, err := s.DB.Session()
+	if err != nil {
+		return -1, err
+	}
+	return IncrementBatch(ctx, ses, s.table, models, s.schema)
+}
+
+// LoadCounters loads the counters of the row by id, mapped by json name.
+func (s *CounterWriter) LoadCounters(ctx context.Context, id interface{}) (map[string]int64, error) {
+	model, err := s.Load(ctx, id)
+	if err != nil || model == nil {
+		return nil, err
+	}
+	mv := reflect.ValueOf(model)
+	for mv.Kind() == reflect.Ptr || mv.Kind() == reflect.Interface {
+		mv = mv.Elem()
+	}
+	counters := make(map[string]int64)
+	for _, fdb := range s.schema.Counters {
+		v, _ := GetCounterValue(mv.Field(fdb.Index))
+		counters[fdb.JSON] = v
+	}
+	return counters, nil
+}
+func (s *CounterWriter) exec(ctx context.Context, query string, args ...interface{}) (int64, error) {
+	if len(query) == 0 {
+		return 0, nil
+	}
+	ctx = WithDefaultOptions(ctx, s.Options)
+	ses, err := s.DB.Session()
+	if err != nil {
+		return -1, err
+	}
+	err = ExecContext(ctx, ses, query, args...)
+	if err != nil {
+		return 0, err
+	}
+	return 1, nil
+}
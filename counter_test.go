@@ -0,0 +1,126 @@
+package cassandra_test
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	c "github.com/core-go/cassandra"
+	"github.com/core-go/cassandra/cqltest"
+)
+
+type pageView struct {
+	Site   string `cql:"site,partition_key"`
+	Page   string `cql:"page,clustering_key"`
+	Views  int64  `cql:"views,counter"`
+	Clicks *int   `cql:"clicks,counter"`
+}
+
+func TestBuildToIncrement(t *testing.T) {
+	two := 2
+	zero := 0
+	tests := []struct {
+		name  string
+		model pageView
+		query string
+		args  []interface{}
+	}{
+		{"all counters", pageView{Site: "a", Page: "p", Views: 1, Clicks: &two}, "update views set views=views+?,clicks=clicks+? where site=? and page=?", []interface{}{int64(1), int64(2), "a", "p"}},
+		{"nil counter", pageView{Site: "a", Page: "p", Views: 1}, "update views set views=views+? where site=? and page=?", []interface{}{int64(1), "a", "p"}},
+		{"zero counter", pageView{Site: "a", Page: "p", Clicks: &two}, "update views set clicks=clicks+? where site=? and page=?", []interface{}{int64(2), "a", "p"}},
+		{"no counter", pageView{Site: "a", Page: "p", Clicks: &zero}, "", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			query, args := c.BuildToIncrement("views", tt.model)
+			if query != tt.query || !reflect.DeepEqual(args, tt.args) {
+				t.Fatalf("BuildToIncrement() = %q, %v, want %q, %v", query, args, tt.query, tt.args)
+			}
+		})
+	}
+	query, args := c.BuildToIncrementByKey("views", map[string]interface{}{"site": "a"}, map[string]int64{"views": 0})
+	if query != "" || args != nil {
+		t.Fatalf("BuildToIncrementByKey() = %q, %v, want no statement", query, args)
+	}
+}
+
+func TestIncrementBatch(t *testing.T) {
+	tests := []struct {
+		name       string
+		models     []pageView
+		count      int64
+		statements int
+		batches    int
+	}{
+		{"skips the models without delta", []pageView{{Site: "a", Page: "1", Views: 1}, {Site: "a", Page: "2"}, {Site: "b", Page: "3", Views: 2}}, 3, 2, 2},
+		{"no delta", []pageView{{Site: "a", Page: "1"}}, 1, 0, 0},
+		{"default batch size", makePageViews(60), 60, 60, 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ses := cqltest.NewSession()
+			count, err := c.IncrementBatch(context.Background(), ses, "views", tt.models)
+			if err != nil || count != tt.count {
+				t.Fatalf("IncrementBatch() = %d, %v, want %d", count, err, tt.count)
+			}
+			executed := ses.Executed()
+			batches := make(map[int]bool)
+			for _, e := range executed {
+				if len(e.Query) == 0 {
+					t.Fatal("IncrementBatch() executed an empty statement")
+				}
+				batches[e.Batch] = true
+			}
+			if len(executed) != tt.statements || len(batches) != tt.batches {
+				t.Fatalf("executed %d statements in %d batches, want %d in %d", len(executed), len(batches), tt.statements, tt.batches)
+			}
+		})
+	}
+}
+func makePageViews(n int) []pageView {
+	models := make([]pageView, n)
+	for i := range models {
+		models[i] = pageView{Site: "a", Page: string(rune('a' + i)), Views: 1}
+	}
+	return models
+}
+
+func TestCounterWriterNoDelta(t *testing.T) {
+	ses := cqltest.NewSession()
+	writer, err := c.NewCounterWriterWithProvider(ses.Provider(), "views", reflect.TypeOf(pageView{}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	res, err := writer.Increment(context.Background(), &pageView{Site: "a", Page: "p"})
+	if res != 0 || err != nil || len(ses.Executed()) != 0 {
+		t.Fatalf("Increment() = %d, %v, executed %v", res, err, ses.Executed())
+	}
+}
+
+func TestWriterCounterNoDelta(t *testing.T) {
+	tests := []struct {
+		name  string
+		write func(w *c.Writer, v *pageView) (int64, error)
+	}{
+		{"insert", func(w *c.Writer, v *pageView) (int64, error) { return w.Insert(context.Background(), v) }},
+		{"update", func(w *c.Writer, v *pageView) (int64, error) { return w.Update(context.Background(), v) }},
+		{"save", func(w *c.Writer, v *pageView) (int64, error) { return w.Save(context.Background(), v) }},
+	}
+	zero := 0
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ses := cqltest.NewSession()
+			writer, err := c.NewWriterWithProvider(ses.Provider(), "views", reflect.TypeOf(pageView{}))
+			if err != nil {
+				t.Fatal(err)
+			}
+			res, err := tt.write(writer, &pageView{Site: "a", Page: "p", Clicks: &zero})
+			if res != 0 || err != nil || len(ses.Executed()) != 0 {
+				t.Fatalf("%s() = %d, %v, executed %v", tt.name, res, err, ses.Executed())
+			}
+			if _, err = tt.write(writer, &pageView{Site: "a", Page: "p", Views: 1}); err != nil || len(ses.Executed()) != 1 {
+				t.Fatalf("%s() = %v, executed %v", tt.name, err, ses.Executed())
+			}
+		})
+	}
+}
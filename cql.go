@@ -0,0 +1,186 @@
+package cassandra
+
+import (
+	"context"
+
+	"github.com/apache/cassandra-gocql-driver"
+)
+
+// Session is the part of *gocql.Session used by the loaders and writers, so that they can run against a fake session in the unit tests.
+// NewSession wraps a *gocql.Session.
+type Session interface {
+	Query(stmt string, values ...interface{}) CqlQuery
+	NewBatch(typ gocql.BatchType) Batch
+	ExecuteBatch(batch Batch) error
+	MapExecuteBatchCAS(batch Batch, dest map[string]interface{}) (bool, Iter, error)
+	AwaitSchemaAgreement(ctx context.Context) error
+	Closed() bool
+	Close()
+}
+
+// CqlQuery is the query of a Session.
+type CqlQuery interface {
+	WithContext(ctx context.Context) CqlQuery
+	Consistency(c gocql.Consistency) CqlQuery
+	SerialConsistency(c gocql.SerialConsistency) CqlQuery
+	Idempotent(value bool) CqlQuery
+	PageSize(n int) CqlQuery
+	PageState(state []byte) CqlQuery
+	RetryPolicy(policy gocql.RetryPolicy) CqlQuery
+	Observer(observer gocql.QueryObserver) CqlQuery
+	Statement() string
+	Values() []interface{}
+	Exec() error
+	Scan(dest ...interface{}) error
+	Iter() Iter
+	MapScanCAS(dest map[string]interface{}) (bool, error)
+}
+
+// Iter is implemented by *gocql.Iter.
+type Iter interface {
+	Columns() []gocql.ColumnInfo
+	Scan(dest ...interface{}) bool
+	MapScan(m map[string]interface{}) bool
+	RowData() (gocql.RowData, error)
+	PageState() []byte
+	Close() error
+}
+
+// ValueSetter is implemented by the scan destinations which accept a decoded value, such as the nested structs of the UDT columns,
+// so that a fake Iter can scan the values without marshalling them.
+type ValueSetter interface {
+	SetValue(v interface{}) error
+}
+
+// Batch is the batch of a Session, Statements returns the statements added by Query.
+type Batch interface {
+	Query(stmt string, args ...interface{})
+	Statements() []Statement
+	Size() int
+	Type() gocql.BatchType
+	Idempotent(value bool) Batch
+	WithContext(ctx context.Context) Batch
+	Consistency(c gocql.Consistency) Batch
+	SerialConsistency(c gocql.SerialConsistency) Batch
+	RetryPolicy(policy gocql.RetryPolicy) Batch
+	Observer(observer gocql.BatchObserver) Batch
+}
+
+// GocqlSession is the Session of a *gocql.Session.
+type GocqlSession struct {
+	*gocql.Session
+}
+
+func NewSession(ses *gocql.Session) Session {
+	return &GocqlSession{Session: ses}
+}
+func (s *GocqlSession) Query(stmt string, values ...interface{}) CqlQuery {
+	return &GocqlQuery{Query: s.Session.Query(stmt, values...)}
+}
+func (s *GocqlSession) NewBatch(typ gocql.BatchType) Batch {
+	return &GocqlBatch{Batch: s.Session.NewBatch(typ)}
+}
+func (s *GocqlSession) ExecuteBatch(batch Batch) error {
+	return s.Session.ExecuteBatch(s.toBatch(batch))
+}
+func (s *GocqlSession) MapExecuteBatchCAS(batch Batch, dest map[string]interface{}) (bool, Iter, error) {
+	applied, iter, err := s.Session.MapExecuteBatchCAS(s.toBatch(batch), dest)
+	if iter == nil {
+		return applied, nil, err
+	}
+	return applied, iter, err
+}
+
+// toBatch returns the *gocql.Batch of the batch, or copies the statements of a batch of another implementation.
+func (s *GocqlSession) toBatch(batch Batch) *gocql.Batch {
+	if b, ok := batch.(*GocqlBatch); ok {
+		return b.Batch
+	}
+	b := s.Session.NewBatch(batch.Type())
+	for _, stmt := range batch.Statements() {
+		b.Query(stmt.Query, stmt.Params...)
+	}
+	return b
+}
+
+type GocqlQuery struct {
+	*gocql.Query
+}
+
+func (q *GocqlQuery) WithContext(ctx context.Context) CqlQuery {
+	return &GocqlQuery{Query: q.Query.WithContext(ctx)}
+}
+func (q *GocqlQuery) Consistency(c gocql.Consistency) CqlQuery {
+	q.Query.Consistency(c)
+	return q
+}
+func (q *GocqlQuery) SerialConsistency(c gocql.SerialConsistency) CqlQuery {
+	q.Query.SerialConsistency(c)
+	return q
+}
+func (q *GocqlQuery) Idempotent(value bool) CqlQuery {
+	q.Query.Idempotent(value)
+	return q
+}
+func (q *GocqlQuery) PageSize(n int) CqlQuery {
+	q.Query.PageSize(n)
+	return q
+}
+func (q *GocqlQuery) PageState(state []byte) CqlQuery {
+	q.Query.PageState(state)
+	return q
+}
+func (q *GocqlQuery) RetryPolicy(policy gocql.RetryPolicy) CqlQuery {
+	q.Query.RetryPolicy(policy)
+	return q
+}
+func (q *GocqlQuery) Observer(observer gocql.QueryObserver) CqlQuery {
+	q.Query.Observer(observer)
+	return q
+}
+func (q *GocqlQuery) Iter() Iter {
+	return q.Query.Iter()
+}
+
+// GocqlBatch is the Batch of a *gocql.Batch. The statements added after Idempotent(true) are marked idempotent, so that the driver can retry them.
+type GocqlBatch struct {
+	*gocql.Batch
+	idempotent bool
+}
+
+func (b *GocqlBatch) Query(stmt string, args ...interface{}) {
+	b.Batch.Entries = append(b.Batch.Entries, gocql.BatchEntry{Stmt: stmt, Args: args, Idempotent: b.idempotent})
+}
+func (b *GocqlBatch) Statements() []Statement {
+	stmts := make([]Statement, 0, len(b.Batch.Entries))
+	for _, e := range b.Batch.Entries {
+		stmts = append(stmts, Statement{Query: e.Stmt, Params: e.Args})
+	}
+	return stmts
+}
+func (b *GocqlBatch) Type() gocql.BatchType {
+	return b.Batch.Type
+}
+func (b *GocqlBatch) Idempotent(value bool) Batch {
+	b.idempotent = value
+	return b
+}
+func (b *GocqlBatch) WithContext(ctx context.Context) Batch {
+	return &GocqlBatch{Batch: b.Batch.WithContext(ctx), idempotent: b.idempotent}
+}
+func (b *GocqlBatch) Consistency(c gocql.Consistency) Batch {
+	b.Batch.Consistency(c)
+	return b
+}
+func (b *GocqlBatch) SerialConsistency(c gocql.SerialConsistency) Batch {
+	b.Batch.SerialConsistency(c)
+	return b
+}
+func (b *GocqlBatch) RetryPolicy(policy gocql.RetryPolicy) Batch {
+	b.Batch.RetryPolicy(policy)
+	return b
+}
+func (b *GocqlBatch) Observer(observer gocql.BatchObserver) Batch {
+	b.Batch.Observer(observer)
+	return b
+}
@@ -0,0 +1,564 @@
+package cqltest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/apache/cassandra-gocql-driver"
+
+	c "github.com/core-go/cassandra"
+)
+
+// Result is the scripted result of the statements which match a rule. NotApplied makes the lightweight transactions fail,
+// the first row is returned as the current row. If Err and Rows are both set, Iter returns the rows then fails with Err,
+// as a query which fails in the middle of the paging.
+type Result struct {
+	Columns    []string
+	Rows       [][]interface{}
+	Err        error
+	NotApplied bool
+}
+
+type rule struct {
+	match  string
+	result Result
+}
+
+// Executed is a statement executed by the session. Batch is the number of the batch, from 1, or 0 if the statement is not in a batch.
+// The consistencies, the idempotence and the deadline of the context are the ones of the query or of the batch, when it is executed.
+type Executed struct {
+	Query             string
+	Params            []interface{}
+	Batch             int
+	Consistency       gocql.Consistency
+	SerialConsistency gocql.SerialConsistency
+	Idempotent        bool
+	PageSize          int
+	Deadline          time.Time
+}
+
+// Session is an in-memory c.Session: it records the executed statements, and returns the scripted rows of the first rule which matches the statement.
+// A rule matches if the statement contains its text, case-insensitive and ignoring the extra spaces; the rules added later are checked first.
+// The statements which match no rule return no rows and no error.
+//
+//	ses := cqltest.NewSession()
+//	ses.Rows("from users", []string{"id", "name"}, []interface{}{"1", "Peter"})
+//	writer, _ := cassandra.NewWriterWithProvider(ses.Provider(), "users", reflect.TypeOf(User{}))
+type Session struct {
+	mu       sync.Mutex
+	rules    []rule
+	executed []Executed
+	batches  int
+	closed   bool
+	keyspace string
+}
+
+func NewSession() *Session {
+	return &Session{}
+}
+
+// On adds the result of the statements which contain match.
+func (s *Session) On(match string, result Result) *Session {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.rules = append(s.rules, rule{match: normalize(match), result: result})
+	return s
+}
+
+// Rows adds the rows of the statements which contain match.
+func (s *Session) Rows(match string, columns []string, rows ...[]interface{}) *Session {
+	return s.On(match, Result{Columns: columns, Rows: rows})
+}
+
+// Fail makes the statements which contain match return the error.
+func (s *Session) Fail(match string, err error) *Session {
+	return s.On(match, Result{Err: err})
+}
+
+// UseKeyspace sets the keyspace of the session, which is returned by the Keyspace of its provider.
+func (s *Session) UseKeyspace(keyspace string) *Session {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.keyspace = keyspace
+	return s
+}
+
+// Executed returns the executed statements in order, the statements of the batches are included.
+func (s *Session) Executed() []Executed {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]Executed{}, s.executed...)
+}
+
+// Reset removes the rules and the executed statements.
+func (s *Session) Reset() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.rules = nil
+	s.executed = nil
+	s.batches = 0
+}
+
+// Provider returns the c.SessionProvider of the session, to build the loaders, writers and handlers.
+func (s *Session) Provider() c.SessionProvider {
+	return &provider{session: s}
+}
+
+type provider struct {
+	session *Session
+}
+
+func (p *provider) Session() (c.Session, error) {
+	return p.session, nil
+}
+func (p *provider) Close() {}
+
+// Keyspace implements c.KeyspaceProvider.
+func (p *provider) Keyspace() string {
+	p.session.mu.Lock()
+	defer p.session.mu.Unlock()
+	return p.session.keyspace
+}
+
+func (s *Session) Query(stmt string, values ...interface{}) c.CqlQuery {
+	return &Query{session: s, stmt: stmt, values: values}
+}
+func (s *Session) NewBatch(typ gocql.BatchType) c.Batch {
+	return &Batch{typ: typ}
+}
+func (s *Session) ExecuteBatch(batch c.Batch) error {
+	_, err := s.executeBatch(batch)
+	return err
+}
+func (s *Session) MapExecuteBatchCAS(batch c.Batch, dest map[string]interface{}) (bool, c.Iter, error) {
+	r, err := s.executeBatch(batch)
+	if err != nil {
+		return false, nil, err
+	}
+	if r.NotApplied {
+		fillMap(r, 0, dest)
+		return false, newIter(r, nil, 0), nil
+	}
+	return true, newIter(Result{}, nil, 0), nil
+}
+func (s *Session) executeBatch(batch c.Batch) (Result, error) {
+	b, ok := batch.(*Batch)
+	if !ok {
+		b = &Batch{typ: batch.Type(), stmts: batch.Statements()}
+	}
+	b.attempts = 0
+	r := retry(b.retry, b, b.idempotent, func() Result {
+		start := time.Now()
+		r := s.executeStatements(b)
+		if b.observer != nil {
+			ob := gocql.ObservedBatch{Start: start, End: time.Now(), Err: r.Err, Attempt: b.attempts - 1}
+			for _, stmt := range b.stmts {
+				ob.Statements = append(ob.Statements, stmt.Query)
+				ob.Values = append(ob.Values, stmt.Params)
+			}
+			b.observer.ObserveBatch(b.Context(), ob)
+		}
+		return r
+	})
+	return r, r.Err
+}
+func (s *Session) executeStatements(b *Batch) Result {
+	s.mu.Lock()
+	s.batches++
+	n := s.batches
+	s.mu.Unlock()
+	deadline, _ := b.Context().Deadline()
+	var result Result
+	for _, stmt := range b.stmts {
+		r := s.execute(Executed{Query: stmt.Query, Params: stmt.Params, Batch: n, Consistency: b.consistency, SerialConsistency: b.serial, Idempotent: b.idempotent, Deadline: deadline})
+		if r.Err != nil {
+			return r
+		}
+		if r.NotApplied {
+			result = r
+		}
+	}
+	return result
+}
+func (s *Session) execute(e Executed) Result {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.executed = append(s.executed, e)
+	q := normalize(e.Query)
+	for i := len(s.rules) - 1; i >= 0; i-- {
+		if strings.Contains(q, s.rules[i].match) {
+			return s.rules[i].result
+		}
+	}
+	return Result{}
+}
+func (s *Session) AwaitSchemaAgreement(ctx context.Context) error {
+	return nil
+}
+func (s *Session) Closed() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.closed
+}
+func (s *Session) Close() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.closed = true
+}
+func normalize(s string) string {
+	return strings.Join(strings.Fields(strings.ToLower(s)), " ")
+}
+
+// Query is the c.CqlQuery of the fake session, the statement is recorded when it is executed.
+type Query struct {
+	session     *Session
+	stmt        string
+	values      []interface{}
+	ctx         context.Context
+	pageSize    int
+	pageState   []byte
+	consistency gocql.Consistency
+	serial      gocql.SerialConsistency
+	idempotent  bool
+	retry       gocql.RetryPolicy
+	observer    gocql.QueryObserver
+	attempts    int
+}
+
+func (q *Query) WithContext(ctx context.Context) c.CqlQuery {
+	r := *q
+	r.ctx = ctx
+	return &r
+}
+func (q *Query) Consistency(cons gocql.Consistency) c.CqlQuery {
+	q.consistency = cons
+	return q
+}
+func (q *Query) SerialConsistency(cons gocql.SerialConsistency) c.CqlQuery {
+	q.serial = cons
+	return q
+}
+func (q *Query) Idempotent(value bool) c.CqlQuery {
+	q.idempotent = value
+	return q
+}
+
+// RetryPolicy sets the retry policy, which is applied as by the driver: the failed idempotent statements are executed again while the policy allows it.
+func (q *Query) RetryPolicy(policy gocql.RetryPolicy) c.CqlQuery {
+	q.retry = policy
+	return q
+}
+
+// Observer sets the observer, which is called after each attempt, as by the driver.
+func (q *Query) Observer(observer gocql.QueryObserver) c.CqlQuery {
+	q.observer = observer
+	return q
+}
+func (q *Query) Attempts() int {
+	return q.attempts
+}
+func (q *Query) SetConsistency(cons gocql.Consistency) {
+	q.consistency = cons
+}
+func (q *Query) GetConsistency() gocql.Consistency {
+	return q.consistency
+}
+func (q *Query) Context() context.Context {
+	if q.ctx == nil {
+		return context.Background()
+	}
+	return q.ctx
+}
+func (q *Query) PageSize(n int) c.CqlQuery {
+	q.pageSize = n
+	return q
+}
+func (q *Query) PageState(state []byte) c.CqlQuery {
+	q.pageState = state
+	return q
+}
+func (q *Query) Statement() string {
+	return q.stmt
+}
+func (q *Query) Values() []interface{} {
+	return q.values
+}
+func (q *Query) run() (Result, error) {
+	q.attempts = 0
+	r := retry(q.retry, q, q.idempotent, func() Result {
+		if q.ctx != nil && q.ctx.Err() != nil {
+			return Result{Err: q.ctx.Err()}
+		}
+		start := time.Now()
+		deadline, _ := q.Context().Deadline()
+		r := q.session.execute(Executed{Query: q.stmt, Params: q.values, Consistency: q.consistency, SerialConsistency: q.serial, Idempotent: q.idempotent, PageSize: q.pageSize, Deadline: deadline})
+		if q.observer != nil {
+			q.observer.ObserveQuery(q.Context(), gocql.ObservedQuery{Statement: q.stmt, Values: q.values, Start: start, End: time.Now(), Rows: len(r.Rows), Err: r.Err, Attempt: q.attempts - 1})
+		}
+		return r
+	})
+	return r, r.Err
+}
+
+type retryable interface {
+	gocql.RetryableQuery
+	attempt()
+}
+
+// retry executes the statement, and executes it again while it fails, it is idempotent and the policy allows it.
+// As the driver, it calls Attempt first, then GetRetryType with the error.
+func retry(policy gocql.RetryPolicy, q retryable, idempotent bool, execute func() Result) Result {
+	for {
+		q.attempt()
+		r := execute()
+		if r.Err == nil || policy == nil || !idempotent || !policy.Attempt(q) {
+			return r
+		}
+		switch policy.GetRetryType(r.Err) {
+		case gocql.Rethrow, gocql.Ignore:
+			return r
+		}
+	}
+}
+func (q *Query) attempt() {
+	q.attempts++
+}
+func (q *Query) Exec() error {
+	_, err := q.run()
+	return err
+}
+func (q *Query) Scan(dest ...interface{}) error {
+	iter := q.Iter()
+	if !iter.Scan(dest...) {
+		if err := iter.Close(); err != nil {
+			return err
+		}
+		return gocql.ErrNotFound
+	}
+	return iter.Close()
+}
+
+// Iter returns the rows of the page: if the page size is set, the page state is the offset of the next page.
+func (q *Query) Iter() c.Iter {
+	r, err := q.run()
+	if err != nil && len(r.Rows) == 0 {
+		return newIter(Result{}, err, 0)
+	}
+	offset := 0
+	if len(q.pageState) > 0 {
+		offset, _ = strconv.Atoi(string(q.pageState))
+	}
+	rows := r.Rows
+	if offset > len(rows) {
+		offset = len(rows)
+	}
+	rows = rows[offset:]
+	var next []byte
+	if q.pageSize > 0 && len(rows) > q.pageSize {
+		rows = rows[:q.pageSize]
+		next = []byte(strconv.Itoa(offset + q.pageSize))
+	}
+	iter := newIter(Result{Columns: r.Columns, Rows: rows, Err: err}, nil, 0)
+	iter.pageState = next
+	return iter
+}
+func (q *Query) MapScanCAS(dest map[string]interface{}) (bool, error) {
+	r, err := q.run()
+	if err != nil {
+		return false, err
+	}
+	if r.NotApplied {
+		fillMap(r, 0, dest)
+		return false, nil
+	}
+	return true, nil
+}
+
+// Batch is the c.Batch of the fake session.
+type Batch struct {
+	typ         gocql.BatchType
+	stmts       []c.Statement
+	ctx         context.Context
+	consistency gocql.Consistency
+	serial      gocql.SerialConsistency
+	idempotent  bool
+	retry       gocql.RetryPolicy
+	observer    gocql.BatchObserver
+	attempts    int
+}
+
+func (b *Batch) Query(stmt string, args ...interface{}) {
+	b.stmts = append(b.stmts, c.Statement{Query: stmt, Params: args})
+}
+func (b *Batch) Statements() []c.Statement {
+	return b.stmts
+}
+func (b *Batch) Size() int {
+	return len(b.stmts)
+}
+func (b *Batch) Type() gocql.BatchType {
+	return b.typ
+}
+func (b *Batch) Idempotent(value bool) c.Batch {
+	b.idempotent = value
+	return b
+}
+func (b *Batch) WithContext(ctx context.Context) c.Batch {
+	b.ctx = ctx
+	return b
+}
+func (b *Batch) Consistency(cons gocql.Consistency) c.Batch {
+	b.consistency = cons
+	return b
+}
+func (b *Batch) SerialConsistency(cons gocql.SerialConsistency) c.Batch {
+	b.serial = cons
+	return b
+}
+func (b *Batch) RetryPolicy(policy gocql.RetryPolicy) c.Batch {
+	b.retry = policy
+	return b
+}
+func (b *Batch) Observer(observer gocql.BatchObserver) c.Batch {
+	b.observer = observer
+	return b
+}
+func (b *Batch) Attempts() int {
+	return b.attempts
+}
+func (b *Batch) SetConsistency(cons gocql.Consistency) {
+	b.consistency = cons
+}
+func (b *Batch) GetConsistency() gocql.Consistency {
+	return b.consistency
+}
+func (b *Batch) Context() context.Context {
+	if b.ctx == nil {
+		return context.Background()
+	}
+	return b.ctx
+}
+func (b *Batch) attempt() {
+	b.attempts++
+}
+
+// Iter is the c.Iter of the scripted rows. The values are assigned to the destinations, converted if needed;
+// the destinations which implement c.ValueSetter, such as the nested structs, receive the values as they are.
+type Iter struct {
+	result    Result
+	err       error
+	index     int
+	pageState []byte
+}
+
+func newIter(result Result, err error, index int) *Iter {
+	return &Iter{result: result, err: err, index: index}
+}
+func (it *Iter) Columns() []gocql.ColumnInfo {
+	columns := make([]gocql.ColumnInfo, 0, len(it.result.Columns))
+	for _, name := range it.result.Columns {
+		columns = append(columns, gocql.ColumnInfo{Name: name})
+	}
+	return columns
+}
+func (it *Iter) Scan(dest ...interface{}) bool {
+	if it.err != nil {
+		return false
+	}
+	if it.index >= len(it.result.Rows) {
+		it.err = it.result.Err
+		return false
+	}
+	row := it.result.Rows[it.index]
+	it.index++
+	for i, d := range dest {
+		var v interface{}
+		if i < len(row) {
+			v = row[i]
+		}
+		if err := assign(d, v); err != nil {
+			it.err = fmt.Errorf("%s: %w", it.column(i), err)
+			return false
+		}
+	}
+	return true
+}
+func (it *Iter) column(i int) string {
+	if i < len(it.result.Columns) {
+		return it.result.Columns[i]
+	}
+	return strconv.Itoa(i)
+}
+func (it *Iter) MapScan(m map[string]interface{}) bool {
+	if it.err != nil {
+		return false
+	}
+	if it.index >= len(it.result.Rows) {
+		it.err = it.result.Err
+		return false
+	}
+	fillMap(it.result, it.index, m)
+	it.index++
+	return true
+}
+func (it *Iter) RowData() (gocql.RowData, error) {
+	values := make([]interface{}, 0, len(it.result.Columns))
+	for range it.result.Columns {
+		values = append(values, new(interface{}))
+	}
+	return gocql.RowData{Columns: append([]string{}, it.result.Columns...), Values: values}, it.err
+}
+func (it *Iter) PageState() []byte {
+	return it.pageState
+}
+func (it *Iter) Close() error {
+	return it.err
+}
+func fillMap(r Result, index int, m map[string]interface{}) {
+	if index >= len(r.Rows) {
+		return
+	}
+	for i, col := range r.Columns {
+		if i < len(r.Rows[index]) {
+			m[col] = r.Rows[index][i]
+		}
+	}
+}
+
+// assign sets the value to the pointer dest, nil sets the zero value.
+func assign(dest interface{}, v interface{}) error {
+	if s, ok := dest.(c.ValueSetter); ok {
+		return s.SetValue(v)
+	}
+	d := reflect.ValueOf(dest)
+	if d.Kind() != reflect.Ptr || d.IsNil() {
+		return errors.New("the destination must be a non-nil pointer")
+	}
+	e := d.Elem()
+	if v == nil {
+		e.Set(reflect.Zero(e.Type()))
+		return nil
+	}
+	sv := reflect.ValueOf(v)
+	for e.Kind() == reflect.Ptr && !sv.Type().AssignableTo(e.Type()) {
+		if e.IsNil() {
+			e.Set(reflect.New(e.Type().Elem()))
+		}
+		e = e.Elem()
+	}
+	switch {
+	case sv.Type().AssignableTo(e.Type()):
+		e.Set(sv)
+	case sv.Type().ConvertibleTo(e.Type()):
+		e.Set(sv.Convert(e.Type()))
+	default:
+		return fmt.Errorf("cannot assign %T to %v", v, e.Type())
+	}
+	return nil
+}
@@ -0,0 +1,62 @@
+package cqltest_test
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/apache/cassandra-gocql-driver"
+
+	c "github.com/core-go/cassandra"
+	"github.com/core-go/cassandra/cqltest"
+)
+
+// policy records the calls of the session, and allows max attempts.
+type policy struct {
+	max   int
+	typ   gocql.RetryType
+	calls []string
+}
+
+func (p *policy) Attempt(q gocql.RetryableQuery) bool {
+	p.calls = append(p.calls, "Attempt")
+	return q.Attempts() <= p.max
+}
+func (p *policy) GetRetryType(error) gocql.RetryType {
+	p.calls = append(p.calls, "GetRetryType")
+	return p.typ
+}
+
+func TestRetry(t *testing.T) {
+	failure := errors.New("timeout")
+	tests := []struct {
+		name       string
+		max        int
+		typ        gocql.RetryType
+		idempotent bool
+		executed   int
+		calls      []string
+	}{
+		{"retried", 2, gocql.RetryNextHost, true, 3, []string{"Attempt", "GetRetryType", "Attempt", "GetRetryType", "Attempt"}},
+		{"rethrown", 2, gocql.Rethrow, true, 1, []string{"Attempt", "GetRetryType"}},
+		{"ignored", 2, gocql.Ignore, true, 1, []string{"Attempt", "GetRetryType"}},
+		{"not idempotent", 2, gocql.RetryNextHost, false, 1, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ses := cqltest.NewSession().Fail("update users", failure)
+			p := &policy{max: tt.max, typ: tt.typ}
+			ctx := c.WithQueryOptions(context.Background(), c.WithRetryPolicy(p), c.WithIdempotent(tt.idempotent))
+			if err := c.ExecContext(ctx, ses, "update users set name = ? where id = ?", "Peter", "1"); !errors.Is(err, failure) {
+				t.Fatalf("ExecContext() error = %v, want %v", err, failure)
+			}
+			if n := len(ses.Executed()); n != tt.executed {
+				t.Fatalf("executed %d times, want %d", n, tt.executed)
+			}
+			if !reflect.DeepEqual(p.calls, tt.calls) {
+				t.Fatalf("calls = %v, want %v", p.calls, tt.calls)
+			}
+		})
+	}
+}
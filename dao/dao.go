@@ -23,7 +23,13 @@ func NewDao[T any, K any](db *gocql.ClusterConfig, tableName string) (*Dao[T, K]
 	return NewDaoWithVersion[T, K](db, tableName, "")
 }
 func NewDaoWithVersion[T any, K any](db *gocql.ClusterConfig, tableName string, versionField string) (*Dao[T, K], error) {
-	adapter, err := NewWriterWithVersion[*T](db, tableName, versionField)
+	return NewDaoWithVersionAndProvider[T, K](q.GetSessionProvider(db), tableName, versionField)
+}
+func NewDaoWithProvider[T any, K any](db q.SessionProvider, tableName string) (*Dao[T, K], error) {
+	return NewDaoWithVersionAndProvider[T, K](db, tableName, "")
+}
+func NewDaoWithVersionAndProvider[T any, K any](db q.SessionProvider, tableName string, versionField string) (*Dao[T, K], error) {
+	adapter, err := NewWriterWithVersionAndProvider[*T](db, tableName, versionField)
 	if err != nil {
 		return nil, err
 	}
@@ -56,12 +62,12 @@ func NewDaoWithVersion[T any, K any](db *gocql.ClusterConfig, tableName string,
 func (a *Dao[T, K]) All(ctx context.Context) ([]T, error) {
 	var objs []T
 	query := fmt.Sprintf("select %s from %s", a.Fields, a.Table)
-	ses, err := a.DB.CreateSession()
+	ctx = q.WithDefaultOptions(ctx, a.Options)
+	ses, err := a.DB.Session()
 	if err != nil {
 		return objs, err
 	}
-	defer ses.Close()
-	err = q.Query(ses, a.Map, &objs, query)
+	err = q.QueryContext(ctx, ses, a.Map, &objs, query)
 	return objs, err
 }
 func toMap(obj interface{}) (map[string]interface{}, error) {
@@ -89,17 +95,38 @@ func (a *Dao[T, K]) Load(ctx context.Context, id K) (*T, error) {
 	var objs []T
 	queryAll := fmt.Sprintf("select %s from %s ", a.Fields, a.Table)
 	query, args := q.BuildFindById(queryAll, ip, a.JsonColumnMap, a.Schema.SKeys)
-	ses, err := a.DB.CreateSession()
+	ctx = q.WithDefaultOptions(ctx, a.Options)
+	ses, err := a.DB.Session()
 	if err != nil {
 		return nil, err
 	}
-	defer ses.Close()
-	err = q.Query(ses, a.Map, &objs, query, args...)
+	err = q.QueryContext(ctx, ses, a.Map, &objs, query, args...)
 	if len(objs) > 0 {
 		return &objs[0], nil
 	}
 	return nil, nil
 }
+
+// LoadMany loads the models of the ids, the result is aligned with ids: it has len(ids) models, nil for the missing ids.
+// The ids are grouped by partition and loaded by concurrent queries.
+// The result is not a map by id, because K can be a map of the composite keys, which is not comparable;
+// use q.MapByIds to map it by a comparable K, each id is in the map, nil for the missing ids.
+func (a *Dao[T, K]) LoadMany(ctx context.Context, ids []K) ([]*T, error) {
+	keys := make([]interface{}, 0, len(ids))
+	for _, id := range ids {
+		ip, err := a.getId(id)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, ip)
+	}
+	ctx = q.WithDefaultOptions(ctx, a.Options)
+	ses, err := a.DB.Session()
+	if err != nil {
+		return nil, err
+	}
+	return q.LoadMany[T](ctx, ses, a.Table, a.Map, keys)
+}
 func (a *Dao[T, K]) Exist(ctx context.Context, id K) (bool, error) {
 	ip, er0 := a.getId(id)
 	if er0 != nil {
@@ -107,12 +134,12 @@ func (a *Dao[T, K]) Exist(ctx context.Context, id K) (bool, error) {
 	}
 	query := fmt.Sprintf("select %s from %s ", a.Schema.SColumns[0], a.Table)
 	query1, args := q.BuildFindById(query, ip, a.JsonColumnMap, a.Schema.SKeys)
-	ses, err := a.DB.CreateSession()
+	ctx = q.WithDefaultOptions(ctx, a.Options)
+	ses, err := a.DB.Session()
 	if err != nil {
 		return false, err
 	}
-	defer ses.Close()
-	res, err := q.QueryMap(ses, nil, query1, args...)
+	res, err := q.QueryMapContext(ctx, ses, nil, query1, args...)
 	if err != nil {
 		return false, err
 	}
@@ -121,19 +148,46 @@ func (a *Dao[T, K]) Exist(ctx context.Context, id K) (bool, error) {
 	}
 	return false, nil
 }
+
+// Delete deletes the row of the id, K is the single key, or a struct or a map of all key parts.
 func (a *Dao[T, K]) Delete(ctx context.Context, id K) (int64, error) {
+	return a.DeleteColumns(ctx, id)
+}
+
+// DeleteColumns sets the columns of the row to null, by json names or column names. If no column is passed, the row is deleted.
+func (a *Dao[T, K]) DeleteColumns(ctx context.Context, id K, columns ...string) (int64, error) {
 	ip, er0 := a.getId(id)
 	if er0 != nil {
 		return -1, er0
 	}
-	query := fmt.Sprintf("delete from %s ", a.Table)
-	query1, args := q.BuildFindById(query, ip, a.JsonColumnMap, a.Schema.SKeys)
-	ses, err := a.DB.CreateSession()
+	ctx = q.WithDefaultOptions(ctx, a.Options)
+	query, args, er1 := q.BuildToDeleteById(a.Table, ip, a.Schema, q.GetUsing(ctx, a.Schema), columns...)
+	if er1 != nil {
+		return -1, er1
+	}
+	return a.execDelete(ctx, query, args)
+}
+
+// DeleteByPartition deletes all rows of the partition. If the partition key has one column, partition can be the value, otherwise it must be a map or a struct.
+func (a *Dao[T, K]) DeleteByPartition(ctx context.Context, partition interface{}) (int64, error) {
+	return a.DeleteRange(ctx, partition)
+}
+
+// DeleteRange deletes the rows of the partition in the range of the clustering columns, such as q.Condition{"created", ">=", from}.
+func (a *Dao[T, K]) DeleteRange(ctx context.Context, partition interface{}, conditions ...q.Condition) (int64, error) {
+	ctx = q.WithDefaultOptions(ctx, a.Options)
+	query, args, er1 := q.BuildToDeleteByPartition(a.Table, partition, a.Schema, q.GetUsing(ctx, a.Schema), conditions...)
+	if er1 != nil {
+		return -1, er1
+	}
+	return a.execDelete(ctx, query, args)
+}
+func (a *Dao[T, K]) execDelete(ctx context.Context, query string, args []interface{}) (int64, error) {
+	ses, err := a.DB.Session()
 	if err != nil {
 		return 0, err
 	}
-	defer ses.Close()
-	er2 := q.Exec(ses, query1, args...)
+	er2 := q.ExecContext(ctx, ses, query, args...)
 	if er2 == nil {
 		return 1, er2
 	}
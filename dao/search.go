@@ -19,7 +19,13 @@ func NewSearchAdapter[T any, K any, F any](db *gocql.ClusterConfig, table string
 	return NewSearchAdapterWithVersion[T, K, F](db, table, buildQuery, "", options...)
 }
 func NewSearchAdapterWithVersion[T any, K any, F any](db *gocql.ClusterConfig, table string, buildQuery func(F) (string, []interface{}), versionField string, opts ...func(*T)) (*SearchAdapter[T, K, F], error) {
-	dao, err := NewDaoWithVersion[T, K](db, table, versionField)
+	return NewSearchAdapterWithVersionAndProvider[T, K, F](q.GetSessionProvider(db), table, buildQuery, versionField, opts...)
+}
+func NewSearchAdapterWithProvider[T any, K any, F any](db q.SessionProvider, table string, buildQuery func(F) (string, []interface{}), options ...func(*T)) (*SearchAdapter[T, K, F], error) {
+	return NewSearchAdapterWithVersionAndProvider[T, K, F](db, table, buildQuery, "", options...)
+}
+func NewSearchAdapterWithVersionAndProvider[T any, K any, F any](db q.SessionProvider, table string, buildQuery func(F) (string, []interface{}), versionField string, opts ...func(*T)) (*SearchAdapter[T, K, F], error) {
+	dao, err := NewDaoWithVersionAndProvider[T, K](db, table, versionField)
 	if err != nil {
 		return nil, err
 	}
@@ -43,13 +49,12 @@ func NewSearchAdapterWithVersion[T any, K any, F any](db *gocql.ClusterConfig, t
 func (b *SearchAdapter[T, K, F]) Search(ctx context.Context, filter F, limit int64, next string) ([]T, string, error) {
 	var objs []T
 	sql, params := b.BuildQuery(filter)
-	ses, err := b.DB.CreateSession()
-	defer ses.Close()
-
+	ctx = q.WithDefaultOptions(ctx, b.Options)
+	ses, err := b.DB.Session()
 	if err != nil {
 		return objs, "", err
 	}
-	nextPageToken, er2 := q.QueryWithMap(ses, b.Map, &objs, sql, params, limit, next)
+	nextPageToken, er2 := q.QueryWithToken(ctx, ses, b.Map, &objs, sql, params, limit, next)
 	if b.Mp != nil {
 		l := len(objs)
 		for i := 0; i < l; i++ {
@@ -0,0 +1,304 @@
+package cassandra
+
+import (
+	"fmt"
+	"math/big"
+	"net"
+	"reflect"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/apache/cassandra-gocql-driver"
+)
+
+var (
+	uuidType     = reflect.TypeOf(gocql.UUID{})
+	durationType = reflect.TypeOf(gocql.Duration{})
+	bigIntType   = reflect.TypeOf(big.Int{})
+	ipType       = reflect.TypeOf(net.IP{})
+)
+
+// TableOptions are the options of "create table ... with ...".
+// DefaultTTL overrides the ttl of the schema; Compaction is the map of the compaction options, such as {"class": "LeveledCompactionStrategy"}.
+type TableOptions struct {
+	Keyspace   string
+	DefaultTTL int
+	Compaction map[string]string
+}
+
+// BuildCreateTable builds "create table if not exists" of the model: the types of the columns are the type tag option, or inferred from the Go types,
+// the primary key is "((partition keys), clustering keys)", with the clustering order, the default ttl and the compaction options.
+func BuildCreateTable(table string, modelType reflect.Type, options ...TableOptions) (string, error) {
+	var opts TableOptions
+	if len(options) > 0 {
+		opts = options[0]
+	}
+	m := modelType
+	if m.Kind() == reflect.Ptr {
+		m = m.Elem()
+	}
+	schema := GetSchema(m)
+	if len(schema.PartitionKeys) == 0 {
+		return "", fmt.Errorf("%v has no primary key", m.Name())
+	}
+	columns := make([]string, 0)
+	for _, f := range schema.Columns {
+		if IsMetadataColumn(f.Column) {
+			continue
+		}
+		t, err := getColumnType(f, m.Field(f.Index).Type)
+		if err != nil {
+			return "", err
+		}
+		if f.Static {
+			t += " static"
+		}
+		columns = append(columns, f.Column+" "+t)
+	}
+	partitionKeys := make([]string, 0)
+	for _, k := range schema.PartitionKeys {
+		partitionKeys = append(partitionKeys, k.Column)
+	}
+	keys := []string{strings.Join(partitionKeys, ", ")}
+	if len(partitionKeys) > 1 {
+		keys[0] = "(" + keys[0] + ")"
+	}
+	orders := make([]string, 0)
+	for _, k := range schema.ClusteringKeys {
+		keys = append(keys, k.Column)
+		order := asc
+		if k.Order == desc {
+			order = desc
+		}
+		orders = append(orders, k.Column+" "+order)
+	}
+	columns = append(columns, "primary key ("+strings.Join(keys, ", ")+")")
+	with := make([]string, 0)
+	if len(orders) > 0 {
+		with = append(with, "clustering order by ("+strings.Join(orders, ", ")+")")
+	}
+	ttl := opts.DefaultTTL
+	if ttl <= 0 {
+		ttl = schema.TTL
+	}
+	if ttl > 0 {
+		with = append(with, fmt.Sprintf("default_time_to_live = %d", ttl))
+	}
+	if len(opts.Compaction) > 0 {
+		with = append(with, "compaction = "+buildOptionMap(opts.Compaction))
+	}
+	sql := fmt.Sprintf("create table if not exists %s (\n  %s\n)", qualify(opts.Keyspace, table), strings.Join(columns, ",\n  "))
+	if len(with) > 0 {
+		sql += " with " + strings.Join(with, " and ")
+	}
+	return sql, nil
+}
+
+// BuildCreateTypes builds "create type if not exists" of the nested structs of the model, which are mapped to user-defined types.
+// A type is created before the types which use it. The name of the type is the snake case of the struct name.
+func BuildCreateTypes(modelType reflect.Type, keyspace string) ([]string, error) {
+	m := modelType
+	if m.Kind() == reflect.Ptr {
+		m = m.Elem()
+	}
+	stmts := make([]string, 0)
+	visited := make(map[reflect.Type]bool)
+	for _, f := range GetSchema(m).Columns {
+		if f.UDT && len(f.Type) == 0 {
+			if err := buildCreateType(nestedType(m.Field(f.Index).Type), keyspace, visited, &stmts); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return stmts, nil
+}
+
+// BuildCreateStatements builds the statements of BuildCreateTypes, then the statement of BuildCreateTable.
+func BuildCreateStatements(table string, modelType reflect.Type, options ...TableOptions) ([]string, error) {
+	var keyspace string
+	if len(options) > 0 {
+		keyspace = options[0].Keyspace
+	}
+	stmts, err := BuildCreateTypes(modelType, keyspace)
+	if err != nil {
+		return nil, err
+	}
+	sql, err := BuildCreateTable(table, modelType, options...)
+	if err != nil {
+		return nil, err
+	}
+	return append(stmts, sql), nil
+}
+func buildCreateType(t reflect.Type, keyspace string, visited map[reflect.Type]bool, stmts *[]string) error {
+	if visited[t] {
+		return nil
+	}
+	visited[t] = true
+	fields := make([]string, 0)
+	schema := GetSchema(t)
+	for _, nf := range getNestedFields(t, false) {
+		ft := t.Field(nf.Index).Type
+		var typ string
+		if f, ok := schema.Fields[nf.Name]; ok && len(f.Type) > 0 {
+			typ = f.Type
+		} else {
+			if !nf.Tuple && IsUDTType(ft) {
+				if err := buildCreateType(nestedType(ft), keyspace, visited, stmts); err != nil {
+					return err
+				}
+			}
+			s, err := GetCQLType(ft, nf.Tuple)
+			if err != nil {
+				return fmt.Errorf("%v.%v: %w", t.Name(), t.Field(nf.Index).Name, err)
+			}
+			typ = s
+		}
+		fields = append(fields, nf.Name+" "+typ)
+	}
+	*stmts = append(*stmts, fmt.Sprintf("create type if not exists %s (\n  %s\n)", qualify(keyspace, GetTypeName(t)), strings.Join(fields, ",\n  ")))
+	return nil
+}
+func getColumnType(f *FieldDB, t reflect.Type) (string, error) {
+	if len(f.Type) > 0 {
+		return f.Type, nil
+	}
+	if f.Counter {
+		return "counter", nil
+	}
+	if f.Scale > 0 {
+		switch indirectType(t).Kind() {
+		case reflect.Float32, reflect.Float64, reflect.String:
+			return "decimal", nil
+		}
+	}
+	s, err := GetCQLType(t, f.Tuple)
+	if err != nil {
+		return "", fmt.Errorf("%v: %w", f.Field, err)
+	}
+	return s, nil
+}
+
+// GetCQLType infers the CQL type of the Go type. The structs are frozen user-defined types, or tuples if tuple is true;
+// the collections inside collections are frozen, map[K]struct{} and map[K]bool are sets.
+func GetCQLType(t reflect.Type, tuple bool) (string, error) {
+	return getCQLType(t, tuple, false)
+}
+func getCQLType(t reflect.Type, tuple bool, nested bool) (string, error) {
+	t = indirectType(t)
+	switch t {
+	case timeType:
+		return "timestamp", nil
+	case uuidType:
+		return "uuid", nil
+	case durationType, reflect.TypeOf(time.Duration(0)):
+		return "duration", nil
+	case bigIntType:
+		return "varint", nil
+	case ipType:
+		return "inet", nil
+	}
+	if t.PkgPath() == "gopkg.in/inf.v0" && t.Name() == "Dec" {
+		return "decimal", nil
+	}
+	switch t.Kind() {
+	case reflect.String:
+		return "text", nil
+	case reflect.Bool:
+		return "boolean", nil
+	case reflect.Int8:
+		return "tinyint", nil
+	case reflect.Int16:
+		return "smallint", nil
+	case reflect.Int32:
+		return "int", nil
+	case reflect.Int, reflect.Int64, reflect.Uint8, reflect.Uint16, reflect.Uint32:
+		return "bigint", nil
+	case reflect.Uint, reflect.Uint64:
+		return "varint", nil
+	case reflect.Float32:
+		return "float", nil
+	case reflect.Float64:
+		return "double", nil
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return "blob", nil
+		}
+		e, err := getCQLType(t.Elem(), tuple, true)
+		if err != nil {
+			return "", err
+		}
+		return frozen("list<"+e+">", nested), nil
+	case reflect.Map:
+		k, err := getCQLType(t.Key(), false, true)
+		if err != nil {
+			return "", err
+		}
+		if t.Elem().Kind() == reflect.Bool || t.Elem().Kind() == reflect.Struct && t.Elem().NumField() == 0 {
+			return frozen("set<"+k+">", nested), nil
+		}
+		v, err := getCQLType(t.Elem(), tuple, true)
+		if err != nil {
+			return "", err
+		}
+		return frozen("map<"+k+", "+v+">", nested), nil
+	case reflect.Struct:
+		if tuple {
+			items := make([]string, 0)
+			for _, nf := range getNestedFields(t, true) {
+				s, err := getCQLType(t.Field(nf.Index).Type, nf.Tuple, true)
+				if err != nil {
+					return "", err
+				}
+				items = append(items, s)
+			}
+			return "tuple<" + strings.Join(items, ", ") + ">", nil
+		}
+		if IsUDTType(t) {
+			return "frozen<" + GetTypeName(t) + ">", nil
+		}
+	}
+	return "", fmt.Errorf("cannot infer the cql type of %v", t)
+}
+func indirectType(t reflect.Type) reflect.Type {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	return t
+}
+func frozen(t string, nested bool) string {
+	if nested {
+		return "frozen<" + t + ">"
+	}
+	return t
+}
+
+// GetTypeName returns the name of the user-defined type of the struct, which is the snake case of the struct name, such as "user_address" of UserAddress.
+func GetTypeName(t reflect.Type) string {
+	var b strings.Builder
+	runes := []rune(t.Name())
+	for i, r := range runes {
+		if unicode.IsUpper(r) {
+			if i > 0 && (unicode.IsLower(runes[i-1]) || i+1 < len(runes) && unicode.IsLower(runes[i+1])) {
+				b.WriteByte('_')
+			}
+			b.WriteRune(unicode.ToLower(r))
+		} else {
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+func qualify(keyspace string, name string) string {
+	if len(keyspace) == 0 || strings.Contains(name, ".") {
+		return name
+	}
+	return keyspace + "." + name
+}
+func buildOptionMap(options map[string]string) string {
+	items := make([]string, 0, len(options))
+	for _, k := range sortedKeys(options) {
+		items = append(items, fmt.Sprintf("'%s': '%s'", k, strings.ReplaceAll(options[k], "'", "''")))
+	}
+	return "{" + strings.Join(items, ", ") + "}"
+}
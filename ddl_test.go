@@ -0,0 +1,128 @@
+package cassandra_test
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	c "github.com/core-go/cassandra"
+)
+
+type product struct {
+	Sku      string              `cql:"sku,partition_key"`
+	Name     string              `cql:"name"`
+	Price    float64             `cql:"price,scale=2"`
+	Tags     map[string]struct{} `cql:"tags"`
+	Sizes    []int32             `cql:"sizes"`
+	Stock    map[string]int64    `cql:"stock"`
+	Variants [][]string          `cql:"variants"`
+	Labels   map[string][]string `cql:"labels"`
+	Image    []byte              `cql:"image"`
+	Updated  *time.Time          `cql:"updated"`
+	Score    float64             `cql:"score,type=float"`
+}
+type contactInfo struct {
+	Email   string   `cql:"email"`
+	Address *address `cql:"address"`
+}
+type store struct {
+	Id       string        `cql:"id,partition_key"`
+	Contacts []contactInfo `cql:"contacts"`
+	Main     address       `cql:"main"`
+}
+type note struct {
+	Text string `cql:"text"`
+}
+
+func TestBuildCreateTable(t *testing.T) {
+	tests := []struct {
+		name    string
+		model   interface{}
+		options c.TableOptions
+		want    string
+	}{
+		{"partition and clustering keys, order and static", message{}, c.TableOptions{Keyspace: "chat"}, `create table if not exists chat.messages (
+  tenant text,
+  room text,
+  sent timestamp,
+  id text,
+  topic text static,
+  content text,
+  primary key ((tenant, room), sent, id)
+) with clustering order by (sent desc, id asc)`},
+		{"counters", pageView{}, c.TableOptions{}, `create table if not exists messages (
+  site text,
+  page text,
+  views counter,
+  clicks counter,
+  primary key (site, page)
+) with clustering order by (page asc)`},
+		{"frozen udt and tuple", customer{}, c.TableOptions{}, `create table if not exists messages (
+  id text,
+  home frozen<address>,
+  work frozen<address>,
+  location tuple<double,double>,
+  addresses list<frozen<address>>,
+  by_name map<text, frozen<address>>,
+  primary key (id)
+)`},
+		{"collections", product{}, c.TableOptions{DefaultTTL: 3600, Compaction: map[string]string{"class": "LeveledCompactionStrategy", "sstable_size_in_mb": "160"}}, `create table if not exists messages (
+  sku text,
+  name text,
+  price decimal,
+  tags set<text>,
+  sizes list<int>,
+  stock map<text, bigint>,
+  variants list<frozen<list<text>>>,
+  labels map<text, frozen<list<text>>>,
+  image blob,
+  updated timestamp,
+  score float,
+  primary key (sku)
+) with default_time_to_live = 3600 and compaction = {'class': 'LeveledCompactionStrategy', 'sstable_size_in_mb': '160'}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := c.BuildCreateTable("messages", reflect.TypeOf(tt.model), tt.options)
+			if err != nil || got != tt.want {
+				t.Fatalf("BuildCreateTable() = %v\n%s\nwant\n%s", err, got, tt.want)
+			}
+		})
+	}
+	if _, err := c.BuildCreateTable("notes", reflect.TypeOf(note{})); err == nil {
+		t.Fatal("BuildCreateTable() of a model without primary key must fail")
+	}
+}
+
+func TestBuildCreateTypes(t *testing.T) {
+	addressType := `create type if not exists shop.address (
+  street text,
+  city text,
+  geo frozen<tuple<double,double>>
+)`
+	tests := []struct {
+		name  string
+		model interface{}
+		want  []string
+	}{
+		{"udt", customer{}, []string{addressType}},
+		{"nested udt after the udt it uses", &store{}, []string{addressType, `create type if not exists shop.contact_info (
+  email text,
+  address frozen<address>
+)`}},
+		{"no udt", message{}, []string{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := c.BuildCreateTypes(reflect.TypeOf(tt.model), "shop")
+			if err != nil || !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("BuildCreateTypes() = %v\n%s\nwant\n%s", err, strings.Join(got, "\n"), strings.Join(tt.want, "\n"))
+			}
+		})
+	}
+	stmts, err := c.BuildCreateStatements("customers", reflect.TypeOf(customer{}), c.TableOptions{Keyspace: "shop"})
+	if err != nil || len(stmts) != 2 || stmts[0] != addressType || !strings.HasPrefix(stmts[1], "create table if not exists shop.customers (") {
+		t.Fatalf("BuildCreateStatements() = %v, %q, want the type before the table", err, stmts)
+	}
+}
@@ -0,0 +1,99 @@
+package cassandra
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// BuildToDeleteById builds "delete from table using timestamp ? where pk=? and ck=?" of all key columns.
+// The id is the value of the single key, or a map or a struct of all key parts, by json name, column name or field name.
+// If columns are passed, only these columns are deleted: "delete c1,c2 from table ...".
+func BuildToDeleteById(table string, id interface{}, schema *Schema, using *Using, columns ...string) (string, []interface{}, error) {
+	values, err := getKeyValues(schema, ToKeyMap(id))
+	if err != nil {
+		return "", nil, err
+	}
+	cols, err := getColumns(schema, columns)
+	if err != nil {
+		return "", nil, err
+	}
+	where := make([]string, 0)
+	for _, k := range orderedKeys(schema) {
+		where = append(where, k.Column+"="+BuildParam(0))
+	}
+	u, args := buildDeleteUsing(using)
+	query := fmt.Sprintf("delete %sfrom %s%s where %s", cols, table, u, strings.Join(where, " and "))
+	return query, append(args, values...), nil
+}
+
+// BuildToDeleteByPartition builds "delete from table where pk=?", or "delete from table where pk=? and ck>=? and ck<?" with the conditions on the clustering columns.
+func BuildToDeleteByPartition(table string, partition interface{}, schema *Schema, using *Using, conditions ...Condition) (string, []interface{}, error) {
+	u, args := buildDeleteUsing(using)
+	query, where, err := BuildFindByPartition("delete from "+table+u, ToKeyMap(partition), schema, conditions...)
+	if err != nil {
+		return "", nil, err
+	}
+	return query, append(args, where...), nil
+}
+
+// buildDeleteUsing builds "using timestamp ?", ttl is not allowed in delete.
+func buildDeleteUsing(using *Using) (string, []interface{}) {
+	if using == nil {
+		return "", nil
+	}
+	return BuildUsing(&Using{Timestamp: using.Timestamp})
+}
+func getColumns(schema *Schema, columns []string) (string, error) {
+	if len(columns) == 0 {
+		return "", nil
+	}
+	cols := make([]string, 0)
+	for _, c := range columns {
+		f, ok := getField(schema, c)
+		if !ok {
+			return "", fmt.Errorf("%s is not a column", c)
+		}
+		if f.Key {
+			return "", fmt.Errorf("%s is a key column, which cannot be deleted", c)
+		}
+		cols = append(cols, f.Column)
+	}
+	return strings.Join(cols, ",") + " ", nil
+}
+
+// ToKeyMap converts the struct of the keys to a map by json name, column name and field name; the other values are returned as is.
+func ToKeyMap(id interface{}) interface{} {
+	v := reflect.ValueOf(id)
+	if v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			return id
+		}
+		v = v.Elem()
+	}
+	if v.Kind() != reflect.Struct || v.Type() == timeType {
+		return id
+	}
+	t := v.Type()
+	m := make(map[string]interface{})
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if len(field.PkgPath) > 0 {
+			continue
+		}
+		value := reflect.Indirect(v.Field(i))
+		if !value.IsValid() {
+			continue
+		}
+		m[field.Name] = value.Interface()
+		if tag, ok := field.Tag.Lookup("json"); ok {
+			if name := strings.Split(tag, ",")[0]; len(name) > 0 && name != "-" {
+				m[name] = value.Interface()
+			}
+		}
+		if tag, ok := ParseTag(field); ok && len(tag.Column) > 0 {
+			m[tag.Column] = value.Interface()
+		}
+	}
+	return m
+}
@@ -0,0 +1,76 @@
+package cassandra_test
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	c "github.com/core-go/cassandra"
+	"github.com/core-go/cassandra/cqltest"
+)
+
+func TestDelete(t *testing.T) {
+	at := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	from := at.Add(-time.Hour)
+	stamp := time.UnixMicro(1700000000000000)
+	tests := []struct {
+		name   string
+		ctx    context.Context
+		delete func(ctx context.Context, w *c.Writer) (int64, error)
+		query  string
+		params []interface{}
+		fails  bool
+	}{
+		{"by map", context.Background(), func(ctx context.Context, w *c.Writer) (int64, error) {
+			return w.Delete(ctx, map[string]interface{}{"site": "a", "at": at})
+		}, "delete from events where site=? and at=?", []interface{}{"a", at}, false},
+		{"by struct", context.Background(), func(ctx context.Context, w *c.Writer) (int64, error) {
+			return w.Delete(ctx, event{Site: "a", At: at})
+		}, "delete from events where site=? and at=?", []interface{}{"a", at}, false},
+		{"columns", context.Background(), func(ctx context.Context, w *c.Writer) (int64, error) {
+			return w.DeleteColumns(ctx, event{Site: "a", At: at}, "name")
+		}, "delete name from events where site=? and at=?", []interface{}{"a", at}, false},
+		{"timestamp", c.WithQueryOptions(context.Background(), c.WithWriteTimestamp(stamp), c.WithTTL(time.Hour)), func(ctx context.Context, w *c.Writer) (int64, error) {
+			return w.Delete(ctx, event{Site: "a", At: at})
+		}, "delete from events using timestamp ? where site=? and at=?", []interface{}{int64(1700000000000000), "a", at}, false},
+		{"partition", context.Background(), func(ctx context.Context, w *c.Writer) (int64, error) {
+			return w.DeleteByPartition(ctx, "a")
+		}, "delete from events where site=?", []interface{}{"a"}, false},
+		{"range", context.Background(), func(ctx context.Context, w *c.Writer) (int64, error) {
+			return w.DeleteRange(ctx, "a", c.Condition{Column: "at", Operator: ">=", Value: from}, c.Condition{Column: "at", Operator: "<", Value: at})
+		}, "delete from events where site=? and at>=? and at<?", []interface{}{"a", from, at}, false},
+		{"missing clustering key", context.Background(), func(ctx context.Context, w *c.Writer) (int64, error) {
+			return w.Delete(ctx, map[string]interface{}{"site": "a"})
+		}, "", nil, true},
+		{"key column", context.Background(), func(ctx context.Context, w *c.Writer) (int64, error) {
+			return w.DeleteColumns(ctx, event{Site: "a", At: at}, "at")
+		}, "", nil, true},
+		{"unknown column", context.Background(), func(ctx context.Context, w *c.Writer) (int64, error) {
+			return w.DeleteColumns(ctx, event{Site: "a", At: at}, "title")
+		}, "", nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ses := cqltest.NewSession()
+			writer, err := c.NewWriterWithProvider(ses.Provider(), "events", reflect.TypeOf(event{}))
+			if err != nil {
+				t.Fatal(err)
+			}
+			res, err := tt.delete(tt.ctx, writer)
+			if tt.fails {
+				if err == nil || len(ses.Executed()) > 0 {
+					t.Fatalf("delete() = %d, %v, executed %v, want an error and nothing executed", res, err, ses.Executed())
+				}
+				return
+			}
+			if err != nil || res != 1 {
+				t.Fatalf("delete() = %d, %v", res, err)
+			}
+			executed := ses.Executed()
+			if len(executed) != 1 || executed[0].Query != tt.query || !reflect.DeepEqual(executed[0].Params, tt.params) {
+				t.Fatalf("delete() executed %v, want %q %v", executed, tt.query, tt.params)
+			}
+		})
+	}
+}
@@ -10,12 +10,15 @@ import (
 )
 
 type Handler struct {
-	DB        *gocql.ClusterConfig
+	DB        c.SessionProvider
 	Transform func(s string) string
 	Error     func(context.Context, string)
 }
 
 func NewHandler(db *gocql.ClusterConfig, transform func(s string) string, options ...func(context.Context, string)) *Handler {
+	return NewHandlerWithProvider(c.GetSessionProvider(db), transform, options...)
+}
+func NewHandlerWithProvider(db c.SessionProvider, transform func(s string) string, options ...func(context.Context, string)) *Handler {
 	var logError func(context.Context, string)
 	if len(options) >= 1 {
 		logError = options[0]
@@ -32,13 +35,12 @@ func (h *Handler) Exec(ctx echo.Context) error {
 		return er0
 	}
 	s.Params = c.ParseDates(s.Params, s.Dates)
-	session, err := h.DB.CreateSession()
+	session, err := h.DB.Session()
 	if err != nil {
 		handleError(ctx, http.StatusInternalServerError, err.Error(), h.Error, err)
 		return err
 	}
-	defer session.Close()
-	er1 := c.Exec(session, s.Query, s.Params...)
+	er1 := c.ExecContext(r.Context(), session, s.Query, s.Params...)
 	res := 0
 	if er1 == nil {
 		res = 1
@@ -59,13 +61,12 @@ func (h *Handler) Query(ctx echo.Context) error {
 		return er0
 	}
 	s.Params = c.ParseDates(s.Params, s.Dates)
-	session, err := h.DB.CreateSession()
+	session, err := h.DB.Session()
 	if err != nil {
 		handleError(ctx, http.StatusInternalServerError, err.Error(), h.Error, err)
 		return err
 	}
-	defer session.Close()
-	res, er1 := c.QueryMap(session, h.Transform, s.Query, s.Params...)
+	res, er1 := c.QueryMapContext(r.Context(), session, h.Transform, s.Query, s.Params...)
 	if er1 != nil {
 		handleError(ctx, http.StatusInternalServerError, er1.Error(), h.Error, er1)
 		return er1
@@ -89,12 +90,11 @@ func (h *Handler) ExecBatch(ctx echo.Context) error {
 		st.Params = c.ParseDates(s[i].Params, s[i].Dates)
 		b = append(b, st)
 	}
-	session, err := h.DB.CreateSession()
+	session, err := h.DB.Session()
 	if err != nil {
 		handleError(ctx, http.StatusInternalServerError, err.Error(), h.Error, err)
 		return err
 	}
-	defer session.Close()
 	res, er1 := c.ExecuteAll(r.Context(), session, b...)
 	if er1 != nil {
 		handleError(ctx, http.StatusInternalServerError, er1.Error(), h.Error, er1)
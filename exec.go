@@ -3,92 +3,265 @@ package cassandra
 import (
 	"context"
 	"github.com/apache/cassandra-gocql-driver"
+	"reflect"
 )
 
-func Exec(ses *gocql.Session, query string, values ...interface{}) error {
-	q := ses.Query(query, values...)
+type ConflictError struct {
+	Current map[string]interface{}
+}
+
+func (e *ConflictError) Error() string {
+	return "conditional statement was not applied"
+}
+
+func Exec(ses Session, query string, values ...interface{}) error {
+	return ExecContext(context.Background(), ses, query, values...)
+}
+func ExecContext(ctx context.Context, ses Session, query string, values ...interface{}) error {
+	q, cancel := ApplyOptions(ctx, ses.Query(query, values...))
+	defer cancel()
 	return q.Exec()
 }
-func ExecuteAll(ctx context.Context, ses *gocql.Session, stmts ...Statement) (int64, error) {
-	return ExecuteAllWithSize(ctx, ses, 5, stmts...)
-}
-func ExecuteAllWithSize(ctx context.Context, ses *gocql.Session, size int, stmts ...Statement) (int64, error) {
-	if stmts == nil || len(stmts) == 0 {
-		return 0, nil
-	}
-	batch := ses.NewBatch(gocql.UnloggedBatch).WithContext(ctx)
-	l := len(stmts)
-	for i := 0; i < l; i++ {
-		var args []interface{}
-		args = stmts[i].Params
-		batch.Entries = append(batch.Entries, gocql.BatchEntry{
-			Stmt:       stmts[i].Query,
-			Args:       args,
-			Idempotent: true,
-		})
-		if i%size == 0 || i == l-1 {
-			err := ses.ExecuteBatch(batch)
-			if err != nil {
-				return int64(i + 1), err
-			}
-			batch = ses.NewBatch(gocql.UnloggedBatch).WithContext(ctx)
+
+// ExecCAS executes a lightweight transaction (insert ... if not exists, update ... if ...).
+// If the statement is not applied, it returns false and the current row.
+func ExecCAS(ctx context.Context, ses Session, query string, values ...interface{}) (bool, map[string]interface{}, error) {
+	q, cancel := ApplyOptions(ctx, ses.Query(query, values...))
+	defer cancel()
+	current := make(map[string]interface{})
+	applied, err := q.MapScanCAS(current)
+	if err != nil {
+		return false, nil, err
+	}
+	if applied {
+		return true, nil, nil
+	}
+	return false, current, nil
+}
+
+// ExecuteCAS returns 1 if the lightweight transaction is applied, 0 if another writer won.
+func ExecuteCAS(ctx context.Context, ses Session, query string, values ...interface{}) (int64, error) {
+	applied, _, err := ExecCAS(ctx, ses, query, values...)
+	if err != nil {
+		return -1, err
+	}
+	if applied {
+		return 1, nil
+	}
+	return 0, nil
+}
+
+// ExecuteWithVersion executes the lightweight transaction of a versioned write, and returns 1 if it is applied,
+// or 0 and a ConflictError with the current row if another writer won.
+func ExecuteWithVersion(ctx context.Context, ses Session, query string, values ...interface{}) (int64, error) {
+	applied, current, err := ExecCAS(ctx, ses, query, values...)
+	if err != nil {
+		return -1, err
+	}
+	if !applied {
+		return 0, &ConflictError{Current: current}
+	}
+	return 1, nil
+}
+func execWithVersion(ctx context.Context, ses Session, versionIndex int, query string, values ...interface{}) error {
+	if versionIndex < 0 {
+		return ExecContext(ctx, ses, query, values...)
+	}
+	applied, current, err := ExecCAS(ctx, ses, query, values...)
+	if err != nil {
+		return err
+	}
+	if !applied {
+		return &ConflictError{Current: current}
+	}
+	return nil
+}
+
+// ExecuteAllCAS executes the conditional statements one by one, because a batch with conditions cannot span multiple partitions.
+// It returns the number of applied statements.
+func ExecuteAllCAS(ctx context.Context, ses Session, stmts ...Statement) (int64, error) {
+	var c int64
+	for _, stmt := range stmts {
+		applied, _, err := ExecCAS(ctx, ses, stmt.Query, stmt.Params...)
+		if err != nil {
+			return c, err
+		}
+		if applied {
+			c = c + 1
 		}
 	}
-	return int64(l), nil
+	return c, nil
+}
+func ExecuteAll(ctx context.Context, ses Session, stmts ...Statement) (int64, error) {
+	return ExecuteAllWithConfig(ctx, ses, gocql.UnloggedBatch, DefaultBatchConfig, stmts, nil)
+}
+func ExecuteAllWithSize(ctx context.Context, ses Session, size int, stmts ...Statement) (int64, error) {
+	return ExecuteAllWithType(ctx, ses, gocql.UnloggedBatch, size, stmts...)
+}
+
+// ExecuteAllWithType executes the statements in batches of at most size statements, limited by the bytes of DefaultBatchConfig.
+// The partitions of the statements are unknown, see ExecuteAllWithConfig to group them by partition.
+func ExecuteAllWithType(ctx context.Context, ses Session, typ gocql.BatchType, size int, stmts ...Statement) (int64, error) {
+	return ExecuteAllWithConfig(ctx, ses, typ, DefaultBatchConfig.WithSize(size), stmts, nil)
 }
 
-func Insert(ses *gocql.Session, table string, model interface{}, options ...*Schema) error {
-	return InsertWithVersion(ses, table, model, -1, options...)
+func Insert(ses Session, table string, model interface{}, options ...*Schema) error {
+	return InsertWithVersionContext(context.Background(), ses, table, model, -1, options...)
 }
-func InsertWithVersion(ses *gocql.Session, table string, model interface{}, versionIndex int, options ...*Schema) error {
+func InsertContext(ctx context.Context, ses Session, table string, model interface{}, options ...*Schema) error {
+	return InsertWithVersionContext(ctx, ses, table, model, -1, options...)
+}
+func InsertWithVersion(ses Session, table string, model interface{}, versionIndex int, options ...*Schema) error {
+	return InsertWithVersionContext(context.Background(), ses, table, model, versionIndex, options...)
+}
+func InsertWithVersionContext(ctx context.Context, ses Session, table string, model interface{}, versionIndex int, options ...*Schema) error {
 	query, values := BuildToInsertWithVersion(table, model, versionIndex, false, options...)
-	return Exec(ses, query, values...)
+	return execWithVersion(ctx, ses, versionIndex, query, values...)
+}
+func Update(ses Session, table string, model interface{}, options ...*Schema) error {
+	return UpdateWithVersionContext(context.Background(), ses, table, model, -1, options...)
+}
+func UpdateContext(ctx context.Context, ses Session, table string, model interface{}, options ...*Schema) error {
+	return UpdateWithVersionContext(ctx, ses, table, model, -1, options...)
 }
-func Update(ses *gocql.Session, table string, model interface{}, options ...*Schema) error {
-	return UpdateWithVersion(ses, table, model, -1, options...)
+func UpdateWithVersion(ses Session, table string, model interface{}, versionIndex int, options ...*Schema) error {
+	return UpdateWithVersionContext(context.Background(), ses, table, model, versionIndex, options...)
 }
-func UpdateWithVersion(ses *gocql.Session, table string, model interface{}, versionIndex int, options ...*Schema) error {
+func UpdateWithVersionContext(ctx context.Context, ses Session, table string, model interface{}, versionIndex int, options ...*Schema) error {
 	query, values := BuildToUpdateWithVersion(table, model, versionIndex, options...)
-	return Exec(ses, query, values...)
+	return execWithVersion(ctx, ses, versionIndex, query, values...)
 }
-func Save(ses *gocql.Session, table string, model interface{}, options ...*Schema) error {
+func Save(ses Session, table string, model interface{}, options ...*Schema) error {
+	return SaveContext(context.Background(), ses, table, model, options...)
+}
+func SaveContext(ctx context.Context, ses Session, table string, model interface{}, options ...*Schema) error {
 	query, values := BuildToSave(table, model, options...)
-	return Exec(ses, query, values...)
+	return ExecContext(ctx, ses, query, values...)
 }
 
-func InsertBatchWithSizeAndVersion(ctx context.Context, ses *gocql.Session, size int, table string, models interface{}, versionIndex int, options ...*Schema) (int64, error) {
+// InsertBatchWithConfig inserts the models in batches grouped by partition, see ExecuteAllWithConfig.
+// If versionIndex is set, the models are inserted one by one by lightweight transactions.
+func InsertBatchWithConfig(ctx context.Context, ses Session, config BatchConfig, table string, models interface{}, versionIndex int, options ...*Schema) (int64, error) {
 	s, err := BuildToInsertBatchWithVersion(table, models, versionIndex, false, options...)
 	if err != nil {
 		return -1, err
 	}
-	return ExecuteAllWithSize(ctx, ses, size, s...)
+	if versionIndex >= 0 {
+		return ExecuteAllCAS(ctx, ses, s...)
+	}
+	return executeModels(ctx, ses, config, models, s, options...)
+}
+
+// InsertBatchWithResult is InsertBatchWithConfig, which returns the result of each model. The models not applied by the lightweight transactions are failed by ConflictError.
+func InsertBatchWithResult(ctx context.Context, ses Session, config BatchConfig, table string, models interface{}, versionIndex int, options ...*Schema) (*BatchResult, error) {
+	s, err := BuildToInsertBatchWithVersion(table, models, versionIndex, false, options...)
+	if err != nil {
+		return nil, err
+	}
+	if versionIndex >= 0 {
+		return ExecuteAllCASWithResult(ctx, ses, config, s...)
+	}
+	return executeModelsWithResult(ctx, ses, config, models, s, options...)
 }
-func InsertBatchWithVersion(ctx context.Context, ses *gocql.Session, table string, models interface{}, versionIndex int, options ...*Schema) (int64, error) {
-	return InsertBatchWithSizeAndVersion(ctx, ses, 5, table, models, versionIndex, options...)
+func InsertBatchWithSizeAndVersion(ctx context.Context, ses Session, size int, table string, models interface{}, versionIndex int, options ...*Schema) (int64, error) {
+	return InsertBatchWithConfig(ctx, ses, DefaultBatchConfig.WithSize(size), table, models, versionIndex, options...)
 }
-func InsertBatch(ctx context.Context, ses *gocql.Session, table string, models interface{}, options ...*Schema) (int64, error) {
-	return InsertBatchWithSizeAndVersion(ctx, ses, 5, table, models, -1, options...)
+func InsertBatchWithVersion(ctx context.Context, ses Session, table string, models interface{}, versionIndex int, options ...*Schema) (int64, error) {
+	return InsertBatchWithConfig(ctx, ses, DefaultBatchConfig, table, models, versionIndex, options...)
 }
-func UpdateBatchWithSizeAndVersion(ctx context.Context, ses *gocql.Session, size int, table string, models interface{}, versionIndex int, options ...*Schema) (int64, error) {
+func InsertBatch(ctx context.Context, ses Session, table string, models interface{}, options ...*Schema) (int64, error) {
+	return InsertBatchWithConfig(ctx, ses, DefaultBatchConfig, table, models, -1, options...)
+}
+
+// UpdateBatchWithConfig updates the models in batches grouped by partition, see ExecuteAllWithConfig.
+// If versionIndex is set, the models are updated one by one by lightweight transactions.
+func UpdateBatchWithConfig(ctx context.Context, ses Session, config BatchConfig, table string, models interface{}, versionIndex int, options ...*Schema) (int64, error) {
 	s, err := BuildToUpdateBatchWithVersion(table, models, versionIndex, options...)
 	if err != nil {
 		return -1, err
 	}
-	return ExecuteAllWithSize(ctx, ses, size, s...)
+	if versionIndex >= 0 {
+		return ExecuteAllCAS(ctx, ses, s...)
+	}
+	return executeModels(ctx, ses, config, models, s, options...)
 }
-func UpdateBatchWithVersion(ctx context.Context, ses *gocql.Session, table string, models interface{}, versionIndex int, options ...*Schema) (int64, error) {
-	return UpdateBatchWithSizeAndVersion(ctx, ses, 5, table, models, versionIndex, options...)
+
+// UpdateBatchWithResult is UpdateBatchWithConfig, which returns the result of each model. The models not applied by the lightweight transactions are failed by ConflictError.
+func UpdateBatchWithResult(ctx context.Context, ses Session, config BatchConfig, table string, models interface{}, versionIndex int, options ...*Schema) (*BatchResult, error) {
+	s, err := BuildToUpdateBatchWithVersion(table, models, versionIndex, options...)
+	if err != nil {
+		return nil, err
+	}
+	if versionIndex >= 0 {
+		return ExecuteAllCASWithResult(ctx, ses, config, s...)
+	}
+	return executeModelsWithResult(ctx, ses, config, models, s, options...)
+}
+func UpdateBatchWithSizeAndVersion(ctx context.Context, ses Session, size int, table string, models interface{}, versionIndex int, options ...*Schema) (int64, error) {
+	return UpdateBatchWithConfig(ctx, ses, DefaultBatchConfig.WithSize(size), table, models, versionIndex, options...)
 }
-func UpdateBatch(ctx context.Context, ses *gocql.Session, table string, models interface{}, options ...*Schema) (int64, error) {
-	return UpdateBatchWithSizeAndVersion(ctx, ses, 5, table, models, -1, options...)
+func UpdateBatchWithVersion(ctx context.Context, ses Session, table string, models interface{}, versionIndex int, options ...*Schema) (int64, error) {
+	return UpdateBatchWithConfig(ctx, ses, DefaultBatchConfig, table, models, versionIndex, options...)
 }
-func SaveBatchWithSize(ctx context.Context, ses *gocql.Session, size int, table string, models interface{}, options ...*Schema) (int64, error) {
+func UpdateBatch(ctx context.Context, ses Session, table string, models interface{}, options ...*Schema) (int64, error) {
+	return UpdateBatchWithConfig(ctx, ses, DefaultBatchConfig, table, models, -1, options...)
+}
+
+// SaveBatchWithConfig inserts or updates the models in batches grouped by partition, see ExecuteAllWithConfig.
+func SaveBatchWithConfig(ctx context.Context, ses Session, config BatchConfig, table string, models interface{}, options ...*Schema) (int64, error) {
 	s, err := BuildToInsertBatchWithVersion(table, models, -1, true, options...)
 	if err != nil {
 		return -1, err
 	}
-	return ExecuteAllWithSize(ctx, ses, size, s...)
+	return executeModels(ctx, ses, config, models, s, options...)
 }
-func SaveBatch(ctx context.Context, ses *gocql.Session, table string, models interface{}, options ...*Schema) (int64, error) {
-	return SaveBatchWithSize(ctx, ses, 5, table, models, options...)
+
+// SaveBatchWithResult is SaveBatchWithConfig, which returns the result of each model.
+func SaveBatchWithResult(ctx context.Context, ses Session, config BatchConfig, table string, models interface{}, options ...*Schema) (*BatchResult, error) {
+	s, err := BuildToInsertBatchWithVersion(table, models, -1, true, options...)
+	if err != nil {
+		return nil, err
+	}
+	return executeModelsWithResult(ctx, ses, config, models, s, options...)
+}
+func SaveBatchWithSize(ctx context.Context, ses Session, size int, table string, models interface{}, options ...*Schema) (int64, error) {
+	return SaveBatchWithConfig(ctx, ses, DefaultBatchConfig.WithSize(size), table, models, options...)
+}
+func SaveBatch(ctx context.Context, ses Session, table string, models interface{}, options ...*Schema) (int64, error) {
+	return SaveBatchWithConfig(ctx, ses, DefaultBatchConfig, table, models, options...)
+}
+func executeModels(ctx context.Context, ses Session, config BatchConfig, models interface{}, stmts []Statement, options ...*Schema) (int64, error) {
+	result, err := executeModelsWithResult(ctx, ses, config, models, stmts, options...)
+	if result == nil {
+		return -1, err
+	}
+	return result.Count(), err
+}
+func executeModelsWithResult(ctx context.Context, ses Session, config BatchConfig, models interface{}, stmts []Statement, options ...*Schema) (*BatchResult, error) {
+	partitions, err := GetPartitionKeys(models, options...)
+	if err != nil {
+		return nil, err
+	}
+	return ExecuteAllWithResult(ctx, ses, GetBatchType(models, options...), config, stmts, partitions)
+}
+
+// GetBatchType returns gocql.CounterBatch if the models have counter columns, which are written by "update ... set c=c+?" in counter batches.
+func GetBatchType(models interface{}, options ...*Schema) gocql.BatchType {
+	var schema *Schema
+	if len(options) > 0 && options[0] != nil {
+		schema = options[0]
+	} else {
+		modelType := reflect.TypeOf(models)
+		for modelType.Kind() == reflect.Ptr || modelType.Kind() == reflect.Slice {
+			modelType = modelType.Elem()
+		}
+		if modelType.Kind() != reflect.Struct {
+			return gocql.UnloggedBatch
+		}
+		schema = GetSchema(modelType)
+	}
+	if len(schema.Counters) > 0 {
+		return gocql.CounterBatch
+	}
+	return gocql.UnloggedBatch
 }
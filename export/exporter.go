@@ -4,6 +4,9 @@ import (
 	"context"
 	"github.com/apache/cassandra-gocql-driver"
 	"reflect"
+	"sync"
+
+	c "github.com/core-go/cassandra"
 )
 
 func NewExportAdapter[T any](db *gocql.ClusterConfig,
@@ -28,6 +31,14 @@ func NewExporter[T any](db *gocql.ClusterConfig,
 	transform func(context.Context, *T) string,
 	write func(p []byte) (n int, err error),
 	close func() error,
+) (*Exporter[T], error) {
+	return NewExporterWithProvider[T](c.GetSessionProvider(db), buildQuery, transform, write, close)
+}
+func NewExporterWithProvider[T any](db c.SessionProvider,
+	buildQuery func(context.Context) (string, []interface{}),
+	transform func(context.Context, *T) string,
+	write func(p []byte) (n int, err error),
+	close func() error,
 ) (*Exporter[T], error) {
 	var t T
 	modelType := reflect.TypeOf(t)
@@ -41,23 +52,53 @@ func NewExporter[T any](db *gocql.ClusterConfig,
 	return &Exporter[T]{DB: db, Write: write, Close: close, Map: fieldsIndex, Transform: transform, BuildQuery: buildQuery}, nil
 }
 
+// NewTableExporter exports the whole table by token ranges in parallel, the lines of the ranges are interleaved.
+func NewTableExporter[T any](db *gocql.ClusterConfig, table string,
+	transform func(context.Context, *T) string,
+	write func(p []byte) (n int, err error),
+	close func() error,
+) (*Exporter[T], error) {
+	return NewTableExporterWithProvider[T](c.GetSessionProvider(db), table, transform, write, close)
+}
+func NewTableExporterWithProvider[T any](db c.SessionProvider, table string,
+	transform func(context.Context, *T) string,
+	write func(p []byte) (n int, err error),
+	close func() error,
+) (*Exporter[T], error) {
+	var t T
+	scanner, err := c.NewTokenScannerWithProvider(db, table, reflect.TypeOf(t))
+	if err != nil {
+		return nil, err
+	}
+	exporter, err := NewExporterWithProvider[T](db, nil, transform, write, close)
+	if err != nil {
+		return nil, err
+	}
+	exporter.Scanner = scanner
+	return exporter, nil
+}
+
 type Exporter[T any] struct {
-	DB         *gocql.ClusterConfig
+	DB         c.SessionProvider
 	Map        map[string]int
 	Transform  func(context.Context, *T) string
 	BuildQuery func(context.Context) (string, []interface{})
 	Write      func(p []byte) (n int, err error)
 	Close      func() error
+	Scanner    *c.TokenScanner
 }
 
 func (s *Exporter[T]) Export(ctx context.Context) (int64, error) {
+	if s.Scanner != nil {
+		return s.ExportByTokenRanges(ctx)
+	}
 	query, p := s.BuildQuery(ctx)
-	session, err := s.DB.CreateSession()
+	session, err := s.DB.Session()
 	if err != nil {
 		return 0, err
 	}
-	defer session.Close()
-	q := session.Query(query, p...)
+	q, cancel := c.ApplyOptions(ctx, session.Query(query, p...))
+	defer cancel()
 	err = q.Exec()
 	if err != nil {
 		return 0, err
@@ -65,7 +106,7 @@ func (s *Exporter[T]) Export(ctx context.Context) (int64, error) {
 	return s.ScanAndWrite(ctx, q.Iter())
 }
 
-func (s *Exporter[T]) ScanAndWrite(ctx context.Context, iter *gocql.Iter) (int64, error) {
+func (s *Exporter[T]) ScanAndWrite(ctx context.Context, iter c.Iter) (int64, error) {
 	defer s.Close()
 	columns := GetColumns(iter.Columns())
 	var i int64
@@ -90,3 +131,20 @@ func (s *Exporter[T]) TransformAndWrite(ctx context.Context, write func(p []byte
 	_, er := write([]byte(line))
 	return er
 }
+
+func (s *Exporter[T]) ExportByTokenRanges(ctx context.Context) (int64, error) {
+	defer s.Close()
+	var mu sync.Mutex
+	var i int64
+	err := c.ScanTable[T](ctx, s.Scanner, func(model *T) error {
+		mu.Lock()
+		defer mu.Unlock()
+		er1 := s.TransformAndWrite(ctx, s.Write, model)
+		if er1 != nil {
+			return er1
+		}
+		i = i + 1
+		return nil
+	})
+	return i, err
+}
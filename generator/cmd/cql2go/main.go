@@ -0,0 +1,86 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/apache/cassandra-gocql-driver"
+
+	q "github.com/core-go/cassandra"
+	"github.com/core-go/cassandra/generator"
+)
+
+// cql2go emits the Go structs of the tables of a keyspace, from the cluster or offline from a DESCRIBE dump:
+//
+//	cql2go -schema schema.cql -package models -out models.go
+//	cql2go -hosts 127.0.0.1 -keyspace shop -tables users,orders -out models.go
+func main() {
+	schema := flag.String("schema", "", "the DESCRIBE dump or the .cql file, read offline")
+	hosts := flag.String("hosts", "127.0.0.1", "the hosts of the cluster, separated by commas")
+	keyspace := flag.String("keyspace", "", "the keyspace, required if -schema is not set")
+	tables := flag.String("tables", "", "the tables, separated by commas, all tables if empty")
+	pkg := flag.String("package", "models", "the package of the generated file")
+	scale := flag.Int("scale", 2, "the scale of the decimal columns")
+	out := flag.String("out", "", "the output file, stdout if empty")
+	flag.Parse()
+
+	ks, err := load(*schema, *hosts, *keyspace, *tables)
+	if err != nil {
+		fail(err)
+	}
+	code, err := generator.Generate(ks, generator.Options{Package: *pkg, DecimalScale: *scale})
+	if err != nil {
+		fail(err)
+	}
+	if len(*out) == 0 {
+		os.Stdout.Write(code)
+		return
+	}
+	if err := os.WriteFile(*out, code, 0644); err != nil {
+		fail(err)
+	}
+}
+func load(schema string, hosts string, keyspace string, tables string) (*generator.Keyspace, error) {
+	var names []string
+	if len(tables) > 0 {
+		names = strings.Split(tables, ",")
+	}
+	if len(schema) > 0 {
+		content, err := os.ReadFile(schema)
+		if err != nil {
+			return nil, err
+		}
+		ks, err := generator.ParseSchema(string(content))
+		if err != nil || len(names) == 0 {
+			return ks, err
+		}
+		filtered := ks.Tables[:0]
+		for _, t := range ks.Tables {
+			for _, n := range names {
+				if strings.TrimSpace(n) == t.Name {
+					filtered = append(filtered, t)
+				}
+			}
+		}
+		ks.Tables = filtered
+		return ks, nil
+	}
+	if len(keyspace) == 0 {
+		return nil, fmt.Errorf("-schema or -keyspace is required")
+	}
+	cluster := gocql.NewCluster(strings.Split(hosts, ",")...)
+	cluster.Keyspace = keyspace
+	ses, err := cluster.CreateSession()
+	if err != nil {
+		return nil, err
+	}
+	defer ses.Close()
+	return generator.LoadSchema(context.Background(), q.NewSession(ses), keyspace, names...)
+}
+func fail(err error) {
+	fmt.Fprintln(os.Stderr, err)
+	os.Exit(1)
+}
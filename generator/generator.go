@@ -0,0 +1,202 @@
+package generator
+
+import (
+	"bytes"
+	"fmt"
+	"go/format"
+	"sort"
+	"strconv"
+	"strings"
+
+	q "github.com/core-go/cassandra"
+)
+
+// Options of Generate. DecimalScale is the scale of the decimal columns, which are mapped to float64.
+type Options struct {
+	Package      string
+	DecimalScale int
+}
+
+type generator struct {
+	options Options
+	imports map[string]bool
+	udts    map[string]bool
+	tuples  []string
+}
+
+// Generate emits the Go structs of the tables and the user-defined types, with the json and gorm tags of the mapping layer:
+// "column:", "primary_key" for the single key, "partition_key" and "clustering_key" for the composite keys, "static", "counter" and "scale".
+// The user-defined types are nested structs, the tuples are structs with the "tuple" option.
+func Generate(ks *Keyspace, options Options) ([]byte, error) {
+	if len(options.Package) == 0 {
+		options.Package = "models"
+	}
+	if options.DecimalScale <= 0 {
+		options.DecimalScale = 2
+	}
+	g := &generator{options: options, imports: make(map[string]bool), udts: make(map[string]bool)}
+	for _, t := range ks.Types {
+		g.udts[t.Name] = true
+	}
+	structs := make([]string, 0)
+	for _, t := range ks.Tables {
+		structs = append(structs, g.buildStruct(GoName(t.Name), t.Columns, true))
+	}
+	for _, t := range ks.Types {
+		structs = append(structs, g.buildStruct(GoName(t.Name), t.Fields, false))
+	}
+	var b bytes.Buffer
+	if len(ks.Name) > 0 {
+		fmt.Fprintf(&b, "// Code generated from the schema of keyspace %s.\n\n", ks.Name)
+	}
+	fmt.Fprintf(&b, "package %s\n\n", options.Package)
+	if len(g.imports) > 0 {
+		imports := make([]string, 0, len(g.imports))
+		for i := range g.imports {
+			imports = append(imports, strconv.Quote(i))
+		}
+		sort.Strings(imports)
+		fmt.Fprintf(&b, "import (\n%s\n)\n\n", strings.Join(imports, "\n"))
+	}
+	b.WriteString(strings.Join(append(structs, g.tuples...), "\n"))
+	return format.Source(b.Bytes())
+}
+func (g *generator) buildStruct(name string, columns []Column, table bool) string {
+	keys := 0
+	for _, c := range columns {
+		if c.Kind == "partition_key" || c.Kind == "clustering" {
+			keys++
+		}
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, "type %s struct {\n", name)
+	for _, c := range columns {
+		field := GoName(c.Name)
+		options := []string{"column:" + c.Name}
+		switch c.Kind {
+		case "partition_key":
+			if keys == 1 {
+				options = append(options, "primary_key")
+			} else {
+				options = append(options, "partition_key")
+			}
+		case "clustering":
+			if c.Order == "desc" {
+				options = append(options, "clustering_key:desc")
+			} else {
+				options = append(options, "clustering_key")
+			}
+		case "static":
+			options = append(options, "static")
+		}
+		base, _ := splitType(c.Type)
+		switch base {
+		case "counter":
+			options = append(options, "counter")
+		case "decimal":
+			options = append(options, fmt.Sprintf("scale:%d", g.options.DecimalScale))
+		case "tuple":
+			options = append(options, "tuple")
+		}
+		t := g.goType(c.Type, name+field, !table || c.Kind == "regular" || c.Kind == "static")
+		fmt.Fprintf(&b, "\t%s %s `json:\"%s,omitempty\" gorm:\"%s\"`\n", field, t, q.ToCamelCase(c.Name), strings.Join(options, ";"))
+	}
+	b.WriteString("}\n")
+	return b.String()
+}
+
+// goType maps the CQL type to the Go type. The user-defined types and the timestamps of the regular columns are pointers, so that null is nil.
+func (g *generator) goType(cqlType string, hint string, nullable bool) string {
+	base, args := splitType(cqlType)
+	switch base {
+	case "ascii", "text", "varchar", "inet", "uuid", "timeuuid":
+		return "string"
+	case "bigint", "counter":
+		return "int64"
+	case "int":
+		return "int32"
+	case "smallint":
+		return "int16"
+	case "tinyint":
+		return "int8"
+	case "varint":
+		g.imports["math/big"] = true
+		return "*big.Int"
+	case "boolean":
+		return "bool"
+	case "float":
+		return "float32"
+	case "double", "decimal":
+		return "float64"
+	case "blob":
+		return "[]byte"
+	case "timestamp", "date":
+		g.imports["time"] = true
+		if nullable {
+			return "*time.Time"
+		}
+		return "time.Time"
+	case "time":
+		g.imports["time"] = true
+		return "time.Duration"
+	case "duration":
+		g.imports["github.com/apache/cassandra-gocql-driver"] = true
+		return "gocql.Duration"
+	case "list", "set":
+		if len(args) == 1 {
+			return "[]" + g.goType(args[0], hint, false)
+		}
+	case "map":
+		if len(args) == 2 {
+			return "map[" + g.goType(args[0], hint+"Key", false) + "]" + g.goType(args[1], hint, false)
+		}
+	case "tuple":
+		name := hint + "Tuple"
+		fields := make([]Column, 0, len(args))
+		for i, a := range args {
+			fields = append(fields, Column{Name: "f" + strconv.Itoa(i), Type: a, Kind: "regular"})
+		}
+		g.tuples = append(g.tuples, g.buildStruct(name, fields, false))
+		if nullable {
+			return "*" + name
+		}
+		return name
+	default:
+		if g.udts[base] {
+			if nullable {
+				return "*" + GoName(base)
+			}
+			return GoName(base)
+		}
+	}
+	return "interface{}"
+}
+
+// splitType splits "frozen<map<text, frozen<address>>>" into "map" and ["text", "frozen<address>"].
+func splitType(t string) (string, []string) {
+	t = strings.TrimSpace(t)
+	for strings.HasPrefix(strings.ToLower(t), "frozen<") && strings.HasSuffix(t, ">") {
+		t = strings.TrimSpace(t[len("frozen<") : len(t)-1])
+	}
+	i := strings.Index(t, "<")
+	if i < 0 || !strings.HasSuffix(t, ">") {
+		if j := strings.LastIndex(t, "."); j >= 0 {
+			t = t[j+1:]
+		}
+		return unquote(t), nil
+	}
+	args := q.SplitTag(t[i+1:len(t)-1], ',')
+	for k := range args {
+		args[k] = strings.TrimSpace(args[k])
+	}
+	return strings.ToLower(strings.TrimSpace(t[:i])), args
+}
+
+// GoName converts the name of the column, the table or the type to the name of the Go field or struct, such as "user_id" to "UserId".
+func GoName(name string) string {
+	if len(name) == 0 {
+		return name
+	}
+	s := q.ToCamelCase(name)
+	return strings.ToUpper(s[:1]) + s[1:]
+}
@@ -0,0 +1,283 @@
+package generator
+
+import (
+	"flag"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"math/big"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"testing"
+	"time"
+
+	q "github.com/core-go/cassandra"
+)
+
+var update = flag.Bool("update", false, "update the golden files")
+
+func TestParsePrimaryKey(t *testing.T) {
+	tests := []struct {
+		key        string
+		partition  []string
+		clustering []string
+	}{
+		{"(id)", []string{"id"}, []string{}},
+		{"(id, created_at)", []string{"id"}, []string{"created_at"}},
+		{"((tenant_id, region), created_at, order_id)", []string{"tenant_id", "region"}, []string{"created_at", "order_id"}},
+		{` ( ("Tenant", region) )`, []string{"Tenant", "region"}, []string{}},
+		{"(ID, Created)", []string{"id"}, []string{"created"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.key, func(t *testing.T) {
+			partition, clustering := parsePrimaryKey(tt.key)
+			if !reflect.DeepEqual(partition, tt.partition) || !reflect.DeepEqual(clustering, tt.clustering) {
+				t.Fatalf("parsePrimaryKey(%q) = %v, %v", tt.key, partition, clustering)
+			}
+		})
+	}
+}
+
+func TestParseClusteringOrder(t *testing.T) {
+	tests := []struct {
+		name string
+		tail string
+		want map[string]string
+	}{
+		{"none", " WITH comment = ''", map[string]string{}},
+		{"desc and asc", " WITH CLUSTERING ORDER BY (created_at DESC, order_id ASC) AND comment = ''", map[string]string{"created_at": "desc", "order_id": "asc"}},
+		{"quoted", ` with clustering order by ("CreatedAt" desc)`, map[string]string{"CreatedAt": "desc"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := parseClusteringOrder(tt.tail); !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("parseClusteringOrder(%q) = %v, want %v", tt.tail, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSplitType(t *testing.T) {
+	tests := []struct {
+		typ  string
+		base string
+		args []string
+	}{
+		{"text", "text", nil},
+		{"frozen<address>", "address", nil},
+		{"shop.address", "address", nil},
+		{"list<int>", "list", []string{"int"}},
+		{"frozen<map<text, frozen<address>>>", "map", []string{"text", "frozen<address>"}},
+		{"frozen<tuple<double, double>>", "tuple", []string{"double", "double"}},
+		{"MAP<text, list<int>>", "map", []string{"text", "list<int>"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.typ, func(t *testing.T) {
+			base, args := splitType(tt.typ)
+			if base != tt.base || !reflect.DeepEqual(args, tt.args) {
+				t.Fatalf("splitType(%q) = %q, %q", tt.typ, base, args)
+			}
+		})
+	}
+}
+
+func TestParseSchema(t *testing.T) {
+	ks := parseTestSchema(t)
+	if ks.Name != "shop" || len(ks.Tables) != 2 || len(ks.Types) != 1 {
+		t.Fatalf("ParseSchema() = keyspace %q, %d tables, %d types", ks.Name, len(ks.Tables), len(ks.Types))
+	}
+	orders := ks.Tables[0]
+	want := []Column{
+		{Name: "tenant_id", Type: "text", Kind: "partition_key"},
+		{Name: "region", Type: "text", Kind: "partition_key"},
+		{Name: "created_at", Type: "timestamp", Kind: "clustering", Order: "desc"},
+		{Name: "order_id", Type: "timeuuid", Kind: "clustering", Order: "asc"},
+		{Name: "items", Type: "map<text, int>", Kind: "regular"},
+		{Name: "location", Type: "frozen<tuple<double, double>>", Kind: "regular"},
+		{Name: "shipping", Type: "frozen<address>", Kind: "regular"},
+		{Name: "tags", Type: "set<text>", Kind: "regular"},
+		{Name: "tenant_name", Type: "text", Kind: "static"},
+		{Name: "total", Type: "decimal", Kind: "regular"},
+	}
+	if orders.Name != "orders" || !reflect.DeepEqual(orders.Columns, want) {
+		t.Fatalf("ParseSchema() orders = %+v", orders)
+	}
+	views := ks.Tables[1]
+	if views.Name != "page_views" || !reflect.DeepEqual(views.Columns, []Column{{Name: "page", Type: "text", Kind: "partition_key"}, {Name: "views", Type: "counter", Kind: "regular"}}) {
+		t.Fatalf("ParseSchema() page_views = %+v", views)
+	}
+}
+
+func TestGenerate(t *testing.T) {
+	ks := parseTestSchema(t)
+	got, err := Generate(ks, Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	golden := filepath.Join("testdata", "shop.golden")
+	if *update {
+		if err := os.WriteFile(golden, got, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	want, err := os.ReadFile(golden)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != string(want) {
+		t.Fatalf("Generate() =\n%s\nwant\n%s", got, want)
+	}
+
+	// the generated structs must map back to the same tables
+	types := structTypes(t, got)
+	for _, table := range ks.Tables {
+		modelType, ok := types[GoName(table.Name)]
+		if !ok {
+			t.Fatalf("no struct for table %s", table.Name)
+		}
+		schema := q.GetSchema(modelType)
+		if len(schema.Columns) != len(table.Columns) {
+			t.Fatalf("%s: %d columns, want %d", table.Name, len(schema.Columns), len(table.Columns))
+		}
+		var partitionKeys, clusteringKeys, counters []string
+		for i, c := range table.Columns {
+			f := schema.Columns[i]
+			base, _ := splitType(c.Type)
+			if f.Column != c.Name || f.Static != (c.Kind == "static") || (f.Order == "desc") != (c.Order == "desc") ||
+				f.Tuple != (base == "tuple") || f.UDT != (base == "address") || (f.Scale == 2) != (base == "decimal") {
+				t.Fatalf("%s: field %+v does not match the column %+v", table.Name, f, c)
+			}
+			switch {
+			case c.Kind == "partition_key":
+				partitionKeys = append(partitionKeys, c.Name)
+			case c.Kind == "clustering":
+				clusteringKeys = append(clusteringKeys, c.Name)
+			case base == "counter":
+				counters = append(counters, c.Name)
+			}
+		}
+		if got := columnNames(schema.PartitionKeys); !reflect.DeepEqual(got, partitionKeys) {
+			t.Fatalf("%s: partition keys %v, want %v", table.Name, got, partitionKeys)
+		}
+		if got := columnNames(schema.ClusteringKeys); !reflect.DeepEqual(got, clusteringKeys) {
+			t.Fatalf("%s: clustering keys %v, want %v", table.Name, got, clusteringKeys)
+		}
+		if got := columnNames(schema.Counters); !reflect.DeepEqual(got, counters) {
+			t.Fatalf("%s: counters %v, want %v", table.Name, got, counters)
+		}
+	}
+}
+
+func parseTestSchema(t *testing.T) *Keyspace {
+	t.Helper()
+	content, err := os.ReadFile(filepath.Join("testdata", "shop.cql"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ks, err := ParseSchema(string(content))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return ks
+}
+func columnNames(fields []*q.FieldDB) []string {
+	var names []string
+	for _, f := range fields {
+		names = append(names, f.Column)
+	}
+	return names
+}
+
+var externalTypes = map[string]reflect.Type{
+	"time.Time":     reflect.TypeOf(time.Time{}),
+	"time.Duration": reflect.TypeOf(time.Duration(0)),
+	"big.Int":       reflect.TypeOf(big.Int{}),
+}
+
+// structTypes builds the types of the structs of the generated source by reflect.StructOf, so that their schema can be read.
+func structTypes(t *testing.T, src []byte) map[string]reflect.Type {
+	t.Helper()
+	f, err := parser.ParseFile(token.NewFileSet(), "", src, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	specs := make(map[string]*ast.StructType)
+	ast.Inspect(f, func(n ast.Node) bool {
+		if s, ok := n.(*ast.TypeSpec); ok {
+			if st, ok := s.Type.(*ast.StructType); ok {
+				specs[s.Name.Name] = st
+			}
+		}
+		return true
+	})
+	types := make(map[string]reflect.Type)
+	var typeOf func(e ast.Expr) reflect.Type
+	var build func(name string) reflect.Type
+	typeOf = func(e ast.Expr) reflect.Type {
+		switch x := e.(type) {
+		case *ast.Ident:
+			switch x.Name {
+			case "string":
+				return reflect.TypeOf("")
+			case "bool":
+				return reflect.TypeOf(false)
+			case "int8":
+				return reflect.TypeOf(int8(0))
+			case "int16":
+				return reflect.TypeOf(int16(0))
+			case "int32":
+				return reflect.TypeOf(int32(0))
+			case "int64":
+				return reflect.TypeOf(int64(0))
+			case "float32":
+				return reflect.TypeOf(float32(0))
+			case "float64":
+				return reflect.TypeOf(float64(0))
+			case "byte":
+				return reflect.TypeOf(byte(0))
+			}
+			return build(x.Name)
+		case *ast.StarExpr:
+			return reflect.PtrTo(typeOf(x.X))
+		case *ast.ArrayType:
+			return reflect.SliceOf(typeOf(x.Elt))
+		case *ast.MapType:
+			return reflect.MapOf(typeOf(x.Key), typeOf(x.Value))
+		case *ast.SelectorExpr:
+			name := x.X.(*ast.Ident).Name + "." + x.Sel.Name
+			if typ, ok := externalTypes[name]; ok {
+				return typ
+			}
+			t.Fatalf("unknown type %s", name)
+		case *ast.InterfaceType:
+			return reflect.TypeOf((*interface{})(nil)).Elem()
+		}
+		t.Fatalf("unknown type %T", e)
+		return nil
+	}
+	build = func(name string) reflect.Type {
+		if typ, ok := types[name]; ok {
+			return typ
+		}
+		st, ok := specs[name]
+		if !ok {
+			t.Fatalf("unknown type %s", name)
+		}
+		fields := make([]reflect.StructField, 0, len(st.Fields.List))
+		for _, field := range st.Fields.List {
+			tag, err := strconv.Unquote(field.Tag.Value)
+			if err != nil {
+				t.Fatal(err)
+			}
+			fields = append(fields, reflect.StructField{Name: field.Names[0].Name, Type: typeOf(field.Type), Tag: reflect.StructTag(tag)})
+		}
+		types[name] = reflect.StructOf(fields)
+		return types[name]
+	}
+	for name := range specs {
+		build(name)
+	}
+	return types
+}
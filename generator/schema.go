@@ -0,0 +1,306 @@
+package generator
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	q "github.com/core-go/cassandra"
+	"github.com/core-go/cassandra/migration"
+)
+
+// Column is a column of a table, or a field of a user-defined type. Kind is partition_key, clustering, static or regular.
+type Column struct {
+	Name  string
+	Type  string
+	Kind  string
+	Order string
+}
+type Table struct {
+	Name    string
+	Columns []Column
+}
+type UDT struct {
+	Name   string
+	Fields []Column
+}
+type Keyspace struct {
+	Name   string
+	Tables []Table
+	Types  []UDT
+}
+
+// ParseSchema parses the "create table" and "create type" statements of a DESCRIBE dump or a .cql file, the other statements are skipped.
+func ParseSchema(content string) (*Keyspace, error) {
+	ks := &Keyspace{}
+	for _, stmt := range migration.SplitStatements(content) {
+		words := strings.Fields(strings.ToLower(stmt))
+		if len(words) < 3 || words[0] != "create" {
+			continue
+		}
+		switch words[1] {
+		case "table":
+			t, err := parseCreateTable(stmt)
+			if err != nil {
+				return nil, err
+			}
+			ks.Tables = append(ks.Tables, *t)
+		case "type":
+			t, err := parseCreateType(stmt)
+			if err != nil {
+				return nil, err
+			}
+			ks.Types = append(ks.Types, *t)
+		case "keyspace":
+			if i := strings.Index(strings.ToLower(stmt), " with"); i > 0 && len(ks.Name) == 0 {
+				ks.Name = unquote(nameOf(stmt[:i]))
+			}
+		}
+	}
+	return ks, nil
+}
+
+// parseCreateTable parses "create table [if not exists] ks.name (col type [static] [primary key], ..., primary key ((a, b), c)) with clustering order by (c desc)".
+func parseCreateTable(stmt string) (*Table, error) {
+	head, body, tail, err := splitBody(stmt)
+	if err != nil {
+		return nil, err
+	}
+	t := &Table{Name: unquote(nameOf(head))}
+	var partitionKeys, clusteringKeys []string
+	for _, item := range q.SplitTag(body, ',') {
+		item = strings.TrimSpace(item)
+		lower := strings.ToLower(item)
+		if strings.HasPrefix(lower, "primary key") {
+			partitionKeys, clusteringKeys = parsePrimaryKey(item[len("primary key"):])
+			continue
+		}
+		c := parseColumn(item)
+		if strings.HasSuffix(lower, " primary key") {
+			c.Type = strings.TrimSpace(c.Type[:len(c.Type)-len(" primary key")])
+			partitionKeys = []string{c.Name}
+		}
+		if strings.HasSuffix(strings.ToLower(c.Type), " static") {
+			c.Type = strings.TrimSpace(c.Type[:len(c.Type)-len(" static")])
+			c.Kind = "static"
+		}
+		t.Columns = append(t.Columns, c)
+	}
+	if len(partitionKeys) == 0 {
+		return nil, fmt.Errorf("table %s has no primary key", t.Name)
+	}
+	orders := parseClusteringOrder(tail)
+	for i := range t.Columns {
+		c := &t.Columns[i]
+		if contains(partitionKeys, c.Name) {
+			c.Kind = "partition_key"
+		} else if contains(clusteringKeys, c.Name) {
+			c.Kind = "clustering"
+			c.Order = "asc"
+			if orders[c.Name] == "desc" {
+				c.Order = "desc"
+			}
+		}
+	}
+	sortKeys(t.Columns, partitionKeys, clusteringKeys)
+	return t, nil
+}
+func parseCreateType(stmt string) (*UDT, error) {
+	head, body, _, err := splitBody(stmt)
+	if err != nil {
+		return nil, err
+	}
+	t := &UDT{Name: unquote(nameOf(head))}
+	for _, item := range q.SplitTag(body, ',') {
+		if item = strings.TrimSpace(item); len(item) > 0 {
+			t.Fields = append(t.Fields, parseColumn(item))
+		}
+	}
+	return t, nil
+}
+
+// splitBody splits the statement into the part before the first "(", the part inside the parentheses, and the part after them.
+func splitBody(stmt string) (string, string, string, error) {
+	start := strings.Index(stmt, "(")
+	if start < 0 {
+		return "", "", "", fmt.Errorf("invalid statement: %s", stmt)
+	}
+	depth := 0
+	for i := start; i < len(stmt); i++ {
+		switch stmt[i] {
+		case '(':
+			depth++
+		case ')':
+			depth--
+			if depth == 0 {
+				return stmt[:start], stmt[start+1 : i], stmt[i+1:], nil
+			}
+		}
+	}
+	return "", "", "", fmt.Errorf("invalid statement: %s", stmt)
+}
+
+// nameOf returns the name without the keyspace of "create table if not exists ks.name".
+func nameOf(head string) string {
+	words := strings.Fields(head)
+	if len(words) == 0 {
+		return ""
+	}
+	name := words[len(words)-1]
+	if i := strings.LastIndex(name, "."); i >= 0 {
+		name = name[i+1:]
+	}
+	return name
+}
+func parseColumn(item string) Column {
+	item = strings.TrimSpace(item)
+	i := strings.IndexAny(item, " \t\n")
+	if i < 0 {
+		return Column{Name: unquote(item), Kind: "regular"}
+	}
+	return Column{Name: unquote(item[:i]), Type: strings.TrimSpace(item[i+1:]), Kind: "regular"}
+}
+
+// parsePrimaryKey parses "((a, b), c, d)" or "(a, c, d)" into the partition keys and the clustering keys.
+func parsePrimaryKey(s string) ([]string, []string) {
+	s = strings.TrimSpace(s)
+	s = strings.TrimSuffix(strings.TrimPrefix(s, "("), ")")
+	items := q.SplitTag(s, ',')
+	if len(items) == 0 {
+		return nil, nil
+	}
+	partitionKeys := make([]string, 0)
+	first := strings.TrimSpace(items[0])
+	if strings.HasPrefix(first, "(") {
+		for _, k := range strings.Split(strings.Trim(first, "()"), ",") {
+			partitionKeys = append(partitionKeys, unquote(strings.TrimSpace(k)))
+		}
+	} else {
+		partitionKeys = append(partitionKeys, unquote(first))
+	}
+	clusteringKeys := make([]string, 0)
+	for _, k := range items[1:] {
+		clusteringKeys = append(clusteringKeys, unquote(strings.TrimSpace(k)))
+	}
+	return partitionKeys, clusteringKeys
+}
+
+// parseClusteringOrder parses "with clustering order by (c desc, d asc) and ...".
+func parseClusteringOrder(tail string) map[string]string {
+	orders := make(map[string]string)
+	lower := strings.ToLower(tail)
+	i := strings.Index(lower, "clustering order by")
+	if i < 0 {
+		return orders
+	}
+	_, body, _, err := splitBody(tail[i:])
+	if err != nil {
+		return orders
+	}
+	for _, item := range strings.Split(body, ",") {
+		words := strings.Fields(item)
+		if len(words) == 2 {
+			orders[unquote(words[0])] = strings.ToLower(words[1])
+		}
+	}
+	return orders
+}
+func unquote(name string) string {
+	if len(name) >= 2 && name[0] == '"' && name[len(name)-1] == '"' {
+		return strings.ReplaceAll(name[1:len(name)-1], `""`, `"`)
+	}
+	return strings.ToLower(name)
+}
+func contains(items []string, s string) bool {
+	for _, item := range items {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
+
+// sortKeys puts the partition keys first, then the clustering keys in the order of the primary key, then the other columns.
+func sortKeys(columns []Column, partitionKeys []string, clusteringKeys []string) {
+	rank := func(c Column) int {
+		for i, k := range partitionKeys {
+			if k == c.Name {
+				return i
+			}
+		}
+		for i, k := range clusteringKeys {
+			if k == c.Name {
+				return len(partitionKeys) + i
+			}
+		}
+		return len(partitionKeys) + len(clusteringKeys)
+	}
+	sort.SliceStable(columns, func(i, j int) bool {
+		return rank(columns[i]) < rank(columns[j])
+	})
+}
+
+// LoadSchema reads the tables and the user-defined types of the keyspace from system_schema. If tables is empty, all tables are loaded.
+func LoadSchema(ctx context.Context, ses q.Session, keyspace string, tables ...string) (*Keyspace, error) {
+	ks := &Keyspace{Name: keyspace}
+	if len(tables) == 0 {
+		rows, err := q.QueryMapContext(ctx, ses, nil, "select table_name from system_schema.tables where keyspace_name = ?", keyspace)
+		if err != nil {
+			return nil, err
+		}
+		for _, row := range rows {
+			tables = append(tables, fmt.Sprint(row["table_name"]))
+		}
+		sort.Strings(tables)
+	}
+	for _, name := range tables {
+		columns, err := q.LoadTableColumns(ctx, ses, keyspace, name)
+		if err != nil {
+			return nil, err
+		}
+		if columns == nil {
+			return nil, fmt.Errorf("table %s.%s does not exist", keyspace, name)
+		}
+		t := Table{Name: name}
+		partitionKeys := make([]q.TableColumn, 0)
+		clusteringKeys := make([]q.TableColumn, 0)
+		for _, c := range columns {
+			col := Column{Name: c.Name, Type: c.Type, Kind: c.Kind}
+			switch c.Kind {
+			case "partition_key":
+				partitionKeys = append(partitionKeys, c)
+			case "clustering":
+				clusteringKeys = append(clusteringKeys, c)
+				col.Order = strings.ToLower(c.ClusteringOrder)
+			}
+			t.Columns = append(t.Columns, col)
+		}
+		sort.SliceStable(partitionKeys, func(i, j int) bool { return partitionKeys[i].Position < partitionKeys[j].Position })
+		sort.SliceStable(clusteringKeys, func(i, j int) bool { return clusteringKeys[i].Position < clusteringKeys[j].Position })
+		sortKeys(t.Columns, names(partitionKeys), names(clusteringKeys))
+		ks.Tables = append(ks.Tables, t)
+	}
+	query, cancel := q.ApplyOptions(ctx, ses.Query("select type_name, field_names, field_types from system_schema.types where keyspace_name = ?", keyspace))
+	defer cancel()
+	iter := query.Iter()
+	var name string
+	var fieldNames, fieldTypes []string
+	for iter.Scan(&name, &fieldNames, &fieldTypes) {
+		t := UDT{Name: name}
+		for i := range fieldNames {
+			if i < len(fieldTypes) {
+				t.Fields = append(t.Fields, Column{Name: fieldNames[i], Type: fieldTypes[i], Kind: "regular"})
+			}
+		}
+		ks.Types = append(ks.Types, t)
+	}
+	return ks, iter.Close()
+}
+func names(columns []q.TableColumn) []string {
+	result := make([]string, 0, len(columns))
+	for _, c := range columns {
+		result = append(result, c.Name)
+	}
+	return result
+}
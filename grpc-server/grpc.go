@@ -11,12 +11,15 @@ import (
 
 type GRPCHandler struct {
 	grpc.DbProxyServer
-	DB        *gocql.ClusterConfig
+	DB        c.SessionProvider
 	Transform func(s string) string
 	Error     func(context.Context, string)
 }
 
 func NewHandler(db *gocql.ClusterConfig, transform func(s string) string, logError func(context.Context, string)) *GRPCHandler {
+	return NewHandlerWithProvider(c.GetSessionProvider(db), transform, logError)
+}
+func NewHandlerWithProvider(db c.SessionProvider, transform func(s string) string, logError func(context.Context, string)) *GRPCHandler {
 	g := GRPCHandler{DB: db, Transform: transform, Error: logError}
 	return &g
 }
@@ -53,12 +56,11 @@ func (s *GRPCHandler) Query(ctx context.Context, in *grpc.Request) (*grpc.QueryR
 		statement.Dates = append(statement.Dates, int(v))
 	}
 	statement.Params = c.ParseDates(statement.Params, statement.Dates)
-	session, err := s.DB.CreateSession()
+	session, err := s.DB.Session()
 	if err != nil {
 		return &grpc.QueryResponse{Message: "Error: " + err.Error()}, err
 	}
-	defer session.Close()
-	res, err := c.QueryMap(session, s.Transform, statement.Query, statement.Params...)
+	res, err := c.QueryMapContext(ctx, session, s.Transform, statement.Query, statement.Params...)
 	data := new(bytes.Buffer)
 	err = json.NewEncoder(data).Encode(&res)
 	if err != nil {
@@ -80,12 +82,11 @@ func (s *GRPCHandler) Execute(ctx context.Context, in *grpc.Request) (*grpc.Resp
 		statement.Dates = append(statement.Dates, int(v))
 	}
 	statement.Params = c.ParseDates(statement.Params, statement.Dates)
-	session, err := s.DB.CreateSession()
+	session, err := s.DB.Session()
 	if err != nil {
 		return &grpc.Response{Result: -1}, err
 	}
-	defer session.Close()
-	er1 := c.Exec(session, statement.Query, statement.Params...)
+	er1 := c.ExecContext(ctx, session, statement.Query, statement.Params...)
 	res := 0
 	if er1 == nil {
 		res = 1
@@ -109,11 +110,10 @@ func (s *GRPCHandler) ExecBatch(ctx context.Context, in *grpc.BatchRequest) (*gr
 		st.Params = c.ParseDates(statements[i].Params, statements[i].Dates)
 		b = append(b, st)
 	}
-	session, err := s.DB.CreateSession()
+	session, err := s.DB.Session()
 	if err != nil {
 		return &grpc.Response{Result: -1}, err
 	}
-	defer session.Close()
 	res, err := c.ExecuteAll(ctx, session, b...)
 	return &grpc.Response{Result: res}, err
 }
@@ -9,12 +9,15 @@ import (
 )
 
 type Handler struct {
-	DB        *gocql.ClusterConfig
+	DB        c.SessionProvider
 	Transform func(s string) string
 	Error     func(context.Context, string)
 }
 
 func NewHandler(db *gocql.ClusterConfig, transform func(s string) string, options ...func(context.Context, string)) *Handler {
+	return NewHandlerWithProvider(c.GetSessionProvider(db), transform, options...)
+}
+func NewHandlerWithProvider(db c.SessionProvider, transform func(s string) string, options ...func(context.Context, string)) *Handler {
 	var logError func(context.Context, string)
 	if len(options) >= 1 {
 		logError = options[0]
@@ -30,13 +33,12 @@ func (h *Handler) Exec(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	s.Params = c.ParseDates(s.Params, s.Dates)
-	session, err := h.DB.CreateSession()
+	session, err := h.DB.Session()
 	if err != nil {
 		handleError(w, r, http.StatusInternalServerError, err.Error(), h.Error, err)
 		return
 	}
-	defer session.Close()
-	er1 := c.Exec(session, s.Query, s.Params...)
+	er1 := c.ExecContext(r.Context(), session, s.Query, s.Params...)
 	res := 0
 	if er1 == nil {
 		res = 1
@@ -56,13 +58,12 @@ func (h *Handler) Query(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	s.Params = c.ParseDates(s.Params, s.Dates)
-	session, err := h.DB.CreateSession()
+	session, err := h.DB.Session()
 	if err != nil {
 		handleError(w, r, http.StatusInternalServerError, err.Error(), h.Error, err)
 		return
 	}
-	defer session.Close()
-	res, err := c.QueryMap(session, h.Transform, s.Query, s.Params...)
+	res, err := c.QueryMapContext(r.Context(), session, h.Transform, s.Query, s.Params...)
 	if err != nil {
 		handleError(w, r, 500, err.Error(), h.Error, err)
 		return
@@ -85,12 +86,11 @@ func (h *Handler) ExecBatch(w http.ResponseWriter, r *http.Request) {
 		st.Params = c.ParseDates(s[i].Params, s[i].Dates)
 		b = append(b, st)
 	}
-	session, err := h.DB.CreateSession()
+	session, err := h.DB.Session()
 	if err != nil {
 		handleError(w, r, http.StatusInternalServerError, err.Error(), h.Error, err)
 		return
 	}
-	defer session.Close()
 	res, err := c.ExecuteAll(r.Context(), session, b...)
 	if err != nil {
 		handleError(w, r, 500, err.Error(), h.Error, err)
@@ -0,0 +1,113 @@
+package cassandra
+
+import (
+	"context"
+	"reflect"
+)
+
+// Iterator scans the rows one by one, the driver fetches the next page when the current page is consumed,
+// so the memory does not grow with the number of rows.
+//
+//	it := NewIterator[User](ctx, ses, fieldsIndex, "select * from users")
+//	for it.Next() {
+//		user := it.Value()
+//	}
+//	err := it.Close()
+type Iterator[T any] struct {
+	ctx         context.Context
+	cancel      context.CancelFunc
+	iter        Iter
+	columns     []string
+	fieldsIndex map[string]int
+	value       *T
+	err         error
+}
+
+// NewIterator executes the query with the options of the context. The page size can be set by WithPageSize.
+func NewIterator[T any](ctx context.Context, ses Session, fieldsIndex map[string]int, sql string, values ...interface{}) *Iterator[T] {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	query := ses.Query(sql, values...)
+	if o := GetQueryOptions(ctx); o != nil && o.PageSize > 0 {
+		query = query.PageSize(o.PageSize)
+	}
+	q, cancel := ApplyOptions(ctx, query)
+	iter := q.Iter()
+	return &Iterator[T]{ctx: ctx, cancel: cancel, iter: iter, columns: GetColumns(iter.Columns()), fieldsIndex: fieldsIndex}
+}
+
+// Next scans the next row, it returns false when there are no more rows, the context is done, or an error occurs.
+func (it *Iterator[T]) Next() bool {
+	if it.err != nil {
+		return false
+	}
+	if err := it.ctx.Err(); err != nil {
+		it.err = err
+		return false
+	}
+	var t T
+	r := StructScan(&t, it.columns, it.fieldsIndex, -1)
+	if !it.iter.Scan(r...) {
+		return false
+	}
+	it.value = &t
+	return true
+}
+
+// Value returns the current row, which is a new value on each call of Next.
+func (it *Iterator[T]) Value() *T {
+	return it.value
+}
+
+// Close releases the iterator, and returns the error of the iteration.
+func (it *Iterator[T]) Close() error {
+	err := it.iter.Close()
+	it.cancel()
+	if it.err != nil {
+		return it.err
+	}
+	return err
+}
+
+// QueryEach calls fn for each row of the query. If fn returns an error, the iteration stops and the error is returned.
+func QueryEach[T any](ctx context.Context, ses Session, fieldsIndex map[string]int, fn func(*T) error, sql string, values ...interface{}) error {
+	it := NewIterator[T](ctx, ses, fieldsIndex, sql, values...)
+	for it.Next() {
+		if err := fn(it.Value()); err != nil {
+			it.Close()
+			return err
+		}
+	}
+	return it.Close()
+}
+
+// QueryEachModel is QueryEach of the model type, fn receives the pointer of the new model of each row.
+func QueryEachModel(ctx context.Context, ses Session, modelType reflect.Type, fieldsIndex map[string]int, fn func(interface{}) error, sql string, values ...interface{}) error {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	query := ses.Query(sql, values...)
+	if o := GetQueryOptions(ctx); o != nil && o.PageSize > 0 {
+		query = query.PageSize(o.PageSize)
+	}
+	q, cancel := ApplyOptions(ctx, query)
+	defer cancel()
+	iter := q.Iter()
+	columns := GetColumns(iter.Columns())
+	for {
+		if err := ctx.Err(); err != nil {
+			iter.Close()
+			return err
+		}
+		model := reflect.New(modelType).Interface()
+		r := StructScan(model, columns, fieldsIndex, -1)
+		if !iter.Scan(r...) {
+			return iter.Close()
+		}
+		if err := fn(model); err != nil {
+			iter.Close()
+			return err
+		}
+	}
+}
@@ -0,0 +1,92 @@
+package cassandra_test
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	c "github.com/core-go/cassandra"
+	"github.com/core-go/cassandra/cqltest"
+)
+
+func TestQueryEach(t *testing.T) {
+	stop := errors.New("stop")
+	broken := errors.New("connection lost")
+	rows := [][]interface{}{{int64(1), "a"}, {int64(2), "b"}, {int64(3), "c"}}
+	tests := []struct {
+		name    string
+		result  cqltest.Result
+		stopAt  int64
+		visited []int64
+		err     error
+	}{
+		{"all rows", cqltest.Result{Rows: rows}, 0, []int64{1, 2, 3}, nil},
+		{"no rows", cqltest.Result{}, 0, nil, nil},
+		{"stopped by the callback", cqltest.Result{Rows: rows}, 2, []int64{1, 2}, stop},
+		{"error of close", cqltest.Result{Rows: rows[:2], Err: broken}, 0, []int64{1, 2}, broken},
+		{"error of the query", cqltest.Result{Err: broken}, 0, nil, broken},
+	}
+	fieldsIndex, err := c.GetColumnIndexes(reflect.TypeOf(account{}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tt := range tests {
+		tt.result.Columns = []string{"id", "name"}
+		visit := func(visited *[]int64, a *account) error {
+			*visited = append(*visited, a.Id)
+			if a.Id == tt.stopAt {
+				return stop
+			}
+			return nil
+		}
+		t.Run(tt.name, func(t *testing.T) {
+			ses := cqltest.NewSession().On("from accounts", tt.result)
+			var visited []int64
+			err := c.QueryEach[account](context.Background(), ses, fieldsIndex, func(a *account) error {
+				return visit(&visited, a)
+			}, "select id, name from accounts")
+			if !errors.Is(err, tt.err) || !reflect.DeepEqual(visited, tt.visited) {
+				t.Fatalf("QueryEach() = %v, visited %v, want %v, %v", err, visited, tt.err, tt.visited)
+			}
+		})
+		t.Run(tt.name+" of the model", func(t *testing.T) {
+			ses := cqltest.NewSession().On("from accounts", tt.result)
+			var visited []int64
+			err := c.QueryEachModel(context.Background(), ses, reflect.TypeOf(account{}), fieldsIndex, func(model interface{}) error {
+				return visit(&visited, model.(*account))
+			}, "select id, name from accounts")
+			if !errors.Is(err, tt.err) || !reflect.DeepEqual(visited, tt.visited) {
+				t.Fatalf("QueryEachModel() = %v, visited %v, want %v, %v", err, visited, tt.err, tt.visited)
+			}
+		})
+	}
+}
+
+func TestIterator(t *testing.T) {
+	rows := [][]interface{}{{int64(1), "a"}, {int64(2), "b"}, {int64(3), "c"}}
+	fieldsIndex, err := c.GetColumnIndexes(reflect.TypeOf(account{}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ses := cqltest.NewSession().Rows("from accounts", []string{"id", "name"}, rows...)
+	ctx, cancel := context.WithCancel(context.Background())
+	it := c.NewIterator[account](c.WithQueryOptions(ctx, c.WithPageSize(2)), ses, fieldsIndex, "select id, name from accounts")
+	if !it.Next() || it.Value().Id != 1 {
+		t.Fatalf("Next() = %v, want the first row", it.Value())
+	}
+	first := it.Value()
+	cancel()
+	if it.Next() {
+		t.Fatal("Next() after the context is canceled must return false")
+	}
+	if first.Id != 1 {
+		t.Fatal("Next() reuses the value of the previous row")
+	}
+	if err := it.Close(); !errors.Is(err, context.Canceled) {
+		t.Fatalf("Close() = %v, want the error of the context", err)
+	}
+	if e := ses.Executed(); len(e) != 1 || e[0].PageSize != 2 {
+		t.Fatalf("executed %v, want one query with the page size 2", e)
+	}
+}
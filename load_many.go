@@ -0,0 +1,327 @@
+package cassandra
+
+import (
+	"context"
+	"encoding/hex"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/apache/cassandra-gocql-driver"
+)
+
+// InQueryLimit is the max number of ids of the table with a single key column, which are loaded by one "in" query.
+// More ids are loaded by one query per partition.
+var InQueryLimit = 10
+
+// DefaultConcurrency is the number of the concurrent queries if the concurrency is not set by WithConcurrency.
+var DefaultConcurrency = 8
+
+func GetConcurrency(ctx context.Context) int {
+	if o := GetQueryOptions(ctx); o != nil && o.Concurrency > 0 {
+		return o.Concurrency
+	}
+	return DefaultConcurrency
+}
+
+// LoadMany loads the rows of the ids, the result is aligned with ids, nil for the missing ids.
+// An id is the value of the single key, or the map of the composite keys by json name or column name.
+// The ids are grouped by partition, the partitions are loaded by concurrent queries, limited by WithConcurrency.
+func LoadMany[T any](ctx context.Context, ses Session, table string, fieldsIndex map[string]int, ids []interface{}) ([]*T, error) {
+	var t T
+	models, err := LoadManyModels(ctx, ses, reflect.TypeOf(t), table, fieldsIndex, ids)
+	if err != nil {
+		return nil, err
+	}
+	result := make([]*T, len(models))
+	for i, m := range models {
+		if m != nil {
+			result[i] = m.(*T)
+		}
+	}
+	return result, nil
+}
+
+// MapByIds maps the result of LoadMany by id. Each id is in the map: the missing ids are mapped to nil, so that m[id] == nil tells a missing id.
+func MapByIds[K comparable, T any](ids []K, models []*T) map[K]*T {
+	m := make(map[K]*T, len(ids))
+	for i, id := range ids {
+		if i < len(models) {
+			m[id] = models[i]
+		} else {
+			m[id] = nil
+		}
+	}
+	return m
+}
+
+// LoadManyByIds is LoadMany, the result is mapped by id as MapByIds: every id is in the map, nil for the missing ids.
+// K is the value of the single key, or a comparable struct of the composite keys, which is converted by ToKeyMap.
+func LoadManyByIds[K comparable, T any](ctx context.Context, ses Session, table string, fieldsIndex map[string]int, ids []K) (map[K]*T, error) {
+	keys := make([]interface{}, 0, len(ids))
+	for _, id := range ids {
+		keys = append(keys, ToKeyMap(id))
+	}
+	models, err := LoadMany[T](ctx, ses, table, fieldsIndex, keys)
+	if err != nil {
+		return nil, err
+	}
+	return MapByIds(ids, models), nil
+}
+
+// LoadManyModels is LoadMany of the model type, the result has the pointers of the models.
+func LoadManyModels(ctx context.Context, ses Session, modelType reflect.Type, table string, fieldsIndex map[string]int, ids []interface{}) ([]interface{}, error) {
+	schema := GetSchema(modelType)
+	result := make([]interface{}, len(ids))
+	if len(ids) == 0 {
+		return result, nil
+	}
+	if len(schema.Keys) == 0 {
+		return nil, fmt.Errorf("%v has no primary key", modelType.Name())
+	}
+	keys := make([][]interface{}, len(ids))
+	for i, id := range ids {
+		values, err := getKeyValues(schema, id)
+		if err != nil {
+			return nil, err
+		}
+		keys[i] = toKeyTypes(modelType, orderedKeys(schema), values)
+	}
+	fields := BuildFieldsBySchema(schema)
+	queries := make([]Statement, 0)
+	if len(schema.Keys) == 1 && len(ids) <= InQueryLimit {
+		queries = append(queries, buildInQuery(fields, table, orderedKeys(schema), keys))
+	} else {
+		groups := make(map[string][][]interface{})
+		order := make([]string, 0)
+		np := len(schema.PartitionKeys)
+		for _, k := range keys {
+			p := toKey(k[:np])
+			if _, ok := groups[p]; !ok {
+				order = append(order, p)
+			}
+			groups[p] = append(groups[p], k)
+		}
+		for _, p := range order {
+			queries = append(queries, buildInQuery(fields, table, orderedKeys(schema), groups[p]))
+		}
+	}
+	rows := make(map[string]interface{})
+	var mu sync.Mutex
+	err := runConcurrently(ctx, GetConcurrency(ctx), len(queries), func(i int) error {
+		return QueryEachModel(ctx, ses, modelType, fieldsIndex, func(model interface{}) error {
+			k := toKey(getModelKeyValues(schema, model))
+			mu.Lock()
+			rows[k] = model
+			mu.Unlock()
+			return nil
+		}, queries[i].Query, queries[i].Params...)
+	})
+	if err != nil {
+		return nil, err
+	}
+	for i, k := range keys {
+		if model, ok := rows[toKey(k)]; ok {
+			result[i] = model
+		}
+	}
+	return result, nil
+}
+
+// buildInQuery builds "where pk = ? and ck in (?,?)" for one partition, "where pk in (?,?)" for the single key,
+// or "where pk = ? and (ck1,ck2) in ((?,?),(?,?))" for the composite clustering keys.
+// schemaKeys are the partition keys then the clustering keys; the partition keys of keys are the same, except the single key.
+func buildInQuery(fields string, table string, schemaKeys []*FieldDB, keys [][]interface{}) Statement {
+	where := make([]string, 0)
+	args := make([]interface{}, 0)
+	np := 0
+	for _, k := range schemaKeys {
+		if k.Partition {
+			np++
+		}
+	}
+	if len(schemaKeys) == 1 && len(keys) > 1 {
+		params := make([]string, 0)
+		for _, k := range keys {
+			params = append(params, BuildParam(0))
+			args = append(args, k[0])
+		}
+		where = append(where, fmt.Sprintf("%s in (%s)", schemaKeys[0].Column, strings.Join(params, ",")))
+		return Statement{Query: fmt.Sprintf("select %s from %s where %s", fields, table, strings.Join(where, " and ")), Params: args}
+	}
+	if len(schemaKeys) == 1 {
+		np = 1
+	}
+	for i := 0; i < np; i++ {
+		where = append(where, schemaKeys[i].Column+" = "+BuildParam(0))
+		args = append(args, keys[0][i])
+	}
+	clustering := schemaKeys[np:]
+	if len(clustering) > 0 && len(keys) == 1 {
+		for i, k := range clustering {
+			where = append(where, k.Column+" = "+BuildParam(0))
+			args = append(args, keys[0][np+i])
+		}
+	} else if len(clustering) > 0 {
+		columns := make([]string, 0)
+		for _, k := range clustering {
+			columns = append(columns, k.Column)
+		}
+		tuples := make([]string, 0)
+		for _, k := range keys {
+			params := make([]string, 0)
+			for i := range clustering {
+				params = append(params, BuildParam(0))
+				args = append(args, k[np+i])
+			}
+			if len(clustering) == 1 {
+				tuples = append(tuples, params[0])
+			} else {
+				tuples = append(tuples, "("+strings.Join(params, ",")+")")
+			}
+		}
+		if len(clustering) == 1 {
+			where = append(where, fmt.Sprintf("%s in (%s)", columns[0], strings.Join(tuples, ",")))
+		} else {
+			where = append(where, fmt.Sprintf("(%s) in (%s)", strings.Join(columns, ","), strings.Join(tuples, ",")))
+		}
+	}
+	return Statement{Query: fmt.Sprintf("select %s from %s where %s", fields, table, strings.Join(where, " and ")), Params: args}
+}
+
+// getKeyValues returns the values of the keys of the id, partition keys first, in the order of schema.Keys.
+func getKeyValues(schema *Schema, id interface{}) ([]interface{}, error) {
+	keys := orderedKeys(schema)
+	if len(keys) == 1 {
+		if m, ok := id.(map[string]interface{}); ok {
+			if v, ok := m[keys[0].JSON]; ok {
+				return []interface{}{v}, nil
+			}
+			if v, ok := m[keys[0].Column]; ok {
+				return []interface{}{v}, nil
+			}
+		}
+		return []interface{}{id}, nil
+	}
+	m, ok := id.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("the id of the composite keys must be a map")
+	}
+	values := make([]interface{}, 0, len(keys))
+	for _, k := range keys {
+		v, ok := m[k.JSON]
+		if !ok {
+			v, ok = m[k.Column]
+		}
+		if !ok {
+			return nil, fmt.Errorf("missing key %s", k.JSON)
+		}
+		values = append(values, v)
+	}
+	return values, nil
+}
+func getModelKeyValues(schema *Schema, model interface{}) []interface{} {
+	mv := reflect.Indirect(reflect.ValueOf(model))
+	values := make([]interface{}, 0)
+	for _, k := range orderedKeys(schema) {
+		values = append(values, reflect.Indirect(mv.Field(k.Index)).Interface())
+	}
+	return values
+}
+func orderedKeys(schema *Schema) []*FieldDB {
+	return append(append([]*FieldDB{}, schema.PartitionKeys...), schema.ClusteringKeys...)
+}
+func toKey(values []interface{}) string {
+	keys := make([]string, 0, len(values))
+	for _, v := range values {
+		switch k := v.(type) {
+		case time.Time:
+			keys = append(keys, strconv.FormatInt(k.UnixNano(), 10))
+		case []byte:
+			keys = append(keys, hex.EncodeToString(k))
+		default:
+			keys = append(keys, fmt.Sprint(v))
+		}
+	}
+	return strings.Join(keys, "\x00")
+}
+
+// toKeyTypes converts the values of the keys of an id to the types of the key fields, so that the key of the id is the key of the scanned row,
+// such as the string of a uuid, or an int of a bigint column.
+func toKeyTypes(modelType reflect.Type, keys []*FieldDB, values []interface{}) []interface{} {
+	r := make([]interface{}, len(values))
+	for i, v := range values {
+		r[i] = v
+		if i < len(keys) {
+			r[i] = toKeyType(v, modelType.Field(keys[i].Index).Type)
+		}
+	}
+	return r
+}
+func toKeyType(value interface{}, t reflect.Type) interface{} {
+	v := reflect.ValueOf(value)
+	for v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			return value
+		}
+		v = v.Elem()
+	}
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if !v.IsValid() || v.Type() == t {
+		return value
+	}
+	if v.Kind() == reflect.String && t == reflect.TypeOf(gocql.UUID{}) {
+		if u, err := gocql.ParseUUID(v.String()); err == nil {
+			return u
+		}
+		return value
+	}
+	if isNumber(v.Kind()) && isNumber(t.Kind()) || v.Kind() == t.Kind() && v.Type().ConvertibleTo(t) {
+		return v.Convert(t).Interface()
+	}
+	return value
+}
+func isNumber(k reflect.Kind) bool {
+	return k >= reflect.Int && k <= reflect.Float64
+}
+
+// runConcurrently runs fn for 0..n-1 by at most concurrency goroutines, and returns the first error.
+func runConcurrently(ctx context.Context, concurrency int, n int, fn func(i int) error) error {
+	if concurrency < 1 {
+		concurrency = 1
+	}
+	sem := make(chan struct{}, concurrency)
+	var wg sync.WaitGroup
+	var once sync.Once
+	var first error
+	for i := 0; i < n; i++ {
+		select {
+		case sem <- struct{}{}:
+		case <-ctx.Done():
+			wg.Wait()
+			if first != nil {
+				return first
+			}
+			return ctx.Err()
+		}
+		wg.Add(1)
+		go func(i int) {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+			if err := fn(i); err != nil {
+				once.Do(func() {
+					first = err
+				})
+			}
+		}(i)
+	}
+	wg.Wait()
+	return first
+}
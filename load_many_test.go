@@ -0,0 +1,140 @@
+package cassandra_test
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	c "github.com/core-go/cassandra"
+	"github.com/core-go/cassandra/cqltest"
+)
+
+type account struct {
+	Id   int64  `cql:"id,partition_key"`
+	Name string `cql:"name"`
+}
+type event struct {
+	Site string    `cql:"site,partition_key"`
+	At   time.Time `cql:"at,clustering_key"`
+	Name string    `cql:"name"`
+}
+type blob struct {
+	Hash []byte `cql:"hash,partition_key"`
+	Size int    `cql:"size"`
+}
+
+func TestLoadMany(t *testing.T) {
+	at := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	tests := []struct {
+		name      string
+		modelType reflect.Type
+		table     string
+		columns   []string
+		rows      [][]interface{}
+		ids       []interface{}
+		found     []bool
+		queries   int
+	}{
+		{
+			name:      "ids of other numeric types",
+			modelType: reflect.TypeOf(account{}),
+			table:     "accounts",
+			columns:   []string{"id", "name"},
+			rows:      [][]interface{}{{int64(1), "a"}, {int64(2), "b"}},
+			ids:       []interface{}{int32(1), float64(2), 3},
+			found:     []bool{true, true, false},
+			queries:   1,
+		},
+		{
+			name:      "time in another location",
+			modelType: reflect.TypeOf(event{}),
+			table:     "events",
+			columns:   []string{"site", "at", "name"},
+			rows:      [][]interface{}{{"a", at, "x"}},
+			ids: []interface{}{
+				map[string]interface{}{"site": "a", "at": at.In(time.FixedZone("ICT", 7*3600))},
+				map[string]interface{}{"site": "a", "at": at.Add(time.Second)},
+				map[string]interface{}{"site": "b", "at": at},
+			},
+			found:   []bool{true, false, false},
+			queries: 2,
+		},
+		{
+			name:      "bytes",
+			modelType: reflect.TypeOf(blob{}),
+			table:     "blobs",
+			columns:   []string{"hash", "size"},
+			rows:      [][]interface{}{{[]byte{1, 2}, 10}},
+			ids:       []interface{}{[]byte{1, 2}, []byte{1, 3}},
+			found:     []bool{true, false},
+			queries:   1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ses := cqltest.NewSession()
+			ses.Rows("from "+tt.table, tt.columns, tt.rows...)
+			loader, err := c.NewLoaderWithProvider(ses.Provider(), tt.table, tt.modelType)
+			if err != nil {
+				t.Fatal(err)
+			}
+			models, err := loader.LoadMany(context.Background(), tt.ids)
+			if err != nil || len(models) != len(tt.ids) {
+				t.Fatalf("LoadMany() = %v, %v", models, err)
+			}
+			for i, found := range tt.found {
+				if (models[i] != nil) != found {
+					t.Fatalf("LoadMany()[%d] = %v, want found %v", i, models[i], found)
+				}
+			}
+			executed := ses.Executed()
+			if len(executed) != tt.queries {
+				t.Fatalf("executed %d queries, want %d", len(executed), tt.queries)
+			}
+			if !strings.Contains(executed[0].Query, " in (") && len(tt.ids) > 1 && tt.queries == 1 {
+				t.Fatalf("executed %q, want an in query", executed[0].Query)
+			}
+		})
+	}
+}
+
+func TestLoadManyBindsKeyTypes(t *testing.T) {
+	ses := cqltest.NewSession()
+	loader, _ := c.NewLoaderWithProvider(ses.Provider(), "accounts", reflect.TypeOf(account{}))
+	if _, err := loader.LoadMany(context.Background(), []interface{}{1, int32(2)}); err != nil {
+		t.Fatal(err)
+	}
+	if params := ses.Executed()[0].Params; !reflect.DeepEqual(params, []interface{}{int64(1), int64(2)}) {
+		t.Fatalf("params = %#v, want the ids as int64", params)
+	}
+}
+
+func TestLoadManyByIds(t *testing.T) {
+	at := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	type eventKey struct {
+		Site string    `cql:"site"`
+		At   time.Time `cql:"at"`
+	}
+	ses := cqltest.NewSession().
+		Rows("from accounts", []string{"id", "name"}, []interface{}{int64(1), "a"}).
+		Rows("from events", []string{"site", "at", "name"}, []interface{}{"a", at, "x"})
+	fieldsIndex, _ := c.GetColumnIndexes(reflect.TypeOf(account{}))
+	accounts, err := c.LoadManyByIds[int64, account](context.Background(), ses, "accounts", fieldsIndex, []int64{1, 2})
+	if err != nil || len(accounts) != 2 || accounts[1] == nil || accounts[1].Name != "a" {
+		t.Fatalf("LoadManyByIds() = %v, %v, want the account 1", accounts, err)
+	}
+	if a, ok := accounts[2]; !ok || a != nil {
+		t.Fatalf("LoadManyByIds()[2] = %v, %v, want the missing id mapped to nil", a, ok)
+	}
+	fieldsIndex, _ = c.GetColumnIndexes(reflect.TypeOf(event{}))
+	found, missing := eventKey{Site: "a", At: at}, eventKey{Site: "b", At: at}
+	events, err := c.LoadManyByIds[eventKey, event](context.Background(), ses, "events", fieldsIndex, []eventKey{found, missing})
+	if err != nil || len(events) != 2 || events[found] == nil || events[found].Name != "x" {
+		t.Fatalf("LoadManyByIds() = %v, %v, want the event of the composite keys", events, err)
+	}
+	if e, ok := events[missing]; !ok || e != nil {
+		t.Fatalf("LoadManyByIds()[missing] = %v, %v, want the missing id mapped to nil", e, ok)
+	}
+}
@@ -18,7 +18,8 @@ func InitFields(modelType reflect.Type) (map[string]int, string, error) {
 }
 
 type Loader struct {
-	DB                *gocql.ClusterConfig
+	DB                SessionProvider
+	Options           *QueryOptions
 	BuildParam        func(i int) string
 	Map               func(ctx context.Context, model interface{}) (interface{}, error)
 	modelType         reflect.Type
@@ -26,11 +27,15 @@ type Loader struct {
 	keys              []string
 	mapJsonColumnKeys map[string]string
 	fieldsIndex       map[string]int
+	schema            *Schema
 	table             string
 	query             string
 }
 
 func NewLoader(db *gocql.ClusterConfig, tableName string, modelType reflect.Type, options ...func(context.Context, interface{}) (interface{}, error)) (*Loader, error) {
+	return NewLoaderWithProvider(GetSessionProvider(db), tableName, modelType, options...)
+}
+func NewLoaderWithProvider(db SessionProvider, tableName string, modelType reflect.Type, options ...func(context.Context, interface{}) (interface{}, error)) (*Loader, error) {
 	_, idNames := FindPrimaryKeys(modelType)
 	mapJsonColumnKeys := MapJsonColumn(modelType)
 	modelsType := reflect.Zero(reflect.SliceOf(modelType)).Type()
@@ -43,8 +48,11 @@ func NewLoader(db *gocql.ClusterConfig, tableName string, modelType reflect.Type
 	if len(options) > 0 {
 		mp = options[0]
 	}
+	if err := CheckSchema(db, tableName, modelType); err != nil {
+		return nil, err
+	}
 	query := BuildQuery(tableName, modelType)
-	return &Loader{DB: db, BuildParam: BuildParam, Map: mp, modelType: modelType, modelsType: modelsType, keys: idNames, mapJsonColumnKeys: mapJsonColumnKeys, fieldsIndex: fieldsIndex, table: tableName, query: query}, nil
+	return &Loader{DB: db, Options: GetDefaultOptions(db), BuildParam: BuildParam, Map: mp, modelType: modelType, modelsType: modelsType, keys: idNames, mapJsonColumnKeys: mapJsonColumnKeys, fieldsIndex: fieldsIndex, schema: GetSchema(modelType), table: tableName, query: query}, nil
 }
 
 func (s *Loader) Keys() []string {
@@ -53,13 +61,12 @@ func (s *Loader) Keys() []string {
 
 func (s *Loader) All(ctx context.Context) (interface{}, error) {
 	result := reflect.New(s.modelsType).Interface()
-	ses, err := s.DB.CreateSession()
+	ses, err := s.DB.Session()
 	if err != nil {
 		return nil, err
 	}
-	defer ses.Close()
-
-	q := ses.Query(s.query)
+	q, cancel := ApplyOptions(WithDefaultOptions(ctx, s.Options), ses.Query(s.query))
+	defer cancel()
 	err = q.Exec()
 	if err != nil {
 		return nil, err
@@ -73,15 +80,51 @@ func (s *Loader) All(ctx context.Context) (interface{}, error) {
 	return result, err
 }
 
+// ScanAll loads all rows of the table by token ranges in parallel, in token order, see TokenScanner. Use it instead of All for the big tables.
+func (s *Loader) ScanAll(ctx context.Context) (interface{}, error) {
+	scanner, err := NewTokenScannerWithProvider(s.DB, s.table, s.modelType)
+	if err != nil {
+		return nil, err
+	}
+	scanner.Options = s.Options
+	result, err := scanner.ScanAll(ctx)
+	if err == nil && s.Map != nil {
+		return MapModels(ctx, result, s.Map)
+	}
+	return result, err
+}
+
+func (s *Loader) LoadPartition(ctx context.Context, partition interface{}) (interface{}, error) {
+	return s.LoadRange(ctx, partition)
+}
+
+func (s *Loader) LoadRange(ctx context.Context, partition interface{}, conditions ...Condition) (interface{}, error) {
+	query, values, err := BuildFindByPartition(s.query, partition, s.schema, conditions...)
+	if err != nil {
+		return nil, err
+	}
+	ses, err := s.DB.Session()
+	if err != nil {
+		return nil, err
+	}
+	result := reflect.New(s.modelsType).Interface()
+	err = QueryContext(WithDefaultOptions(ctx, s.Options), ses, s.fieldsIndex, result, query, values...)
+	if err == nil {
+		if s.Map != nil {
+			return MapModels(ctx, result, s.Map)
+		}
+	}
+	return result, err
+}
+
 func (s *Loader) Load(ctx context.Context, id interface{}) (interface{}, error) {
 	queryFindById, values := BuildFindById(s.query, id, s.mapJsonColumnKeys, s.keys)
-	ses, err := s.DB.CreateSession()
+	ses, err := s.DB.Session()
 	if err != nil {
 		return nil, err
 	}
-	defer ses.Close()
-
-	q := ses.Query(queryFindById, values...)
+	q, cancel := ApplyOptions(WithDefaultOptions(ctx, s.Options), ses.Query(queryFindById, values...))
+	defer cancel()
 	err = q.Exec()
 	if err != nil {
 		return nil, err
@@ -101,19 +144,39 @@ func (s *Loader) Load(ctx context.Context, id interface{}) (interface{}, error)
 	}
 }
 
+// LoadMany loads the models of the ids, the result is aligned with ids: it has len(ids) models, nil for the missing ids. See LoadManyModels.
+// The result is not a map by id, because an id can be a map of the composite keys, which is not comparable; see LoadManyByIds.
+func (s *Loader) LoadMany(ctx context.Context, ids []interface{}) ([]interface{}, error) {
+	ses, err := s.DB.Session()
+	if err != nil {
+		return nil, err
+	}
+	models, err := LoadManyModels(WithDefaultOptions(ctx, s.Options), ses, s.modelType, s.table, s.fieldsIndex, ids)
+	if err != nil || s.Map == nil {
+		return models, err
+	}
+	for _, model := range models {
+		if model != nil {
+			if _, err := s.Map(ctx, model); err != nil {
+				return models, err
+			}
+		}
+	}
+	return models, nil
+}
+
 func (s *Loader) LoadAndDecode(ctx context.Context, id interface{}, result interface{}) (bool, error) {
 	return s.Get(ctx, id, result)
 }
 
 func (s *Loader) Get(ctx context.Context, id interface{}, result interface{}) (bool, error) {
 	queryFindById, values := BuildFindById(s.query, id, s.mapJsonColumnKeys, s.keys)
-	ses, err := s.DB.CreateSession()
+	ses, err := s.DB.Session()
 	if err != nil {
 		return false, err
 	}
-	defer ses.Close()
-
-	q := ses.Query(queryFindById, values...)
+	q, cancel := ApplyOptions(WithDefaultOptions(ctx, s.Options), ses.Query(queryFindById, values...))
+	defer cancel()
 	err = q.Exec()
 	if err != nil {
 		return false, err
@@ -142,72 +205,18 @@ func (s *Loader) Exist(ctx context.Context, id interface{}) (bool, error) {
 }
 
 func FindPrimaryKeys(modelType reflect.Type) ([]string, []string) {
-	numField := modelType.NumField()
 	var idColumnFields []string
 	var idJsons []string
-	for i := 0; i < numField; i++ {
-		field := modelType.Field(i)
-		ormTag := field.Tag.Get("gorm")
-		tags := strings.Split(ormTag, ";")
-		for _, tag := range tags {
-			if strings.Compare(strings.TrimSpace(tag), "primary_key") == 0 {
-				k, ok := findTag(ormTag, "column")
-				if ok {
-					idColumnFields = append(idColumnFields, k)
-					tag1, ok1 := field.Tag.Lookup("json")
-					tagJsons := strings.Split(tag1, ",")
-					if ok1 && len(tagJsons) > 0 {
-						idJsons = append(idJsons, tagJsons[0])
-					}
-				}
-			}
-		}
+	for _, k := range GetSchema(modelType).Keys {
+		idColumnFields = append(idColumnFields, k.Column)
+		idJsons = append(idJsons, k.JSON)
 	}
 	return idColumnFields, idJsons
 }
-func findTag(tag string, key string) (string, bool) {
-	if has := strings.Contains(tag, key); has {
-		str1 := strings.Split(tag, ";")
-		num := len(str1)
-		for i := 0; i < num; i++ {
-			str2 := strings.Split(str1[i], ":")
-			for j := 0; j < len(str2); j++ {
-				if str2[j] == key {
-					return str2[j+1], true
-				}
-			}
-		}
-	}
-	return "", false
-}
 func MapJsonColumn(modelType reflect.Type) map[string]string {
-	numField := modelType.NumField()
 	columnNameKeys := make(map[string]string)
-	for i := 0; i < numField; i++ {
-		field := modelType.Field(i)
-		ormTag := field.Tag.Get("gorm")
-		tags := strings.Split(ormTag, ";")
-		for _, tag := range tags {
-			if strings.Compare(strings.TrimSpace(tag), "primary_key") == 0 {
-				if has := strings.Contains(ormTag, "column"); has {
-					str1 := strings.Split(ormTag, ";")
-					num := len(str1)
-					for i := 0; i < num; i++ {
-						str2 := strings.Split(str1[i], ":")
-						for j := 0; j < len(str2); j++ {
-							if str2[j] == "column" {
-								tagj, ok1 := field.Tag.Lookup("json")
-								t := strings.Split(tagj, ",")
-								if ok1 && len(t) > 0 {
-									json := t[0]
-									columnNameKeys[json] = str2[j+1]
-								}
-							}
-						}
-					}
-				}
-			}
-		}
+	for _, k := range GetSchema(modelType).Keys {
+		columnNameKeys[k.JSON] = k.Column
 	}
 	return columnNameKeys
 }
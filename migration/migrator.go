@@ -0,0 +1,341 @@
+package migration
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/apache/cassandra-gocql-driver"
+
+	q "github.com/core-go/cassandra"
+)
+
+var (
+	ErrLocked   = errors.New("migration is locked by another instance")
+	ErrLockLost = errors.New("migration lock expired or was taken by another instance")
+)
+
+// Migration is a .cql file, the version is the number before the first "_" of the file name, such as 0001 of "0001_create_users.cql".
+type Migration struct {
+	Version    int64
+	Name       string
+	File       string
+	Checksum   string
+	Statements []string
+}
+
+// Status is the state of a migration: Applied is false for the pending migrations,
+// Modified is true if the file was changed after it was applied.
+type Status struct {
+	Migration
+	Applied   bool
+	AppliedAt time.Time
+	Modified  bool
+}
+
+// Migrator applies the pending .cql files of the directory in order of version, and records them in the tracking table.
+// Only one instance migrates at a time: the lock is a row of the lock table inserted by a lightweight transaction, which expires after LockTTL
+// if the instance dies. The lock is renewed before each file, so a file must be applied within LockTTL. The statements of a file are not atomic, if a statement fails the file is not recorded, so they should be idempotent,
+// such as "create table if not exists".
+type Migrator struct {
+	DB        q.SessionProvider
+	FS        fs.FS
+	Dir       string
+	Table     string
+	LockTable string
+	LockTTL   time.Duration
+	Owner     string
+	DryRun    bool
+	Logf      func(format string, args ...interface{})
+}
+
+func NewMigrator(db *gocql.ClusterConfig, dir string) *Migrator {
+	return NewMigratorWithProvider(q.GetSessionProvider(db), os.DirFS(dir), ".")
+}
+
+// NewMigratorWithProvider reads the files of dir in fsys, which can be an embed.FS.
+func NewMigratorWithProvider(db q.SessionProvider, fsys fs.FS, dir string) *Migrator {
+	return &Migrator{DB: db, FS: fsys, Dir: dir, Table: "schema_migrations", LockTable: "schema_migrations_lock", LockTTL: 10 * time.Minute, Owner: newOwner()}
+}
+func newOwner() string {
+	host, _ := os.Hostname()
+	b := make([]byte, 4)
+	rand.Read(b)
+	return host + "-" + hex.EncodeToString(b)
+}
+
+// Load reads the .cql files, it returns an error if two files have the same version.
+func (m *Migrator) Load() ([]Migration, error) {
+	entries, err := fs.ReadDir(m.FS, m.Dir)
+	if err != nil {
+		return nil, err
+	}
+	migrations := make([]Migration, 0)
+	versions := make(map[int64]string)
+	for _, e := range entries {
+		if e.IsDir() || !strings.HasSuffix(strings.ToLower(e.Name()), ".cql") {
+			continue
+		}
+		version, name, err := ParseFileName(e.Name())
+		if err != nil {
+			return nil, err
+		}
+		if f, ok := versions[version]; ok {
+			return nil, fmt.Errorf("%s and %s have the same version %d", f, e.Name(), version)
+		}
+		versions[version] = e.Name()
+		content, err := fs.ReadFile(m.FS, path.Join(m.Dir, e.Name()))
+		if err != nil {
+			return nil, err
+		}
+		sum := sha256.Sum256(content)
+		migrations = append(migrations, Migration{Version: version, Name: name, File: e.Name(), Checksum: hex.EncodeToString(sum[:]), Statements: SplitStatements(string(content))})
+	}
+	sort.Slice(migrations, func(i, j int) bool {
+		return migrations[i].Version < migrations[j].Version
+	})
+	return migrations, nil
+}
+
+// ParseFileName parses "0001_create_users.cql" into 1 and "create_users".
+func ParseFileName(file string) (int64, string, error) {
+	base := strings.TrimSuffix(file, path.Ext(file))
+	s, name := base, ""
+	if i := strings.IndexAny(base, "_-."); i >= 0 {
+		s, name = base[:i], base[i+1:]
+	}
+	version, err := strconv.ParseInt(s, 10, 64)
+	if err != nil {
+		return 0, "", fmt.Errorf("the file name %s must start with the version number", file)
+	}
+	return version, name, nil
+}
+
+// Init creates the tracking table and the lock table if they do not exist.
+func (m *Migrator) Init(ctx context.Context) error {
+	ses, err := m.DB.Session()
+	if err != nil {
+		return err
+	}
+	stmts := []string{
+		fmt.Sprintf("create table if not exists %s (version bigint primary key, name text, file text, checksum text, applied_at timestamp)", m.Table),
+		fmt.Sprintf("create table if not exists %s (id text primary key, owner text, locked_at timestamp)", m.LockTable),
+	}
+	for _, stmt := range stmts {
+		if err := q.ExecContext(ctx, ses, stmt); err != nil {
+			return err
+		}
+	}
+	return ses.AwaitSchemaAgreement(ctx)
+}
+
+// Status lists the applied migrations and the pending migrations, in order of version.
+// The applied migrations whose files were removed are listed with the data of the tracking table. It does not create the tracking table.
+func (m *Migrator) Status(ctx context.Context) ([]Status, error) {
+	migrations, err := m.Load()
+	if err != nil {
+		return nil, err
+	}
+	applied, err := m.applied(ctx)
+	if err != nil {
+		return nil, err
+	}
+	result := make([]Status, 0, len(migrations))
+	for _, mg := range migrations {
+		s := Status{Migration: mg}
+		if a, ok := applied[mg.Version]; ok {
+			s.Applied = true
+			s.AppliedAt = a.AppliedAt
+			s.Modified = a.Checksum != mg.Checksum
+			delete(applied, mg.Version)
+		}
+		result = append(result, s)
+	}
+	for _, a := range applied {
+		result = append(result, a)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Version < result[j].Version
+	})
+	return result, nil
+}
+func (m *Migrator) applied(ctx context.Context) (map[int64]Status, error) {
+	ses, err := m.DB.Session()
+	if err != nil {
+		return nil, err
+	}
+	exist, err := m.exist(ctx, ses)
+	if err != nil || !exist {
+		return make(map[int64]Status), err
+	}
+	return m.readApplied(ctx, ses)
+}
+func (m *Migrator) readApplied(ctx context.Context, ses q.Session) (map[int64]Status, error) {
+	query, cancel := q.ApplyOptions(ctx, ses.Query(fmt.Sprintf("select version, name, file, checksum, applied_at from %s", m.Table)))
+	defer cancel()
+	iter := query.Iter()
+	result := make(map[int64]Status)
+	var s Status
+	for iter.Scan(&s.Version, &s.Name, &s.File, &s.Checksum, &s.AppliedAt) {
+		s.Applied = true
+		result[s.Version] = s
+	}
+	return result, iter.Close()
+}
+
+// Migrate applies the pending migrations, and returns them. In DryRun mode, the pending migrations are returned and logged, but not applied.
+// It fails if an applied file was modified, or another instance holds the lock.
+func (m *Migrator) Migrate(ctx context.Context) ([]Migration, error) {
+	if !m.DryRun {
+		if err := m.Init(ctx); err != nil {
+			return nil, err
+		}
+	}
+	statuses, err := m.Status(ctx)
+	if err != nil {
+		return nil, err
+	}
+	pending := make([]Migration, 0)
+	for _, s := range statuses {
+		if s.Modified {
+			return nil, fmt.Errorf("%s was modified after it was applied", s.File)
+		}
+		if !s.Applied {
+			pending = append(pending, s.Migration)
+		}
+	}
+	if m.DryRun {
+		for _, mg := range pending {
+			m.logf("dry run %s", mg.File)
+			for _, stmt := range mg.Statements {
+				m.logf("%s;", stmt)
+			}
+		}
+		return pending, nil
+	}
+	if len(pending) == 0 {
+		return pending, nil
+	}
+	if err = m.Lock(ctx); err != nil {
+		return nil, err
+	}
+	defer m.Unlock(context.Background())
+	// another instance may have applied the migrations before this instance got the lock
+	ses, err := m.DB.Session()
+	if err != nil {
+		return nil, err
+	}
+	applied, err := m.readApplied(ctx, ses)
+	if err != nil {
+		return nil, err
+	}
+	done := make([]Migration, 0)
+	for i, mg := range pending {
+		if _, ok := applied[mg.Version]; ok {
+			continue
+		}
+		if i > 0 {
+			if err = m.Renew(ctx); err != nil {
+				return done, err
+			}
+		}
+		if err = m.apply(ctx, mg); err != nil {
+			return done, err
+		}
+		done = append(done, mg)
+	}
+	return done, nil
+}
+
+// exist checks if the tracking table exists. If the keyspace is unknown, the tracking table is treated as missing, so that Status and DryRun create nothing;
+// the table should be qualified as "keyspace.table".
+func (m *Migrator) exist(ctx context.Context, ses q.Session) (bool, error) {
+	keyspace, table := "", m.Table
+	if i := strings.Index(table, "."); i >= 0 {
+		keyspace, table = table[:i], table[i+1:]
+	} else {
+		keyspace, _ = q.GetKeyspace(m.DB)
+	}
+	if len(keyspace) == 0 {
+		m.logf("the keyspace of %s is unknown, the applied migrations are not read", m.Table)
+		return false, nil
+	}
+	columns, err := q.LoadTableColumns(ctx, ses, keyspace, table)
+	return columns != nil, err
+}
+func (m *Migrator) apply(ctx context.Context, mg Migration) error {
+	ses, err := m.DB.Session()
+	if err != nil {
+		return err
+	}
+	m.logf("applying %s", mg.File)
+	for _, stmt := range mg.Statements {
+		if err := q.ExecContext(ctx, ses, stmt); err != nil {
+			return fmt.Errorf("%s: %w", mg.File, err)
+		}
+		if err := ses.AwaitSchemaAgreement(ctx); err != nil {
+			return fmt.Errorf("%s: %w", mg.File, err)
+		}
+	}
+	return q.ExecContext(ctx, ses, fmt.Sprintf("insert into %s (version, name, file, checksum, applied_at) values (?, ?, ?, ?, ?)", m.Table),
+		mg.Version, mg.Name, mg.File, mg.Checksum, time.Now())
+}
+
+// Lock gets the migration lock by a lightweight transaction, it returns ErrLocked if another instance holds it.
+func (m *Migrator) Lock(ctx context.Context) error {
+	ses, err := m.DB.Session()
+	if err != nil {
+		return err
+	}
+	applied, current, err := q.ExecCAS(ctx, ses, fmt.Sprintf("insert into %s (id, owner, locked_at) values ('migration', ?, ?) if not exists using ttl ?", m.LockTable),
+		m.Owner, time.Now(), int(m.LockTTL/time.Second))
+	if err != nil {
+		return err
+	}
+	if !applied {
+		return fmt.Errorf("%w: %v", ErrLocked, current["owner"])
+	}
+	return nil
+}
+
+// Renew extends the lock by LockTTL, it returns ErrLockLost if the lock is not held by this instance anymore.
+func (m *Migrator) Renew(ctx context.Context) error {
+	ses, err := m.DB.Session()
+	if err != nil {
+		return err
+	}
+	applied, current, err := q.ExecCAS(ctx, ses, fmt.Sprintf("update %s using ttl ? set owner = ?, locked_at = ? where id = 'migration' if owner = ?", m.LockTable),
+		int(m.LockTTL/time.Second), m.Owner, time.Now(), m.Owner)
+	if err != nil {
+		return err
+	}
+	if !applied {
+		return fmt.Errorf("%w: %v", ErrLockLost, current["owner"])
+	}
+	return nil
+}
+
+// Unlock releases the lock if it is held by this instance.
+func (m *Migrator) Unlock(ctx context.Context) error {
+	ses, err := m.DB.Session()
+	if err != nil {
+		return err
+	}
+	_, _, err = q.ExecCAS(ctx, ses, fmt.Sprintf("delete from %s where id = 'migration' if owner = ?", m.LockTable), m.Owner)
+	return err
+}
+func (m *Migrator) logf(format string, args ...interface{}) {
+	if m.Logf != nil {
+		m.Logf(format, args...)
+	}
+}
@@ -0,0 +1,146 @@
+package migration_test
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/core-go/cassandra/cqltest"
+	"github.com/core-go/cassandra/migration"
+)
+
+func TestParseFileName(t *testing.T) {
+	tests := []struct {
+		file    string
+		version int64
+		name    string
+		err     bool
+	}{
+		{"0001_create_users.cql", 1, "create_users", false},
+		{"20240102-add-index.cql", 20240102, "add-index", false},
+		{"7.cql", 7, "", false},
+		{"0002.seed.cql", 2, "seed", false},
+		{"create_users.cql", 0, "", true},
+		{"v1_create_users.cql", 0, "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.file, func(t *testing.T) {
+			version, name, err := migration.ParseFileName(tt.file)
+			if (err != nil) != tt.err || version != tt.version || name != tt.name {
+				t.Fatalf("ParseFileName(%q) = %d, %q, %v", tt.file, version, name, err)
+			}
+		})
+	}
+}
+
+func TestSplitStatements(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    []string
+	}{
+		{"statements", "create table a (id int primary key);\ncreate table b (id int primary key);", []string{"create table a (id int primary key)", "create table b (id int primary key)"}},
+		{"no trailing semicolon", "drop table a", []string{"drop table a"}},
+		{"empty statements", ";;\n ; drop table a;;", []string{"drop table a"}},
+		{"string", "insert into a (id, s) values (1, 'x;y');", []string{"insert into a (id, s) values (1, 'x;y')"}},
+		{"escaped quote", "insert into a (id, s) values (1, 'it''s;');", []string{"insert into a (id, s) values (1, 'it''s;')"}},
+		{"quoted name", `create table "a;b" (id int primary key);`, []string{`create table "a;b" (id int primary key)`}},
+		{"dollar string", "create function f() returns text language java as $$ return \"a;b\"; $$;", []string{"create function f() returns text language java as $$ return \"a;b\"; $$"}},
+		{"line comments", "-- drop table a;\ndrop table b; // drop table c;\n", []string{"drop table b"}},
+		{"block comment", "/* drop table a; */ drop table b;", []string{"drop table b"}},
+		{"comments only", "-- nothing\n/* nothing */", []string{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := migration.SplitStatements(tt.content); !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("SplitStatements() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+var files = fstest.MapFS{
+	"0001_create_users.cql":  {Data: []byte("create table if not exists users (id text primary key);")},
+	"0002_create_orders.cql": {Data: []byte("create table if not exists orders (id text primary key);")},
+}
+
+func TestStatusCreatesNothing(t *testing.T) {
+	tests := []struct {
+		name     string
+		keyspace string
+		run      func(m *migration.Migrator) (int, error)
+	}{
+		{"status without keyspace", "", func(m *migration.Migrator) (int, error) {
+			s, err := m.Status(context.Background())
+			return len(s), err
+		}},
+		{"status", "app", func(m *migration.Migrator) (int, error) {
+			s, err := m.Status(context.Background())
+			return len(s), err
+		}},
+		{"dry run without keyspace", "", func(m *migration.Migrator) (int, error) {
+			m.DryRun = true
+			pending, err := m.Migrate(context.Background())
+			return len(pending), err
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ses := cqltest.NewSession().UseKeyspace(tt.keyspace)
+			n, err := tt.run(migration.NewMigratorWithProvider(ses.Provider(), files, "."))
+			if err != nil || n != 2 {
+				t.Fatalf("got %d migrations, %v", n, err)
+			}
+			for _, e := range ses.Executed() {
+				if !strings.HasPrefix(e.Query, "select ") {
+					t.Fatalf("executed %q", e.Query)
+				}
+			}
+		})
+	}
+}
+
+func TestMigrateRenewsLock(t *testing.T) {
+	tests := []struct {
+		name string
+		lost bool
+		done int
+		err  error
+	}{
+		{"renewed", false, 2, nil},
+		{"lost", true, 1, migration.ErrLockLost},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ses := cqltest.NewSession().UseKeyspace("app")
+			if tt.lost {
+				ses.On("update schema_migrations_lock", cqltest.Result{NotApplied: true, Columns: []string{"owner"}, Rows: [][]interface{}{{"other"}}})
+			}
+			done, err := migration.NewMigratorWithProvider(ses.Provider(), files, ".").Migrate(context.Background())
+			if len(done) != tt.done || !errors.Is(err, tt.err) {
+				t.Fatalf("Migrate() = %d migrations, %v, want %d, %v", len(done), err, tt.done, tt.err)
+			}
+			var order []string
+			for _, e := range ses.Executed() {
+				switch {
+				case strings.HasPrefix(e.Query, "create table if not exists users"):
+					order = append(order, "users")
+				case strings.HasPrefix(e.Query, "create table if not exists orders"):
+					order = append(order, "orders")
+				case strings.HasPrefix(e.Query, "update schema_migrations_lock using ttl"):
+					order = append(order, "renew")
+				}
+			}
+			want := []string{"users", "renew", "orders"}
+			if tt.lost {
+				want = []string{"users", "renew"}
+			}
+			if !reflect.DeepEqual(order, want) {
+				t.Fatalf("executed %v, want %v", order, want)
+			}
+		})
+	}
+}
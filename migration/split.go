@@ -0,0 +1,70 @@
+package migration
+
+import "strings"
+
+// SplitStatements splits the content of a .cql file by ";", except the ";" in the strings, the quoted names, the $$ strings and the comments.
+// The comments "--", "//" and "/* */" are removed, the empty statements are skipped.
+func SplitStatements(content string) []string {
+	stmts := make([]string, 0)
+	var b strings.Builder
+	flush := func() {
+		if s := strings.TrimSpace(b.String()); len(s) > 0 {
+			stmts = append(stmts, s)
+		}
+		b.Reset()
+	}
+	n := len(content)
+	for i := 0; i < n; i++ {
+		c := content[i]
+		switch {
+		case c == '\'' || c == '"':
+			j := i + 1
+			for j < n {
+				if content[j] == c {
+					// '' is an escaped quote
+					if j+1 < n && content[j+1] == c {
+						j += 2
+						continue
+					}
+					break
+				}
+				j++
+			}
+			b.WriteString(content[i:min(j+1, n)])
+			i = j
+		case c == '$' && i+1 < n && content[i+1] == '$':
+			end := strings.Index(content[i+2:], "$$")
+			j := n
+			if end >= 0 {
+				j = i + 2 + end + 2
+			}
+			b.WriteString(content[i:j])
+			i = j - 1
+		case (c == '-' || c == '/') && i+1 < n && content[i+1] == c:
+			for i < n && content[i] != '\n' {
+				i++
+			}
+			b.WriteByte('\n')
+		case c == '/' && i+1 < n && content[i+1] == '*':
+			end := strings.Index(content[i+2:], "*/")
+			if end < 0 {
+				i = n
+			} else {
+				i = i + 2 + end + 1
+			}
+			b.WriteByte(' ')
+		case c == ';':
+			flush()
+		default:
+			b.WriteByte(c)
+		}
+	}
+	flush()
+	return stmts
+}
+func min(a int, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,36 @@
+package cassandra
+
+import "github.com/apache/cassandra-gocql-driver"
+
+// QueryInfo is what is known of a query before it is executed, and is not in gocql.ObservedQuery.
+// Consistency is nil if the query uses the consistency of the session.
+type QueryInfo struct {
+	Statement   string
+	Consistency *gocql.Consistency
+	PageSize    int
+	Idempotent  bool
+}
+
+// BatchInfo is what is known of a batch before it is executed, and is not in gocql.ObservedBatch.
+type BatchInfo struct {
+	Type        gocql.BatchType
+	Size        int
+	Consistency *gocql.Consistency
+}
+
+// Observer returns the gocql observers of the queries and the batches, such as the Tracer of the tracing package.
+// The driver calls the observers after each attempt, with the context of the statement.
+type Observer interface {
+	QueryObserver(info QueryInfo) gocql.QueryObserver
+	BatchObserver(info BatchInfo) gocql.BatchObserver
+}
+
+// DefaultObserver observes the statements if no observer is set by WithObserver, nil by default.
+var DefaultObserver Observer
+
+func getObserver(o *QueryOptions) Observer {
+	if o != nil && o.Observer != nil {
+		return o.Observer
+	}
+	return DefaultObserver
+}
@@ -0,0 +1,329 @@
+package cassandra
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/apache/cassandra-gocql-driver"
+)
+
+type QueryOptions struct {
+	Consistency       *gocql.Consistency
+	SerialConsistency *gocql.SerialConsistency
+	Timeout           time.Duration
+	Idempotent        *bool
+	Prepared          *bool
+	Statements        *StatementCache
+	TTL               *int
+	Timestamp         *int64
+	PageSize          int
+	Concurrency       int
+	RetryPolicy       gocql.RetryPolicy
+	Observer          Observer
+	StrictSchema      bool
+}
+type QueryOption func(*QueryOptions)
+
+func WithConsistency(consistency gocql.Consistency) QueryOption {
+	return func(o *QueryOptions) {
+		o.Consistency = &consistency
+	}
+}
+func WithSerialConsistency(consistency gocql.SerialConsistency) QueryOption {
+	return func(o *QueryOptions) {
+		o.SerialConsistency = &consistency
+	}
+}
+func WithTimeout(timeout time.Duration) QueryOption {
+	return func(o *QueryOptions) {
+		o.Timeout = timeout
+	}
+}
+func WithIdempotent(idempotent bool) QueryOption {
+	return func(o *QueryOptions) {
+		o.Idempotent = &idempotent
+	}
+}
+
+// WithPrepared makes the writers bind all values as parameters, instead of inlining numbers, booleans, empty strings, null and versions,
+// so that the statement of a table and a set of columns is always the same text, and the driver reuses its prepared statement.
+// The driver prepares each distinct text once and keeps it in its own LRU, of the size of ClusterConfig.MaxPreparedStmts.
+// The executed statements are tracked by the StatementCache of WithStatementCache, or DefaultStatementCache.
+func WithPrepared(prepared bool) QueryOption {
+	return func(o *QueryOptions) {
+		o.Prepared = &prepared
+	}
+}
+
+// WithStatementCache sets the cache which tracks the statements executed with WithPrepared, it should have the size of ClusterConfig.MaxPreparedStmts.
+func WithStatementCache(cache *StatementCache) QueryOption {
+	return func(o *QueryOptions) {
+		o.Statements = cache
+	}
+}
+
+// WithTTL sets the ttl of the inserted or updated columns, 0 means the columns never expire, even if the table has a default_time_to_live.
+func WithTTL(ttl time.Duration) QueryOption {
+	return func(o *QueryOptions) {
+		seconds := int(ttl / time.Second)
+		o.TTL = &seconds
+	}
+}
+
+// WithWriteTimestamp sets the client-side timestamp of the write.
+func WithWriteTimestamp(timestamp time.Time) QueryOption {
+	return func(o *QueryOptions) {
+		microseconds := timestamp.UnixNano() / int64(time.Microsecond)
+		o.Timestamp = &microseconds
+	}
+}
+
+// WithPageSize sets the number of rows fetched per page when the rows are streamed by Iterator.
+func WithPageSize(size int) QueryOption {
+	return func(o *QueryOptions) {
+		o.PageSize = size
+	}
+}
+
+// WithConcurrency sets the max number of the concurrent queries of LoadMany and the parallel scans.
+func WithConcurrency(concurrency int) QueryOption {
+	return func(o *QueryOptions) {
+		o.Concurrency = concurrency
+	}
+}
+
+// WithRetryPolicy sets the retry policy of the statements, such as a RetryPolicy.
+func WithRetryPolicy(policy gocql.RetryPolicy) QueryOption {
+	return func(o *QueryOptions) {
+		o.RetryPolicy = policy
+	}
+}
+
+// WithObserver sets the observer of the statements, such as a tracer.
+func WithObserver(observer Observer) QueryOption {
+	return func(o *QueryOptions) {
+		o.Observer = observer
+	}
+}
+
+// WithStrictSchema makes the constructors of the loaders and writers built from the OptionsProvider validate the model against the table by ValidateSchema,
+// and fail if a column is missing, a type or a key does not match. The extra columns of the table are not errors.
+func WithStrictSchema() QueryOption {
+	return func(o *QueryOptions) {
+		o.StrictSchema = true
+	}
+}
+func NewQueryOptions(opts ...QueryOption) *QueryOptions {
+	o := &QueryOptions{}
+	for _, opt := range opts {
+		opt(o)
+	}
+	return o
+}
+
+// Merge returns a copy of the options, in which the fields set in other override the fields of o.
+func (o *QueryOptions) Merge(other *QueryOptions) *QueryOptions {
+	r := &QueryOptions{}
+	if o != nil {
+		*r = *o
+	}
+	if other == nil {
+		return r
+	}
+	if other.Consistency != nil {
+		r.Consistency = other.Consistency
+	}
+	if other.SerialConsistency != nil {
+		r.SerialConsistency = other.SerialConsistency
+	}
+	if other.Timeout > 0 {
+		r.Timeout = other.Timeout
+	}
+	if other.Idempotent != nil {
+		r.Idempotent = other.Idempotent
+	}
+	if other.Prepared != nil {
+		r.Prepared = other.Prepared
+	}
+	if other.Statements != nil {
+		r.Statements = other.Statements
+	}
+	if other.TTL != nil {
+		r.TTL = other.TTL
+	}
+	if other.Timestamp != nil {
+		r.Timestamp = other.Timestamp
+	}
+	if other.PageSize > 0 {
+		r.PageSize = other.PageSize
+	}
+	if other.Concurrency > 0 {
+		r.Concurrency = other.Concurrency
+	}
+	if other.RetryPolicy != nil {
+		r.RetryPolicy = other.RetryPolicy
+	}
+	if other.Observer != nil {
+		r.Observer = other.Observer
+	}
+	if other.StrictSchema {
+		r.StrictSchema = true
+	}
+	return r
+}
+
+type optionsKey struct{}
+
+// WithQueryOptions returns a context carrying the options for the queries executed with it.
+func WithQueryOptions(ctx context.Context, opts ...QueryOption) context.Context {
+	return context.WithValue(ctx, optionsKey{}, GetQueryOptions(ctx).Merge(NewQueryOptions(opts...)))
+}
+func GetQueryOptions(ctx context.Context) *QueryOptions {
+	if ctx == nil {
+		return nil
+	}
+	o, _ := ctx.Value(optionsKey{}).(*QueryOptions)
+	return o
+}
+
+// WithDefaultOptions returns a context carrying the default options, overridden by the options already in the context.
+func WithDefaultOptions(ctx context.Context, defaults *QueryOptions) context.Context {
+	if defaults == nil {
+		return ctx
+	}
+	return context.WithValue(ctx, optionsKey{}, defaults.Merge(GetQueryOptions(ctx)))
+}
+
+// GetUsing returns the ttl, timestamp and prepared mode of the write: the options of the context override the default ttl of the schema.
+func GetUsing(ctx context.Context, schema *Schema) *Using {
+	using := &Using{}
+	if schema != nil && schema.TTL > 0 {
+		ttl := schema.TTL
+		using.TTL = &ttl
+	}
+	o := GetQueryOptions(ctx)
+	if o != nil {
+		if o.TTL != nil {
+			using.TTL = o.TTL
+		}
+		if o.Timestamp != nil {
+			using.Timestamp = *o.Timestamp
+		}
+		if o.Prepared != nil {
+			using.Prepared = *o.Prepared
+		}
+	}
+	return using
+}
+
+// ApplyOptions applies the options of the context to the query. The returned cancel function must be called after the query is done.
+func ApplyOptions(ctx context.Context, q CqlQuery) (CqlQuery, context.CancelFunc) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	cancel := func() {}
+	o := GetQueryOptions(ctx)
+	if o != nil {
+		if o.Timeout > 0 {
+			ctx, cancel = context.WithTimeout(ctx, o.Timeout)
+		}
+		if o.Consistency != nil {
+			q = q.Consistency(*o.Consistency)
+		}
+		if o.SerialConsistency != nil {
+			q = q.SerialConsistency(*o.SerialConsistency)
+		}
+		if o.Idempotent != nil {
+			q = q.Idempotent(*o.Idempotent)
+		}
+	}
+	if cache := getStatementCache(o); cache != nil {
+		cache.Touch(q.Statement())
+	}
+	if policy := getRetryPolicy(o); policy != nil {
+		if (o == nil || o.Idempotent == nil) && IsIdempotent(q.Statement()) {
+			q = q.Idempotent(true)
+		}
+		q = q.RetryPolicy(policy)
+	}
+	if observer := getObserver(o); observer != nil {
+		info := QueryInfo{Statement: q.Statement(), Idempotent: IsIdempotent(q.Statement())}
+		if o != nil {
+			info.Consistency = o.Consistency
+			info.PageSize = o.PageSize
+			if o.Idempotent != nil {
+				info.Idempotent = *o.Idempotent
+			}
+		}
+		q = q.Observer(observer.QueryObserver(info))
+	}
+	return q.WithContext(ctx), cancel
+}
+func ApplyBatchOptions(ctx context.Context, b Batch) (Batch, context.CancelFunc) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	cancel := func() {}
+	o := GetQueryOptions(ctx)
+	if o != nil {
+		if o.Timeout > 0 {
+			ctx, cancel = context.WithTimeout(ctx, o.Timeout)
+		}
+		if o.Consistency != nil {
+			b = b.Consistency(*o.Consistency)
+		}
+		if o.SerialConsistency != nil {
+			b = b.SerialConsistency(*o.SerialConsistency)
+		}
+	}
+	if cache := getStatementCache(o); cache != nil {
+		for _, stmt := range b.Statements() {
+			cache.Touch(stmt.Query)
+		}
+	}
+	if policy := getRetryPolicy(o); policy != nil {
+		b = b.RetryPolicy(policy)
+	}
+	if observer := getObserver(o); observer != nil {
+		info := BatchInfo{Type: b.Type(), Size: b.Size()}
+		if o != nil {
+			info.Consistency = o.Consistency
+		}
+		b = b.Observer(observer.BatchObserver(info))
+	}
+	return b.WithContext(ctx), cancel
+}
+
+// IsIdempotent returns true for the select statements, which are always safe to retry.
+// The writes are idempotent if they are marked by WithIdempotent or by DefaultIdempotence of the cluster config.
+func IsIdempotent(stmt string) bool {
+	return strings.HasPrefix(strings.ToLower(strings.TrimSpace(stmt)), "select ")
+}
+func getRetryPolicy(o *QueryOptions) gocql.RetryPolicy {
+	policy := DefaultRetryPolicy
+	if o != nil && o.RetryPolicy != nil {
+		policy = o.RetryPolicy
+	}
+	if p, ok := policy.(*RetryPolicy); ok && p != nil {
+		return p.bind()
+	}
+	return policy
+}
+
+type OptionsProvider struct {
+	SessionProvider
+	Options *QueryOptions
+}
+
+// NewOptionsProvider wraps the provider with the default options of the loaders and writers built from it.
+// The session is still shared with the wrapped provider.
+func NewOptionsProvider(provider SessionProvider, opts ...QueryOption) *OptionsProvider {
+	return &OptionsProvider{SessionProvider: provider, Options: NewQueryOptions(opts...)}
+}
+func GetDefaultOptions(provider SessionProvider) *QueryOptions {
+	if p, ok := provider.(*OptionsProvider); ok {
+		return p.Options
+	}
+	return nil
+}
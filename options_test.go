@@ -0,0 +1,96 @@
+package cassandra_test
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/apache/cassandra-gocql-driver"
+	c "github.com/core-go/cassandra"
+	"github.com/core-go/cassandra/cqltest"
+)
+
+type expiringSession struct {
+	Id   string   `cql:"id,partition_key"`
+	Data string   `cql:"data"`
+	_    struct{} `cql:",ttl=3600"`
+}
+
+func TestUsing(t *testing.T) {
+	schema := c.GetSchema(reflect.TypeOf(expiringSession{}))
+	tests := []struct {
+		name   string
+		schema *c.Schema
+		opts   []c.QueryOption
+		using  string
+		args   []interface{}
+	}{
+		{"no ttl", nil, nil, "", nil},
+		{"default ttl of the schema", schema, nil, " using ttl ?", []interface{}{3600}},
+		{"ttl", nil, []c.QueryOption{c.WithTTL(time.Minute)}, " using ttl ?", []interface{}{60}},
+		{"ttl overrides the schema", schema, []c.QueryOption{c.WithTTL(time.Minute)}, " using ttl ?", []interface{}{60}},
+		{"ttl 0 never expires", schema, []c.QueryOption{c.WithTTL(0)}, " using ttl ?", []interface{}{0}},
+		{"timestamp", nil, []c.QueryOption{c.WithWriteTimestamp(time.UnixMicro(1000))}, " using timestamp ?", []interface{}{int64(1000)}},
+		{"ttl and timestamp", nil, []c.QueryOption{c.WithTTL(0), c.WithWriteTimestamp(time.UnixMicro(1000))}, " using ttl ? and timestamp ?", []interface{}{0, int64(1000)}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := c.WithQueryOptions(context.Background(), tt.opts...)
+			using, args := c.BuildUsing(c.GetUsing(ctx, tt.schema))
+			if using != tt.using || !reflect.DeepEqual(args, tt.args) {
+				t.Fatalf("BuildUsing() = %q, %v, want %q, %v", using, args, tt.using, tt.args)
+			}
+		})
+	}
+}
+
+func TestApplyOptions(t *testing.T) {
+	tests := []struct {
+		name     string
+		defaults []c.QueryOption
+		opts     []c.QueryOption
+		batch    bool
+		want     cqltest.Executed
+		timeout  time.Duration
+	}{
+		{"no options", nil, nil, false, cqltest.Executed{}, 0},
+		{"consistency", nil, []c.QueryOption{c.WithConsistency(gocql.Quorum)}, false, cqltest.Executed{Consistency: gocql.Quorum}, 0},
+		{"serial consistency", nil, []c.QueryOption{c.WithSerialConsistency(gocql.LocalSerial)}, false, cqltest.Executed{SerialConsistency: gocql.LocalSerial}, 0},
+		{"idempotent", nil, []c.QueryOption{c.WithIdempotent(true)}, false, cqltest.Executed{Idempotent: true}, 0},
+		{"timeout", nil, []c.QueryOption{c.WithTimeout(time.Minute)}, false, cqltest.Executed{}, time.Minute},
+		{"defaults of the provider", []c.QueryOption{c.WithConsistency(gocql.One), c.WithTimeout(time.Hour)}, nil, false, cqltest.Executed{Consistency: gocql.One}, time.Hour},
+		{"context overrides the defaults", []c.QueryOption{c.WithConsistency(gocql.One), c.WithIdempotent(true)}, []c.QueryOption{c.WithConsistency(gocql.LocalQuorum)}, false, cqltest.Executed{Consistency: gocql.LocalQuorum, Idempotent: true}, 0},
+		{"batch", []c.QueryOption{c.WithConsistency(gocql.One)}, []c.QueryOption{c.WithSerialConsistency(gocql.LocalSerial), c.WithTimeout(time.Minute)}, true, cqltest.Executed{Consistency: gocql.One, SerialConsistency: gocql.LocalSerial, Idempotent: true}, time.Minute},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ses := cqltest.NewSession()
+			writer, err := c.NewWriterWithProvider(c.NewOptionsProvider(ses.Provider(), tt.defaults...), "profiles", reflect.TypeOf(profile{}))
+			if err != nil {
+				t.Fatal(err)
+			}
+			ctx := c.WithQueryOptions(context.Background(), tt.opts...)
+			start := time.Now()
+			if tt.batch {
+				_, err = c.UpdateBatch(c.WithDefaultOptions(ctx, writer.Options), ses, "profiles", []profile{{Id: "1", Name: "Peter"}, {Id: "2", Name: "Mary"}})
+			} else {
+				_, err = writer.Update(ctx, &profile{Id: "1", Name: "Peter"})
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			e := ses.Executed()[0]
+			if e.Consistency != tt.want.Consistency || e.SerialConsistency != tt.want.SerialConsistency || e.Idempotent != tt.want.Idempotent {
+				t.Fatalf("executed with consistency %v, serial consistency %v, idempotent %v, want %v, %v, %v",
+					e.Consistency, e.SerialConsistency, e.Idempotent, tt.want.Consistency, tt.want.SerialConsistency, tt.want.Idempotent)
+			}
+			if tt.timeout == 0 && !e.Deadline.IsZero() {
+				t.Fatalf("executed with a deadline %v, want none", e.Deadline)
+			}
+			if tt.timeout > 0 && (e.Deadline.Before(start.Add(tt.timeout)) || e.Deadline.After(time.Now().Add(tt.timeout))) {
+				t.Fatalf("executed with the deadline %v, want in %v", e.Deadline, tt.timeout)
+			}
+		})
+	}
+}
@@ -0,0 +1,222 @@
+package cassandra
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"reflect"
+	"sync"
+	"time"
+)
+
+const (
+	pageTokenVersion         = 1
+	unsignedPageTokenVersion = 2
+	queryHashSize            = 16
+	pageTokenHeader          = 1 + 8 + queryHashSize
+)
+
+var (
+	ErrInvalidPageToken  = errors.New("invalid page token")
+	ErrExpiredPageToken  = errors.New("page token expired")
+	ErrPageTokenMismatch = errors.New("page token does not match the query")
+	ErrNoPageTokenKey    = errors.New("page token key is not configured, call SetPageTokenKey or SetPageTokenCodec")
+	ErrEmptyPageTokenKey = errors.New("page token key is empty")
+)
+
+// DefaultPageTokenTTL is the expiry of the tokens of the codec set by SetPageTokenKey, and of the unsigned tokens.
+const DefaultPageTokenTTL = 24 * time.Hour
+
+// Signer signs and verifies the page tokens.
+type Signer interface {
+	Sign(data []byte) []byte
+	Verify(data []byte, signature []byte) bool
+}
+
+// HMACSigner signs with the first key by HMAC-SHA256, and verifies with any key, so that the keys can be rotated:
+// put the new key first, and remove the old key when the tokens signed by it are expired.
+type HMACSigner struct {
+	Keys [][]byte
+}
+
+func NewHMACSigner(keys ...[]byte) *HMACSigner {
+	return &HMACSigner{Keys: keys}
+}
+func (s *HMACSigner) Sign(data []byte) []byte {
+	if len(s.Keys) == 0 {
+		return nil
+	}
+	return sign(s.Keys[0], data)
+}
+func (s *HMACSigner) Verify(data []byte, signature []byte) bool {
+	for _, key := range s.Keys {
+		if hmac.Equal(sign(key, data), signature) {
+			return true
+		}
+	}
+	return false
+}
+func sign(key []byte, data []byte) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write(data)
+	return mac.Sum(nil)
+}
+
+// PageTokenCodec wraps the page state of the driver with the hash of the query and the parameters, and the expiry,
+// signs it and encodes it as URL-safe base64, so that the client cannot read or change the page state,
+// or replay the token against another query or another filter.
+// A codec without Signer does not sign the tokens: they are still checked against the query and the expiry, but the client can forge them.
+type PageTokenCodec struct {
+	Signer Signer
+	TTL    time.Duration
+	Now    func() time.Time
+}
+
+func NewPageTokenCodec(signer Signer, ttl time.Duration) *PageTokenCodec {
+	return &PageTokenCodec{Signer: signer, TTL: ttl, Now: time.Now}
+}
+
+var (
+	pageTokensMu sync.RWMutex
+	pageTokens   *PageTokenCodec
+)
+
+// GetPageTokenCodec returns the codec of the search builders, or nil if no key is configured.
+func GetPageTokenCodec() *PageTokenCodec {
+	pageTokensMu.RLock()
+	defer pageTokensMu.RUnlock()
+	return pageTokens
+}
+
+// getPageTokenCodec returns the codec of GetPageTokenCodec, or the codec of the unsigned tokens if no key is configured.
+func getPageTokenCodec() *PageTokenCodec {
+	if codec := GetPageTokenCodec(); codec != nil {
+		return codec
+	}
+	return NewPageTokenCodec(nil, DefaultPageTokenTTL)
+}
+
+// SetPageTokenCodec sets the codec of the search builders. The services with several instances must share its keys.
+func SetPageTokenCodec(codec *PageTokenCodec) {
+	pageTokensMu.Lock()
+	defer pageTokensMu.Unlock()
+	pageTokens = codec
+}
+
+// SetPageTokenKey sets the keys of the default codec, the first key signs the new tokens. The keys must not be empty.
+// Until a key is configured, the paged queries use unsigned tokens.
+func SetPageTokenKey(keys ...[]byte) error {
+	if len(keys) == 0 {
+		return ErrNoPageTokenKey
+	}
+	for _, key := range keys {
+		if len(key) == 0 {
+			return ErrEmptyPageTokenKey
+		}
+	}
+	ttl := DefaultPageTokenTTL
+	if codec := GetPageTokenCodec(); codec != nil {
+		ttl = codec.TTL
+	}
+	SetPageTokenCodec(NewPageTokenCodec(NewHMACSigner(keys...), ttl))
+	return nil
+}
+
+// Encode returns the token of the page state of the query, or an empty string if there is no next page.
+func (c *PageTokenCodec) Encode(pageState []byte, sql string, values []interface{}) (string, error) {
+	if len(pageState) == 0 {
+		return "", nil
+	}
+	data := make([]byte, pageTokenHeader, pageTokenHeader+len(pageState))
+	data[0] = pageTokenVersion
+	if c.Signer == nil {
+		data[0] = unsignedPageTokenVersion
+	}
+	var expiry int64
+	if c.TTL > 0 {
+		expiry = c.now().Add(c.TTL).Unix()
+	}
+	binary.BigEndian.PutUint64(data[1:9], uint64(expiry))
+	copy(data[9:pageTokenHeader], HashQuery(sql, values))
+	data = append(data, pageState...)
+	if c.Signer == nil {
+		return base64.RawURLEncoding.EncodeToString(data), nil
+	}
+	signature := c.Signer.Sign(data)
+	if len(signature) == 0 {
+		return "", ErrNoPageTokenKey
+	}
+	return base64.RawURLEncoding.EncodeToString(append(data, signature...)), nil
+}
+
+// Decode verifies the token and returns the page state. An empty token is the first page, the page state is nil.
+func (c *PageTokenCodec) Decode(token string, sql string, values []interface{}) ([]byte, error) {
+	if len(token) == 0 {
+		return nil, nil
+	}
+	b, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return nil, ErrInvalidPageToken
+	}
+	data := b
+	if c.Signer == nil {
+		if len(b) <= pageTokenHeader || b[0] != unsignedPageTokenVersion {
+			return nil, ErrInvalidPageToken
+		}
+	} else {
+		if len(b) <= pageTokenHeader+sha256.Size || b[0] != pageTokenVersion {
+			return nil, ErrInvalidPageToken
+		}
+		var signature []byte
+		data, signature = b[:len(b)-sha256.Size], b[len(b)-sha256.Size:]
+		if !c.Signer.Verify(data, signature) {
+			return nil, ErrInvalidPageToken
+		}
+	}
+	expiry := int64(binary.BigEndian.Uint64(data[1:9]))
+	if expiry > 0 && c.now().Unix() > expiry {
+		return nil, ErrExpiredPageToken
+	}
+	if !hmac.Equal(data[9:pageTokenHeader], HashQuery(sql, values)) {
+		return nil, ErrPageTokenMismatch
+	}
+	return data[pageTokenHeader:], nil
+}
+func (c *PageTokenCodec) now() time.Time {
+	if c.Now == nil {
+		return time.Now()
+	}
+	return c.Now()
+}
+
+// HashQuery returns the hash of the statement and the types and values of the parameters.
+// The pointers are hashed as the values they point to, as they are bound, not by their addresses; a nil pointer is hashed as nil.
+func HashQuery(sql string, values []interface{}) []byte {
+	h := sha256.New()
+	h.Write([]byte(sql))
+	for _, v := range values {
+		rv := reflect.ValueOf(v)
+		for rv.Kind() == reflect.Ptr && !rv.IsNil() {
+			rv = rv.Elem()
+		}
+		if rv.Kind() == reflect.Ptr {
+			fmt.Fprintf(h, "\x00%T:<nil>", v)
+			continue
+		}
+		if rv.IsValid() {
+			v = rv.Interface()
+		}
+		fmt.Fprintf(h, "\x00%T:%v", v, v)
+	}
+	return h.Sum(nil)[:queryHashSize]
+}
+
+// QueryWithToken is QueryWithMapContext: the token is verified against the query and the parameters by the codec of GetPageTokenCodec,
+// and the token of the next page is signed; the tokens are unsigned if no key is configured.
+func QueryWithToken(ctx context.Context, ses Session, fieldsIndex map[string]int, results interface{}, sql string, values []interface{}, max int64, token string, options ...func(context.Context, interface{}) (interface{}, error)) (string, error) {
+	return QueryWithMapContext(ctx, ses, fieldsIndex, results, sql, values, max, token, options...)
+}
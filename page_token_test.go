@@ -0,0 +1,209 @@
+package cassandra_test
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	c "github.com/core-go/cassandra"
+	"github.com/core-go/cassandra/cqltest"
+)
+
+func TestPageTokenCodec(t *testing.T) {
+	now := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	oldKey, newKey := []byte("old key"), []byte("new key")
+	sql := "select * from users where status = ? and level = ?"
+	tests := []struct {
+		name   string
+		signer *c.HMACSigner
+		verify *c.HMACSigner
+		sql    string
+		values []interface{}
+		after  time.Duration
+		tamper bool
+		err    error
+	}{
+		{"valid", c.NewHMACSigner(newKey), c.NewHMACSigner(newKey), sql, []interface{}{"active", 1}, 0, false, nil},
+		{"rotated key", c.NewHMACSigner(oldKey), c.NewHMACSigner(newKey, oldKey), sql, []interface{}{"active", 1}, 0, false, nil},
+		{"unknown key", c.NewHMACSigner(oldKey), c.NewHMACSigner(newKey), sql, []interface{}{"active", 1}, 0, false, c.ErrInvalidPageToken},
+		{"tampered", c.NewHMACSigner(newKey), c.NewHMACSigner(newKey), sql, []interface{}{"active", 1}, 0, true, c.ErrInvalidPageToken},
+		{"other query", c.NewHMACSigner(newKey), c.NewHMACSigner(newKey), "select * from orders where status = ? and level = ?", []interface{}{"active", 1}, 0, false, c.ErrPageTokenMismatch},
+		{"other filter", c.NewHMACSigner(newKey), c.NewHMACSigner(newKey), sql, []interface{}{"locked", 1}, 0, false, c.ErrPageTokenMismatch},
+		{"other type", c.NewHMACSigner(newKey), c.NewHMACSigner(newKey), sql, []interface{}{"active", "1"}, 0, false, c.ErrPageTokenMismatch},
+		{"expired", c.NewHMACSigner(newKey), c.NewHMACSigner(newKey), sql, []interface{}{"active", 1}, time.Hour + time.Second, false, c.ErrExpiredPageToken},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			encoder := c.NewPageTokenCodec(tt.signer, time.Hour)
+			encoder.Now = func() time.Time { return now }
+			token, err := encoder.Encode([]byte("state"), sql, []interface{}{"active", 1})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tt.tamper {
+				token = token[:len(token)-2] + "AA"
+			}
+			decoder := c.NewPageTokenCodec(tt.verify, time.Hour)
+			decoder.Now = func() time.Time { return now.Add(tt.after) }
+			state, err := decoder.Decode(token, tt.sql, tt.values)
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("Decode() error = %v, want %v", err, tt.err)
+			}
+			if err == nil && string(state) != "state" {
+				t.Fatalf("Decode() = %q, want the page state", state)
+			}
+		})
+	}
+}
+
+func TestPageTokenCodecWithoutKey(t *testing.T) {
+	if _, err := c.NewPageTokenCodec(c.NewHMACSigner(), time.Hour).Encode([]byte("state"), "select * from users", nil); !errors.Is(err, c.ErrNoPageTokenKey) {
+		t.Fatalf("Encode() error = %v, want ErrNoPageTokenKey", err)
+	}
+	sql := "select * from users where status = ?"
+	unsigned := c.NewPageTokenCodec(nil, time.Hour)
+	signed := c.NewPageTokenCodec(c.NewHMACSigner([]byte("key")), time.Hour)
+	token, err := unsigned.Encode([]byte("state"), sql, []interface{}{"active"})
+	if err != nil || len(token) == 0 {
+		t.Fatalf("Encode() of the unsigned codec = %q, %v", token, err)
+	}
+	signedToken, err := signed.Encode([]byte("state"), sql, []interface{}{"active"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name   string
+		codec  *c.PageTokenCodec
+		token  string
+		values []interface{}
+		err    error
+	}{
+		{"unsigned", unsigned, token, []interface{}{"active"}, nil},
+		{"unsigned with other filter", unsigned, token, []interface{}{"locked"}, c.ErrPageTokenMismatch},
+		{"unsigned token of the signed codec", signed, token, []interface{}{"active"}, c.ErrInvalidPageToken},
+		{"signed token of the unsigned codec", unsigned, signedToken, []interface{}{"active"}, c.ErrInvalidPageToken},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			state, err := tt.codec.Decode(tt.token, sql, tt.values)
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("Decode() error = %v, want %v", err, tt.err)
+			}
+			if err == nil && string(state) != "state" {
+				t.Fatalf("Decode() = %q, want the page state", state)
+			}
+		})
+	}
+}
+
+func TestHashQuery(t *testing.T) {
+	sql := "select * from users where name = ?"
+	a, b, same := "a", "b", "a"
+	var none *string
+	tests := []struct {
+		name  string
+		x     []interface{}
+		y     []interface{}
+		equal bool
+	}{
+		{"pointers to the same value", []interface{}{&a}, []interface{}{&same}, true},
+		{"pointers to other values", []interface{}{&a}, []interface{}{&b}, false},
+		{"pointer to pointer", []interface{}{&a}, []interface{}{func() **string { p := &same; return &p }()}, true},
+		{"nil pointers", []interface{}{none}, []interface{}{(*string)(nil)}, true},
+		{"nil pointer and empty value", []interface{}{none}, []interface{}{new(string)}, false},
+		{"pointer and value, bound the same", []interface{}{&a}, []interface{}{a}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if equal := string(c.HashQuery(sql, tt.x)) == string(c.HashQuery(sql, tt.y)); equal != tt.equal {
+				t.Fatalf("HashQuery() of %v and %v equal %v, want %v", tt.x, tt.y, equal, tt.equal)
+			}
+		})
+	}
+	p := "a"
+	hash := c.HashQuery(sql, []interface{}{&p})
+	p = "b"
+	if string(c.HashQuery(sql, []interface{}{&p})) == string(hash) {
+		t.Fatal("HashQuery() hashes the address of the pointer, not its value")
+	}
+}
+
+func TestSetPageTokenKey(t *testing.T) {
+	defer c.SetPageTokenCodec(nil)
+	tests := []struct {
+		name string
+		keys [][]byte
+		err  error
+	}{
+		{"no keys", nil, c.ErrNoPageTokenKey},
+		{"empty key", [][]byte{[]byte("key"), {}}, c.ErrEmptyPageTokenKey},
+		{"keys", [][]byte{[]byte("new"), []byte("old")}, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c.SetPageTokenCodec(nil)
+			err := c.SetPageTokenKey(tt.keys...)
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("SetPageTokenKey() error = %v, want %v", err, tt.err)
+			}
+			if configured := c.GetPageTokenCodec() != nil; configured != (tt.err == nil) {
+				t.Fatalf("codec configured = %v", configured)
+			}
+		})
+	}
+}
+
+func TestQueryWithPage(t *testing.T) {
+	defer c.SetPageTokenCodec(nil)
+	ses := cqltest.NewSession()
+	ses.Rows("from accounts", []string{"id", "name"}, []interface{}{int64(1), "a"}, []interface{}{int64(2), "b"}, []interface{}{int64(3), "c"})
+	fieldsIndex, err := c.GetColumnIndexes(reflect.TypeOf(account{}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	sql := "select id, name from accounts where name > ?"
+
+	c.SetPageTokenCodec(nil)
+	var accounts []account
+	unsigned, err := c.QueryWithPage(ses, fieldsIndex, &accounts, 2, "", sql, "")
+	if err != nil || len(accounts) != 2 || len(unsigned) == 0 {
+		t.Fatalf("QueryWithPage() without key = %d rows, %q, %v, want the first page", len(accounts), unsigned, err)
+	}
+	accounts = nil
+	if next, err := c.QueryWithMap(ses, fieldsIndex, &accounts, sql, []interface{}{""}, 2, unsigned); err != nil || len(accounts) != 1 || next != "" {
+		t.Fatalf("QueryWithMap() without key = %d rows, %q, %v, want the last page", len(accounts), next, err)
+	}
+
+	if err := c.SetPageTokenKey([]byte("key")); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name   string
+		values []interface{}
+		rows   int
+		next   bool
+		err    error
+	}{
+		{"first page", []interface{}{""}, 2, true, nil},
+		{"last page", []interface{}{""}, 1, false, nil},
+		{"replayed with another filter", []interface{}{"b"}, 0, false, c.ErrPageTokenMismatch},
+	}
+	token := ""
+	for _, tt := range tests {
+		var page []account
+		next, err := c.QueryWithPage(ses, fieldsIndex, &page, 2, token, sql, tt.values...)
+		if !errors.Is(err, tt.err) || len(page) != tt.rows || (len(next) > 0) != tt.next {
+			t.Fatalf("%s: QueryWithPage() = %d rows, %q, %v", tt.name, len(page), next, err)
+		}
+		if _, err := c.QueryWithMap(ses, fieldsIndex, &page, sql, tt.values, 2, "3"); !errors.Is(err, c.ErrInvalidPageToken) {
+			t.Fatalf("%s: QueryWithMap() accepts the raw page state: %v", tt.name, err)
+		}
+		if next != "" {
+			token = next
+		}
+	}
+	if _, err := c.QueryWithPage(ses, fieldsIndex, &accounts, 2, unsigned, sql, ""); !errors.Is(err, c.ErrInvalidPageToken) {
+		t.Fatalf("QueryWithPage() accepts the unsigned token when a key is configured: %v", err)
+	}
+}
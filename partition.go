@@ -0,0 +1,78 @@
+package cassandra
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Condition is a restriction on a clustering column, such as created >= ?.
+type Condition struct {
+	Column   string
+	Operator string
+	Value    interface{}
+}
+
+func isRangeOperator(operator string) bool {
+	switch operator {
+	case "=", ">", ">=", "<", "<=":
+		return true
+	default:
+		return false
+	}
+}
+func getField(schema *Schema, name string) (*FieldDB, bool) {
+	if f, ok := schema.Fields[name]; ok {
+		return f, true
+	}
+	for _, f := range schema.Columns {
+		if f.JSON == name {
+			return f, true
+		}
+	}
+	return nil, false
+}
+
+// BuildFindByPartition builds the query to load the rows of a partition.
+// If the partition key has one column, partition can be the value, otherwise it must be a map of json names or column names to values.
+// The conditions are on clustering columns only, to load a range of rows.
+func BuildFindByPartition(query string, partition interface{}, schema *Schema, conditions ...Condition) (string, []interface{}, error) {
+	keys := schema.PartitionKeys
+	if len(keys) == 0 {
+		return "", nil, errors.New("no partition key")
+	}
+	where := make([]string, 0)
+	args := make([]interface{}, 0)
+	ids, isMap := partition.(map[string]interface{})
+	if len(keys) == 1 && !isMap {
+		where = append(where, keys[0].Column+"="+BuildParam(1))
+		args = append(args, partition)
+	} else {
+		if !isMap {
+			return "", nil, errors.New("partition must be a map for composite partition key")
+		}
+		for _, k := range keys {
+			v, ok := ids[k.JSON]
+			if !ok {
+				v, ok = ids[k.Column]
+			}
+			if !ok {
+				return "", nil, fmt.Errorf("missing partition key %s", k.Column)
+			}
+			where = append(where, k.Column+"="+BuildParam(len(args)+1))
+			args = append(args, v)
+		}
+	}
+	for _, c := range conditions {
+		f, ok := getField(schema, c.Column)
+		if !ok || !f.Clustering {
+			return "", nil, fmt.Errorf("%s is not a clustering column", c.Column)
+		}
+		if !isRangeOperator(c.Operator) {
+			return "", nil, fmt.Errorf("invalid operator %s", c.Operator)
+		}
+		where = append(where, f.Column+c.Operator+BuildParam(len(args)+1))
+		args = append(args, c.Value)
+	}
+	return fmt.Sprintf("%v where %v", strings.TrimSpace(query), strings.Join(where, " and ")), args, nil
+}
@@ -7,10 +7,11 @@ import (
 	"time"
 
 	"github.com/apache/cassandra-gocql-driver"
+	c "github.com/core-go/cassandra"
 )
 
 type PasscodeRepository struct {
-	db            *gocql.ClusterConfig
+	db            c.SessionProvider
 	tableName     string
 	idName        string
 	passcodeName  string
@@ -21,6 +22,9 @@ func NewPasscodeAdapter(db *gocql.ClusterConfig, tableName string, options ...st
 	return NewPasscodeRepository(db, tableName, options...)
 }
 func NewPasscodeRepository(db *gocql.ClusterConfig, tableName string, options ...string) *PasscodeRepository {
+	return NewPasscodeRepositoryWithProvider(c.GetSessionProvider(db), tableName, options...)
+}
+func NewPasscodeRepositoryWithProvider(db c.SessionProvider, tableName string, options ...string) *PasscodeRepository {
 	var idName, passcodeName, expiredAtName string
 	if len(options) >= 1 && len(options[0]) > 0 {
 		expiredAtName = options[0]
@@ -51,12 +55,19 @@ func (p *PasscodeRepository) Save(ctx context.Context, id string, passcode strin
 	queryString := fmt.Sprintf("INSERT INTO %s (%s) VALUES (? ,? ,?)",
 		p.tableName,
 		strings.Join(columns, ","))
-	session, er0 := p.db.CreateSession()
+	args := []interface{}{id, passcode, expiredAt}
+	ttl := int(time.Until(expiredAt) / time.Second)
+	if ttl > 0 {
+		queryString = queryString + " USING TTL ?"
+		args = append(args, ttl)
+	}
+	session, er0 := p.db.Session()
 	if er0 != nil {
 		return 0, er0
 	}
-	defer session.Close()
-	err := session.Query(queryString, id, passcode, expiredAt).Exec()
+	q, cancel := c.ApplyOptions(ctx, session.Query(queryString, args...))
+	defer cancel()
+	err := q.Exec()
 	if err != nil {
 		return 0, err
 	}
@@ -64,16 +75,17 @@ func (p *PasscodeRepository) Save(ctx context.Context, id string, passcode strin
 }
 
 func (p *PasscodeRepository) Load(ctx context.Context, id string) (string, time.Time, error) {
-	session, er0 := p.db.CreateSession()
+	session, er0 := p.db.Session()
 	if er0 != nil {
 		return "", time.Now().Add(-24 * time.Hour), er0
 	}
-	defer session.Close()
 	// var returnId strng
 	var code string
 	var expiredAt time.Time
 	strSql := fmt.Sprintf(`SELECT %s, %s FROM `, p.passcodeName, p.expiredAtName) + p.tableName + ` WHERE ` + p.idName + ` =? ALLOW FILTERING`
-	er1 := session.Query(strSql, id).Scan(&code, &expiredAt)
+	q, cancel := c.ApplyOptions(ctx, session.Query(strSql, id))
+	defer cancel()
+	er1 := q.Scan(&code, &expiredAt)
 	if er1 != nil {
 		return "", time.Now().Add(-24 * time.Hour), er1
 	}
@@ -81,13 +93,14 @@ func (p *PasscodeRepository) Load(ctx context.Context, id string) (string, time.
 }
 
 func (p *PasscodeRepository) Delete(ctx context.Context, id string) (int64, error) {
-	session, er0 := p.db.CreateSession()
+	session, er0 := p.db.Session()
 	if er0 != nil {
 		return 0, er0
 	}
-	defer session.Close()
 	query := "delete from " + p.tableName + " where " + p.idName + " = ?"
-	er1 := session.Query(query, id).Exec()
+	q, cancel := c.ApplyOptions(ctx, session.Query(query, id))
+	defer cancel()
+	er1 := q.Exec()
 	if er1 != nil {
 		return 0, er1
 	}
@@ -0,0 +1,227 @@
+package cassandra
+
+import (
+	"container/list"
+	"encoding/binary"
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/apache/cassandra-gocql-driver"
+)
+
+// DefaultStatementCache tracks the statements executed with WithPrepared if no cache is set by WithStatementCache.
+// Its size is the default of ClusterConfig.MaxPreparedStmts, so that its hit rate is the one of the prepared statements of the driver.
+var DefaultStatementCache = NewStatementCache(1000)
+
+func getStatementCache(o *QueryOptions) *StatementCache {
+	if o == nil || o.Prepared == nil || !*o.Prepared {
+		return nil
+	}
+	if o.Statements != nil {
+		return o.Statements
+	}
+	return DefaultStatementCache
+}
+
+type StatementStats struct {
+	Hits      int64
+	Misses    int64
+	Evictions int64
+	Size      int
+}
+
+// HitRate is the ratio of the executions of the statements which were already prepared.
+func (s StatementStats) HitRate() float64 {
+	total := s.Hits + s.Misses
+	if total == 0 {
+		return 0
+	}
+	return float64(s.Hits) / float64(total)
+}
+
+// StatementCache is a bounded LRU of the statement texts executed in prepared mode.
+// The driver prepares each distinct text once and keeps it in its own LRU, so a miss is a statement which the driver prepares.
+type StatementCache struct {
+	mu        sync.Mutex
+	capacity  int
+	items     map[string]*list.Element
+	lru       *list.List
+	hits      int64
+	misses    int64
+	evictions int64
+}
+
+func NewStatementCache(capacity int) *StatementCache {
+	if capacity < 1 {
+		capacity = 1
+	}
+	return &StatementCache{capacity: capacity, items: make(map[string]*list.Element), lru: list.New()}
+}
+
+// Touch records the execution of the statement, it returns true if the statement is in the cache.
+func (c *StatementCache) Touch(stmt string) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if e, ok := c.items[stmt]; ok {
+		c.lru.MoveToFront(e)
+		c.hits++
+		return true
+	}
+	c.misses++
+	c.items[stmt] = c.lru.PushFront(stmt)
+	if c.lru.Len() > c.capacity {
+		last := c.lru.Back()
+		c.lru.Remove(last)
+		delete(c.items, last.Value.(string))
+		c.evictions++
+	}
+	return false
+}
+func (c *StatementCache) Contains(stmt string) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	_, ok := c.items[stmt]
+	return ok
+}
+func (c *StatementCache) Stats() StatementStats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return StatementStats{Hits: c.hits, Misses: c.misses, Evictions: c.evictions, Size: c.lru.Len()}
+}
+
+// HitRate is the hit rate of the stats.
+func (c *StatementCache) HitRate() float64 {
+	return c.Stats().HitRate()
+}
+func (c *StatementCache) Reset() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.items = make(map[string]*list.Element)
+	c.lru.Init()
+	c.hits = 0
+	c.misses = 0
+	c.evictions = 0
+}
+
+// GetParamValue returns the literal of the value as GetDBValue. If prepared, nothing is inlined, so that the statement of a table
+// and a set of columns is always the same text, and the driver reuses its prepared statement.
+func GetParamValue(v interface{}, scale int8, prepared bool) (string, bool) {
+	if prepared {
+		return "", false
+	}
+	return GetDBValue(v, scale)
+}
+
+// BindValue returns the value to bind as a parameter: the numbers which GetDBValue inlines, such as the big numbers and the decimals with scale,
+// are bound as the Numeric of their literal, so that they are rounded the same way as when they are inlined.
+func BindValue(v interface{}, scale int8) interface{} {
+	switch v.(type) {
+	case nil, string, bool, int, int64, int32:
+		return v
+	}
+	s, ok := GetDBValue(v, scale)
+	if !ok {
+		return v
+	}
+	if s == "null" {
+		return nil
+	}
+	return Numeric(s)
+}
+
+// Numeric is a number bound by its literal, it is marshaled by the type of the column.
+type Numeric string
+
+func (n Numeric) MarshalCQL(info gocql.TypeInfo) ([]byte, error) {
+	s := string(n)
+	switch info.Type() {
+	case gocql.TypeDecimal:
+		unscaled, scale, ok := parseDecimal(s)
+		if !ok {
+			return nil, fmt.Errorf("cannot bind %s as a decimal", s)
+		}
+		b := make([]byte, 4)
+		binary.BigEndian.PutUint32(b, uint32(scale))
+		return append(b, encodeVarint(unscaled)...), nil
+	case gocql.TypeVarint:
+		i, ok := new(big.Int).SetString(s, 10)
+		if !ok {
+			return nil, fmt.Errorf("cannot bind %s as a varint", s)
+		}
+		return encodeVarint(i), nil
+	case gocql.TypeFloat:
+		f, err := strconv.ParseFloat(s, 32)
+		if err != nil {
+			return nil, err
+		}
+		return gocql.Marshal(info, float32(f))
+	case gocql.TypeDouble:
+		f, err := strconv.ParseFloat(s, 64)
+		if err != nil {
+			return nil, err
+		}
+		return gocql.Marshal(info, f)
+	case gocql.TypeBigInt, gocql.TypeCounter, gocql.TypeInt, gocql.TypeSmallInt, gocql.TypeTinyInt:
+		i, err := strconv.ParseInt(s, 10, 64)
+		if err != nil {
+			return nil, err
+		}
+		return gocql.Marshal(info, i)
+	}
+	return gocql.Marshal(info, s)
+}
+
+// parseDecimal parses a literal such as "-12.340" or "1.5e+06" to its unscaled value and scale.
+func parseDecimal(s string) (*big.Int, int32, bool) {
+	exp := 0
+	if i := strings.IndexAny(s, "eE"); i >= 0 {
+		e, err := strconv.Atoi(s[i+1:])
+		if err != nil {
+			return nil, 0, false
+		}
+		exp = e
+		s = s[:i]
+	}
+	scale := 0
+	if i := strings.IndexByte(s, '.'); i >= 0 {
+		scale = len(s) - i - 1
+		s = s[:i] + s[i+1:]
+	}
+	unscaled, ok := new(big.Int).SetString(s, 10)
+	if !ok {
+		return nil, 0, false
+	}
+	scale = scale - exp
+	if scale < 0 {
+		unscaled.Mul(unscaled, new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(-scale)), nil))
+		scale = 0
+	}
+	return unscaled, int32(scale), true
+}
+
+// encodeVarint encodes the number in the big-endian two's complement of the varint type.
+func encodeVarint(n *big.Int) []byte {
+	switch n.Sign() {
+	case 0:
+		return []byte{0}
+	case 1:
+		b := n.Bytes()
+		if b[0]&0x80 != 0 {
+			b = append([]byte{0}, b...)
+		}
+		return b
+	}
+	m := new(big.Int).Neg(n)
+	m.Sub(m, big.NewInt(1))
+	b := m.Bytes()
+	for i := range b {
+		b[i] = ^b[i]
+	}
+	if len(b) == 0 || b[0]&0x80 == 0 {
+		b = append([]byte{0xff}, b...)
+	}
+	return b
+}
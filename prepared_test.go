@@ -0,0 +1,235 @@
+package cassandra_test
+
+import (
+	"bytes"
+	"context"
+	"math/big"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/apache/cassandra-gocql-driver"
+	c "github.com/core-go/cassandra"
+	"github.com/core-go/cassandra/cqltest"
+)
+
+type typeInfo gocql.Type
+
+func (t typeInfo) Type() gocql.Type { return gocql.Type(t) }
+func (t typeInfo) Version() byte    { return 4 }
+func (t typeInfo) Custom() string   { return "" }
+func (t typeInfo) New() interface{} { return nil }
+
+type wallet struct {
+	Id      string  `json:"id" cql:"id,partition_key"`
+	Name    string  `json:"name" cql:"name"`
+	Active  bool    `json:"active" cql:"active"`
+	Points  int64   `json:"points" cql:"points"`
+	Balance float64 `json:"balance" cql:"balance,scale=2"`
+	Rank    big.Int `json:"rank" cql:"rank"`
+	Version int     `json:"version" cql:"version"`
+	Note    *string `json:"note" cql:"note"`
+}
+
+func TestPreparedMode(t *testing.T) {
+	a1 := wallet{Id: "1", Name: "", Active: true, Points: 10, Balance: 1.235, Rank: *big.NewInt(7), Version: 1}
+	a2 := wallet{Id: "2", Name: "Peter", Active: false, Points: 20, Balance: 2.5, Rank: *big.NewInt(8), Version: 2}
+	tests := []struct {
+		name  string
+		write func(w *c.Writer, a wallet) (int64, error)
+	}{
+		{"insert", func(w *c.Writer, a wallet) (int64, error) { return w.Insert(context.Background(), &a) }},
+		{"update", func(w *c.Writer, a wallet) (int64, error) { return w.Update(context.Background(), &a) }},
+		{"save", func(w *c.Writer, a wallet) (int64, error) { return w.Save(context.Background(), &a) }},
+		{"patch", func(w *c.Writer, a wallet) (int64, error) {
+			return w.Patch(context.Background(), map[string]interface{}{"id": a.Id, "name": a.Name, "active": a.Active, "points": a.Points, "version": a.Version})
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ses := cqltest.NewSession()
+			writer, err := c.NewWriterWithVersionAndProvider(c.NewOptionsProvider(ses.Provider(), c.WithPrepared(true)), "accounts", reflect.TypeOf(wallet{}), "Version")
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, a := range []wallet{a1, a2} {
+				if _, err := tt.write(writer, a); err != nil {
+					t.Fatal(err)
+				}
+			}
+			executed := ses.Executed()
+			if len(executed) != 2 || executed[0].Query != executed[1].Query {
+				t.Fatalf("statements = %v, want the same text for both", executed)
+			}
+			for _, literal := range []string{"''", "true", "false", "null", "=1", "=2", "10", "20"} {
+				if strings.Contains(executed[0].Query, literal) {
+					t.Fatalf("statement %q inlines %s", executed[0].Query, literal)
+				}
+			}
+			if n := strings.Count(executed[0].Query, "?"); n != len(executed[0].Params) {
+				t.Fatalf("statement %q has %d parameters, but %d values are bound", executed[0].Query, n, len(executed[0].Params))
+			}
+		})
+	}
+}
+
+func TestPreparedModePerWriter(t *testing.T) {
+	ses := cqltest.NewSession()
+	prepared, err := c.NewWriterWithVersionAndProvider(c.NewOptionsProvider(ses.Provider(), c.WithPrepared(true)), "accounts", reflect.TypeOf(wallet{}), "Version")
+	if err != nil {
+		t.Fatal(err)
+	}
+	inlined, err := c.NewWriterWithVersionAndProvider(ses.Provider(), "accounts", reflect.TypeOf(wallet{}), "Version")
+	if err != nil {
+		t.Fatal(err)
+	}
+	a := wallet{Id: "1", Active: true, Balance: 1.235, Version: 1}
+	if _, err := prepared.Update(context.Background(), &a); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := inlined.Update(context.Background(), &a); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := inlined.Update(c.WithQueryOptions(context.Background(), c.WithPrepared(true)), &a); err != nil {
+		t.Fatal(err)
+	}
+	executed := ses.Executed()
+	if !strings.Contains(executed[1].Query, "active=true") || !strings.Contains(executed[1].Query, "balance=1.24") {
+		t.Fatalf("statement %q of the default writer does not inline the values", executed[1].Query)
+	}
+	if executed[0].Query != executed[2].Query {
+		t.Fatalf("statement %q of the context option is not %q", executed[2].Query, executed[0].Query)
+	}
+}
+
+func TestBindValue(t *testing.T) {
+	tests := []struct {
+		name  string
+		value interface{}
+		scale int8
+		want  interface{}
+	}{
+		{"string", "", -1, ""},
+		{"bool", true, -1, true},
+		{"int", 1, -1, 1},
+		{"float without scale", 1.5, -1, 1.5},
+		{"float with scale", 1.235, 2, c.Numeric("1.24")},
+		{"big int", *big.NewInt(12), -1, c.Numeric("12")},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := c.BindValue(tt.value, tt.scale); !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("BindValue(%v, %d) = %#v, want %#v", tt.value, tt.scale, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNumericMarshalCQL(t *testing.T) {
+	tests := []struct {
+		name  string
+		value c.Numeric
+		typ   gocql.Type
+		want  []byte
+	}{
+		{"decimal", "12.34", gocql.TypeDecimal, []byte{0, 0, 0, 2, 0x04, 0xd2}},
+		{"negative decimal", "-1.28", gocql.TypeDecimal, []byte{0, 0, 0, 2, 0x80}},
+		{"decimal with exponent", "1.5e+3", gocql.TypeDecimal, []byte{0, 0, 0, 0, 0x05, 0xdc}},
+		{"zero", "0", gocql.TypeDecimal, []byte{0, 0, 0, 0, 0}},
+		{"varint", "128", gocql.TypeVarint, []byte{0, 0x80}},
+		{"negative varint", "-129", gocql.TypeVarint, []byte{0xff, 0x7f}},
+		{"minus one", "-1", gocql.TypeVarint, []byte{0xff}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.value.MarshalCQL(typeInfo(tt.typ))
+			if err != nil || !bytes.Equal(got, tt.want) {
+				t.Fatalf("MarshalCQL(%s) = %v, %v, want %v", tt.value, got, err, tt.want)
+			}
+		})
+	}
+	if _, err := c.Numeric("1.5").MarshalCQL(typeInfo(gocql.TypeVarint)); err == nil {
+		t.Fatal("MarshalCQL(1.5) as a varint must fail")
+	}
+}
+
+func TestStatementCache(t *testing.T) {
+	tests := []struct {
+		name      string
+		capacity  int
+		stmts     []string
+		hits      int64
+		misses    int64
+		evictions int64
+		contains  []string
+		evicted   []string
+	}{
+		{"hits and misses", 2, []string{"a", "b", "a", "a"}, 2, 2, 0, []string{"a", "b"}, nil},
+		{"least recently used is evicted", 2, []string{"a", "b", "a", "c"}, 1, 3, 1, []string{"a", "c"}, []string{"b"}},
+		{"evicted statement misses again", 1, []string{"a", "b", "a"}, 0, 3, 2, []string{"a"}, []string{"b"}},
+		{"capacity of at least 1", 0, []string{"a", "a"}, 1, 1, 0, []string{"a"}, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cache := c.NewStatementCache(tt.capacity)
+			for _, stmt := range tt.stmts {
+				cache.Touch(stmt)
+			}
+			stats := cache.Stats()
+			if stats.Hits != tt.hits || stats.Misses != tt.misses || stats.Evictions != tt.evictions || stats.Size != len(tt.contains) {
+				t.Fatalf("Stats() = %+v, want %d hits, %d misses, %d evictions, size %d", stats, tt.hits, tt.misses, tt.evictions, len(tt.contains))
+			}
+			if want := float64(tt.hits) / float64(tt.hits+tt.misses); cache.HitRate() != want {
+				t.Fatalf("HitRate() = %v, want %v", cache.HitRate(), want)
+			}
+			for _, stmt := range tt.contains {
+				if !cache.Contains(stmt) {
+					t.Fatalf("Contains(%s) = false", stmt)
+				}
+			}
+			for _, stmt := range tt.evicted {
+				if cache.Contains(stmt) {
+					t.Fatalf("Contains(%s) = true, want evicted", stmt)
+				}
+			}
+			cache.Reset()
+			if stats := cache.Stats(); stats != (c.StatementStats{}) || stats.HitRate() != 0 {
+				t.Fatalf("Stats() after Reset() = %+v", stats)
+			}
+		})
+	}
+}
+
+func TestStatementCacheOfPreparedWriter(t *testing.T) {
+	cache := c.NewStatementCache(10)
+	ses := cqltest.NewSession()
+	prepared, err := c.NewWriterWithProvider(c.NewOptionsProvider(ses.Provider(), c.WithPrepared(true), c.WithStatementCache(cache)), "accounts", reflect.TypeOf(wallet{}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	inlined, err := c.NewWriterWithProvider(c.NewOptionsProvider(ses.Provider(), c.WithStatementCache(cache)), "accounts", reflect.TypeOf(wallet{}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, a := range []wallet{{Id: "1", Points: 1}, {Id: "2", Points: 2}, {Id: "3", Points: 3}} {
+		if _, err := prepared.Update(context.Background(), &a); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := inlined.Update(context.Background(), &a); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := prepared.Insert(context.Background(), &wallet{Id: "4"}); err != nil {
+		t.Fatal(err)
+	}
+	stats := cache.Stats()
+	if stats.Hits != 2 || stats.Misses != 2 || stats.Size != 2 {
+		t.Fatalf("Stats() = %+v, want the update prepared once and reused twice, and the insert prepared once", stats)
+	}
+	if !cache.Contains(ses.Executed()[0].Query) || cache.Contains(ses.Executed()[1].Query) {
+		t.Fatal("the cache tracks the statements which are not prepared")
+	}
+	if cache.HitRate() != 0.5 {
+		t.Fatalf("HitRate() = %v, want 0.5", cache.HitRate())
+	}
+}
@@ -1,25 +1,26 @@
 package cassandra
 
 import (
-	"encoding/hex"
+	"context"
 	"reflect"
 	"strings"
 
 	"github.com/apache/cassandra-gocql-driver"
 )
 
-func QueryMap(ses *gocql.Session, transform func(s string) string, sql string, values ...interface{}) ([]map[string]interface{}, error) {
-	q := ses.Query(sql, values...)
+func QueryMap(ses Session, transform func(s string) string, sql string, values ...interface{}) ([]map[string]interface{}, error) {
+	return QueryMapContext(context.Background(), ses, transform, sql, values...)
+}
+func QueryMapContext(ctx context.Context, ses Session, transform func(s string) string, sql string, values ...interface{}) ([]map[string]interface{}, error) {
+	q, cancel := ApplyOptions(ctx, ses.Query(sql, values...))
+	defer cancel()
 	list := make([]map[string]interface{}, 0)
-	if q.Exec() != nil {
-		return list, q.Exec()
-	}
 	iter := q.Iter()
 	if transform == nil {
 		for {
 			row := make(map[string]interface{})
 			if !iter.MapScan(row) {
-				return list, nil
+				return list, iter.Close()
 			} else {
 				list = append(list, row)
 			}
@@ -27,6 +28,7 @@ func QueryMap(ses *gocql.Session, transform func(s string) string, sql string, v
 	} else {
 		rowData, err := iter.RowData()
 		if err != nil {
+			iter.Close()
 			return list, err
 		}
 		var cols []string
@@ -37,14 +39,14 @@ func QueryMap(ses *gocql.Session, transform func(s string) string, sql string, v
 			row := make(map[string]interface{})
 			boolScan := ScanMap(row, iter, rowData, cols)
 			if !boolScan {
-				return list, nil
+				return list, iter.Close()
 			} else {
 				list = append(list, row)
 			}
 		}
 	}
 }
-func ScanMap(m map[string]interface{}, iter *gocql.Iter, rowData gocql.RowData, newCols []string) bool {
+func ScanMap(m map[string]interface{}, iter Iter, rowData gocql.RowData, newCols []string) bool {
 	for i, col := range rowData.Columns {
 		if dest, ok := m[col]; ok {
 			rowData.Values[i] = dest
@@ -65,28 +67,55 @@ func ScanMap(m map[string]interface{}, iter *gocql.Iter, rowData gocql.RowData,
 	}
 	return false
 }
-func Query(ses *gocql.Session, fieldsIndex map[string]int, results interface{}, sql string, values ...interface{}) error {
-	q := ses.Query(sql, values...)
-	if q.Exec() != nil {
-		return q.Exec()
+func Query(ses Session, fieldsIndex map[string]int, results interface{}, sql string, values ...interface{}) error {
+	return QueryContext(context.Background(), ses, fieldsIndex, results, sql, values...)
+}
+func QueryContext(ctx context.Context, ses Session, fieldsIndex map[string]int, results interface{}, sql string, values ...interface{}) error {
+	q, cancel := ApplyOptions(ctx, ses.Query(sql, values...))
+	defer cancel()
+	iter := q.Iter()
+	err := ScanIter(iter, results, fieldsIndex)
+	if err != nil {
+		iter.Close()
+		return err
 	}
-	return ScanIter(q.Iter(), results, fieldsIndex)
+	return iter.Close()
+}
+func QueryWithPage(ses Session, fieldsIndex map[string]int, results interface{}, max int64, refId string, sql string, values ...interface{}) (string, error) {
+	return QueryWithPageContext(context.Background(), ses, fieldsIndex, results, max, refId, sql, values...)
 }
-func QueryWithPage(ses *gocql.Session, fieldsIndex map[string]int, results interface{}, max int64, refId string, sql string, values ...interface{}) (string, error) {
-	next, er0 := hex.DecodeString(refId)
+
+// QueryWithPageContext scans the page of the token refId, and returns the token of the next page.
+// The tokens are signed by the codec of GetPageTokenCodec, they are unsigned if no key is configured.
+func QueryWithPageContext(ctx context.Context, ses Session, fieldsIndex map[string]int, results interface{}, max int64, refId string, sql string, values ...interface{}) (string, error) {
+	codec := getPageTokenCodec()
+	next, er0 := codec.Decode(refId, sql, values)
 	if er0 != nil {
 		return "", er0
 	}
-	query := ses.Query(sql, values...).PageState(next).PageSize(int(max))
-	if query.Exec() != nil {
-		return "", query.Exec()
-	}
-	err := ScanIter(query.Iter(), results, fieldsIndex)
+	pageState, err := queryPage(ctx, ses, fieldsIndex, results, max, next, sql, values...)
 	if err != nil {
 		return "", err
 	}
-	nextPageToken := hex.EncodeToString(query.Iter().PageState())
-	return nextPageToken, nil
+	return codec.Encode(pageState, sql, values)
+}
+
+// queryPage scans one page of max rows from the page state, and returns the page state of the next page.
+func queryPage(ctx context.Context, ses Session, fieldsIndex map[string]int, results interface{}, max int64, pageState []byte, sql string, values ...interface{}) ([]byte, error) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	ctx = WithQueryOptions(ctx, WithPageSize(int(max)))
+	query, cancel := ApplyOptions(ctx, ses.Query(sql, values...).PageState(pageState).PageSize(int(max)))
+	defer cancel()
+	iter := query.Iter()
+	err := ScanIter(iter, results, fieldsIndex)
+	if err != nil {
+		iter.Close()
+		return nil, err
+	}
+	next := iter.PageState()
+	return next, iter.Close()
 }
 func ToCamelCase(s string) string {
 	s2 := strings.ToLower(s)
@@ -13,7 +13,7 @@ import (
 )
 
 type Loader[T any, K any] struct {
-	DB            *gocql.ClusterConfig
+	DB            q.SessionProvider
 	Table         string
 	Map           map[string]int
 	JsonColumnMap map[string]string
@@ -21,9 +21,14 @@ type Loader[T any, K any] struct {
 	Keys          []string
 	IdMap         bool
 	field1        string
+	Options       *q.QueryOptions
+	Schema        *q.Schema
 }
 
 func NewLoader[T any, K any](db *gocql.ClusterConfig, tableName string) (*Loader[T, K], error) {
+	return NewLoaderWithProvider[T, K](q.GetSessionProvider(db), tableName)
+}
+func NewLoaderWithProvider[T any, K any](db q.SessionProvider, tableName string) (*Loader[T, K], error) {
 	var t T
 	modelType := reflect.TypeOf(t)
 	if modelType.Kind() != reflect.Struct {
@@ -52,19 +57,60 @@ func NewLoader[T any, K any](db *gocql.ClusterConfig, tableName string) (*Loader
 	if err != nil {
 		return nil, err
 	}
-	return &Loader[T, K]{db, tableName, fieldsIndex, jsonColumnKeys, strings.Join(fields, ","), primaryKeys, idMap, field1}, nil
+	if err = q.CheckSchema(db, tableName, modelType); err != nil {
+		return nil, err
+	}
+	return &Loader[T, K]{db, tableName, fieldsIndex, jsonColumnKeys, strings.Join(fields, ","), primaryKeys, idMap, field1, q.GetDefaultOptions(db), q.GetSchema(modelType)}, nil
 }
+
 func (a *Loader[T, K]) All(ctx context.Context) ([]T, error) {
 	var objs []T
 	query := fmt.Sprintf("select %s from %s", a.Fields, a.Table)
-	ses, err := a.DB.CreateSession()
+	ctx = q.WithDefaultOptions(ctx, a.Options)
+	ses, err := a.DB.Session()
 	if err != nil {
 		return objs, err
 	}
-	defer ses.Close()
-	err = q.Query(ses, a.Map, &objs, query)
+	err = q.QueryContext(ctx, ses, a.Map, &objs, query)
 	return objs, err
 }
+
+// ScanAll loads all rows of the table by token ranges in parallel, in token order, see q.TokenScanner. Use it instead of All for the big tables.
+func (a *Loader[T, K]) ScanAll(ctx context.Context) ([]T, error) {
+	var t T
+	scanner, err := q.NewTokenScannerWithProvider(a.DB, a.Table, reflect.TypeOf(t))
+	if err != nil {
+		return nil, err
+	}
+	scanner.Options = a.Options
+	result, err := scanner.ScanAll(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return *result.(*[]T), nil
+}
+
+// Each calls fn for each row of the table, the rows are scanned one by one with the driver paging.
+func (a *Loader[T, K]) Each(ctx context.Context, fn func(*T) error) error {
+	query := fmt.Sprintf("select %s from %s", a.Fields, a.Table)
+	ctx = q.WithDefaultOptions(ctx, a.Options)
+	ses, err := a.DB.Session()
+	if err != nil {
+		return err
+	}
+	return q.QueryEach[T](ctx, ses, a.Map, fn, query)
+}
+
+// Iterate returns the iterator of the rows of the table, which must be closed.
+func (a *Loader[T, K]) Iterate(ctx context.Context) (*q.Iterator[T], error) {
+	query := fmt.Sprintf("select %s from %s", a.Fields, a.Table)
+	ctx = q.WithDefaultOptions(ctx, a.Options)
+	ses, err := a.DB.Session()
+	if err != nil {
+		return nil, err
+	}
+	return q.NewIterator[T](ctx, ses, a.Map, query), nil
+}
 func toMap(obj interface{}) (map[string]interface{}, error) {
 	b, err := json.Marshal(obj)
 	if err != nil {
@@ -90,17 +136,56 @@ func (a *Loader[T, K]) Load(ctx context.Context, id K) (*T, error) {
 	var objs []T
 	queryAll := fmt.Sprintf("select %s from %s ", a.Fields, a.Table)
 	query, args := q.BuildFindById(queryAll, ip, a.JsonColumnMap, a.Keys)
-	ses, err := a.DB.CreateSession()
+	ctx = q.WithDefaultOptions(ctx, a.Options)
+	ses, err := a.DB.Session()
 	if err != nil {
 		return nil, err
 	}
-	defer ses.Close()
-	err = q.Query(ses, a.Map, &objs, query, args...)
+	err = q.QueryContext(ctx, ses, a.Map, &objs, query, args...)
 	if len(objs) > 0 {
 		return &objs[0], nil
 	}
 	return nil, nil
 }
+func (a *Loader[T, K]) LoadPartition(ctx context.Context, partition interface{}) ([]T, error) {
+	return a.LoadRange(ctx, partition)
+}
+func (a *Loader[T, K]) LoadRange(ctx context.Context, partition interface{}, conditions ...q.Condition) ([]T, error) {
+	var objs []T
+	queryAll := fmt.Sprintf("select %s from %s", a.Fields, a.Table)
+	query, args, err := q.BuildFindByPartition(queryAll, partition, a.Schema, conditions...)
+	if err != nil {
+		return objs, err
+	}
+	ctx = q.WithDefaultOptions(ctx, a.Options)
+	ses, err := a.DB.Session()
+	if err != nil {
+		return objs, err
+	}
+	err = q.QueryContext(ctx, ses, a.Map, &objs, query, args...)
+	return objs, err
+}
+
+// LoadMany loads the models of the ids, the result is aligned with ids: it has len(ids) models, nil for the missing ids.
+// The ids are grouped by partition and loaded by concurrent queries.
+// The result is not a map by id, because K can be a map of the composite keys, which is not comparable;
+// use q.MapByIds to map it by a comparable K, each id is in the map, nil for the missing ids.
+func (a *Loader[T, K]) LoadMany(ctx context.Context, ids []K) ([]*T, error) {
+	keys := make([]interface{}, 0, len(ids))
+	for _, id := range ids {
+		ip, err := a.getId(id)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, ip)
+	}
+	ctx = q.WithDefaultOptions(ctx, a.Options)
+	ses, err := a.DB.Session()
+	if err != nil {
+		return nil, err
+	}
+	return q.LoadMany[T](ctx, ses, a.Table, a.Map, keys)
+}
 func (a *Loader[T, K]) Exist(ctx context.Context, id K) (bool, error) {
 	ip, er0 := a.getId(id)
 	if er0 != nil {
@@ -108,12 +193,12 @@ func (a *Loader[T, K]) Exist(ctx context.Context, id K) (bool, error) {
 	}
 	query := fmt.Sprintf("select %s from %s ", a.field1, a.Table)
 	query1, args := q.BuildFindById(query, ip, a.JsonColumnMap, a.Keys)
-	ses, err := a.DB.CreateSession()
+	ctx = q.WithDefaultOptions(ctx, a.Options)
+	ses, err := a.DB.Session()
 	if err != nil {
 		return false, err
 	}
-	defer ses.Close()
-	res, err := q.QueryMap(ses, nil, query1, args...)
+	res, err := q.QueryMapContext(ctx, ses, nil, query1, args...)
 	if err != nil {
 		return false, err
 	}
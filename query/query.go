@@ -15,7 +15,10 @@ type Query[T any, K any, F any] struct {
 }
 
 func NewQuery[T any, K any, F any](db *gocql.ClusterConfig, table string, buildQuery func(F) (string, []interface{}), opts ...func(*T)) (*Query[T, K, F], error) {
-	loader, err := NewLoader[T, K](db, table)
+	return NewQueryWithProvider[T, K, F](q.GetSessionProvider(db), table, buildQuery, opts...)
+}
+func NewQueryWithProvider[T any, K any, F any](db q.SessionProvider, table string, buildQuery func(F) (string, []interface{}), opts ...func(*T)) (*Query[T, K, F], error) {
+	loader, err := NewLoaderWithProvider[T, K](db, table)
 	if err != nil {
 		return nil, err
 	}
@@ -39,13 +42,12 @@ func NewQuery[T any, K any, F any](db *gocql.ClusterConfig, table string, buildQ
 func (b *Query[T, K, F]) Search(ctx context.Context, filter F, limit int64, next string) ([]T, string, error) {
 	var objs []T
 	sql, params := b.BuildQuery(filter)
-	ses, err := b.DB.CreateSession()
-	defer ses.Close()
-
+	ctx = q.WithDefaultOptions(ctx, b.Options)
+	ses, err := b.DB.Session()
 	if err != nil {
 		return objs, "", err
 	}
-	nextPageToken, er2 := q.QueryWithMap(ses, b.Map, &objs, sql, params, limit, next)
+	nextPageToken, er2 := q.QueryWithToken(ctx, ses, b.Map, &objs, sql, params, limit, next)
 	if b.Mp != nil {
 		l := len(objs)
 		for i := 0; i < l; i++ {
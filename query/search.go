@@ -8,14 +8,18 @@ import (
 )
 
 type SearchBuilder[T any, K any, F any] struct {
-	DB         *gocql.ClusterConfig
+	DB         q.SessionProvider
 	Table      string
 	BuildQuery func(F) (string, []interface{})
 	Mp         func(*T)
 	Map        map[string]int
+	Options    *q.QueryOptions
 }
 
 func NewSearchBuilder[T any, K any, F any](db *gocql.ClusterConfig, table string, buildQuery func(F) (string, []interface{}), opts ...func(*T)) (*SearchBuilder[T, K, F], error) {
+	return NewSearchBuilderWithProvider[T, K, F](q.GetSessionProvider(db), table, buildQuery, opts...)
+}
+func NewSearchBuilderWithProvider[T any, K any, F any](db q.SessionProvider, table string, buildQuery func(F) (string, []interface{}), opts ...func(*T)) (*SearchBuilder[T, K, F], error) {
 	var mp func(*T)
 	if len(opts) >= 1 {
 		mp = opts[0]
@@ -29,20 +33,19 @@ func NewSearchBuilder[T any, K any, F any](db *gocql.ClusterConfig, table string
 	if err != nil {
 		return nil, err
 	}
-	builder := &SearchBuilder[T, K, F]{DB: db, Table: table, Map: fieldsIndex, BuildQuery: buildQuery, Mp: mp}
+	builder := &SearchBuilder[T, K, F]{DB: db, Options: q.GetDefaultOptions(db), Table: table, Map: fieldsIndex, BuildQuery: buildQuery, Mp: mp}
 	return builder, nil
 }
 
 func (b *SearchBuilder[T, K, F]) Search(ctx context.Context, filter F, limit int64, next string) ([]T, string, error) {
 	var objs []T
 	sql, params := b.BuildQuery(filter)
-	ses, err := b.DB.CreateSession()
-	defer ses.Close()
-
+	ctx = q.WithDefaultOptions(ctx, b.Options)
+	ses, err := b.DB.Session()
 	if err != nil {
 		return objs, "", err
 	}
-	nextPageToken, er2 := q.QueryWithMap(ses, b.Map, &objs, sql, params, limit, next)
+	nextPageToken, er2 := q.QueryWithToken(ctx, ses, b.Map, &objs, sql, params, limit, next)
 	if b.Mp != nil {
 		l := len(objs)
 		for i := 0; i < l; i++ {
@@ -51,3 +54,19 @@ func (b *SearchBuilder[T, K, F]) Search(ctx context.Context, filter F, limit int
 	}
 	return objs, nextPageToken, er2
 }
+
+// Each calls fn for each row of the filter, without paging by limit; the rows are scanned one by one with the driver paging.
+func (b *SearchBuilder[T, K, F]) Each(ctx context.Context, filter F, fn func(*T) error) error {
+	sql, params := b.BuildQuery(filter)
+	ctx = q.WithDefaultOptions(ctx, b.Options)
+	ses, err := b.DB.Session()
+	if err != nil {
+		return err
+	}
+	return q.QueryEach[T](ctx, ses, b.Map, func(t *T) error {
+		if b.Mp != nil {
+			b.Mp(t)
+		}
+		return fn(t)
+	}, sql, params...)
+}
@@ -19,7 +19,13 @@ func NewSearchRepository[T any, K any, F any](db *gocql.ClusterConfig, table str
 	return NewSearchRepositoryWithVersion[T, K, F](db, table, buildQuery, "", options...)
 }
 func NewSearchRepositoryWithVersion[T any, K any, F any](db *gocql.ClusterConfig, table string, buildQuery func(F) (string, []interface{}), versionField string, opts ...func(*T)) (*SearchRepository[T, K, F], error) {
-	repo, err := NewRepositoryWithVersion[T, K](db, table, versionField)
+	return NewSearchRepositoryWithVersionAndProvider[T, K, F](q.GetSessionProvider(db), table, buildQuery, versionField, opts...)
+}
+func NewSearchRepositoryWithProvider[T any, K any, F any](db q.SessionProvider, table string, buildQuery func(F) (string, []interface{}), options ...func(*T)) (*SearchRepository[T, K, F], error) {
+	return NewSearchRepositoryWithVersionAndProvider[T, K, F](db, table, buildQuery, "", options...)
+}
+func NewSearchRepositoryWithVersionAndProvider[T any, K any, F any](db q.SessionProvider, table string, buildQuery func(F) (string, []interface{}), versionField string, opts ...func(*T)) (*SearchRepository[T, K, F], error) {
+	repo, err := NewRepositoryWithVersionAndProvider[T, K](db, table, versionField)
 	if err != nil {
 		return nil, err
 	}
@@ -43,13 +49,12 @@ func NewSearchRepositoryWithVersion[T any, K any, F any](db *gocql.ClusterConfig
 func (b *SearchRepository[T, K, F]) Search(ctx context.Context, filter F, limit int64, next string) ([]T, string, error) {
 	var objs []T
 	sql, params := b.BuildQuery(filter)
-	ses, err := b.DB.CreateSession()
-	defer ses.Close()
-
+	ctx = q.WithDefaultOptions(ctx, b.Options)
+	ses, err := b.DB.Session()
 	if err != nil {
 		return objs, "", err
 	}
-	nextPageToken, er2 := q.QueryWithMap(ses, b.Map, &objs, sql, params, limit, next)
+	nextPageToken, er2 := q.QueryWithToken(ctx, ses, b.Map, &objs, sql, params, limit, next)
 	if b.Mp != nil {
 		l := len(objs)
 		for i := 0; i < l; i++ {
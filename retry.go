@@ -0,0 +1,156 @@
+package cassandra
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"strings"
+	"time"
+
+	"github.com/apache/cassandra-gocql-driver"
+)
+
+// ErrorClass is the class of an error of the driver, to decide if the statement can be retried.
+type ErrorClass int
+
+const (
+	// PermanentError is not retried: invalid statement, unauthorized, failure of the replicas, or a write which is not safe to replay.
+	PermanentError ErrorClass = iota
+	// UnavailableError means that the statement is not executed: not enough replicas, the coordinator is overloaded or bootstrapping, or no connection.
+	UnavailableError
+	// TimeoutError means that the statement may be applied or not: read and write timeouts, no response, connection closed.
+	TimeoutError
+)
+
+// DefaultRetryPolicy is the retry policy of the statements if it is not set by WithRetryPolicy, nil to keep the retry policy of the cluster config.
+var DefaultRetryPolicy gocql.RetryPolicy
+
+// ClassifyError returns the class of the error. The write timeouts of the counters, the lightweight transactions and the materialized views are permanent,
+// because a replay may apply them twice or break the serial consistency.
+func ClassifyError(err error) ErrorClass {
+	if err == nil || errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return PermanentError
+	}
+	var unavailable *gocql.RequestErrUnavailable
+	var writeTimeout *gocql.RequestErrWriteTimeout
+	var readTimeout *gocql.RequestErrReadTimeout
+	switch {
+	case errors.As(err, &unavailable):
+		return UnavailableError
+	case errors.As(err, &writeTimeout):
+		return classifyWriteType(writeTimeout.WriteType)
+	case errors.As(err, &readTimeout):
+		return TimeoutError
+	case errors.Is(err, gocql.ErrNoConnections):
+		return UnavailableError
+	case errors.Is(err, gocql.ErrTimeoutNoResponse), errors.Is(err, gocql.ErrConnectionClosed):
+		return TimeoutError
+	}
+	var reqErr gocql.RequestError
+	if errors.As(err, &reqErr) {
+		switch reqErr.Code() {
+		case gocql.ErrCodeUnavailable, gocql.ErrCodeOverloaded, gocql.ErrCodeBootstrapping:
+			return UnavailableError
+		case gocql.ErrCodeReadTimeout:
+			return TimeoutError
+		}
+	}
+	return PermanentError
+}
+func classifyWriteType(writeType string) ErrorClass {
+	switch strings.ToUpper(writeType) {
+	case "SIMPLE", "BATCH", "UNLOGGED_BATCH", "BATCH_LOG":
+		return TimeoutError
+	}
+	return PermanentError
+}
+
+// RetryPolicy is a gocql.RetryPolicy which retries the errors classified as unavailable or timeout on the next host,
+// after an exponential backoff with jitter. The driver retries the idempotent statements only.
+// The permanent errors are rethrown at once. The backoff is applied to the statements of WithRetryPolicy and DefaultRetryPolicy only,
+// which get a policy of their own: set on the cluster config, the policy retries without delay.
+//
+//	provider := cassandra.NewOptionsProvider(cassandra.GetSessionProvider(cluster), cassandra.WithRetryPolicy(cassandra.NewRetryPolicy(3, 100*time.Millisecond, 2*time.Second)))
+type RetryPolicy struct {
+	MaxRetries int
+	BaseDelay  time.Duration
+	MaxDelay   time.Duration
+	// Jitter is the random part of the delay, from 0 to 1: 0.5 waits from half of the delay to the full delay.
+	Jitter float64
+	// Classify replaces ClassifyError if it is set.
+	Classify func(err error) ErrorClass
+}
+
+func NewRetryPolicy(maxRetries int, baseDelay time.Duration, maxDelay time.Duration) *RetryPolicy {
+	return &RetryPolicy{MaxRetries: maxRetries, BaseDelay: baseDelay, MaxDelay: maxDelay, Jitter: 0.5}
+}
+
+// Attempt returns false if the retries are exhausted or the context of the statement is done.
+func (p *RetryPolicy) Attempt(q gocql.RetryableQuery) bool {
+	if q.Attempts() > p.MaxRetries {
+		return false
+	}
+	ctx := q.Context()
+	return ctx == nil || ctx.Err() == nil
+}
+func (p *RetryPolicy) GetRetryType(err error) gocql.RetryType {
+	classify := ClassifyError
+	if p.Classify != nil {
+		classify = p.Classify
+	}
+	if classify(err) == PermanentError {
+		return gocql.Rethrow
+	}
+	return gocql.RetryNextHost
+}
+
+// bind returns the policy of a statement, which waits for the delay of the attempt before a retry.
+// The driver calls Attempt, then GetRetryType with the error, so the delay is known only when the error is classified.
+func (p *RetryPolicy) bind() gocql.RetryPolicy {
+	return &statementRetryPolicy{policy: p}
+}
+
+type statementRetryPolicy struct {
+	policy   *RetryPolicy
+	attempts int
+	ctx      context.Context
+}
+
+func (s *statementRetryPolicy) Attempt(q gocql.RetryableQuery) bool {
+	s.attempts = q.Attempts()
+	s.ctx = q.Context()
+	return s.policy.Attempt(q)
+}
+func (s *statementRetryPolicy) GetRetryType(err error) gocql.RetryType {
+	typ := s.policy.GetRetryType(err)
+	if typ == gocql.Rethrow {
+		return typ
+	}
+	ctx := s.ctx
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	timer := time.NewTimer(s.policy.Delay(s.attempts))
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return gocql.Rethrow
+	case <-timer.C:
+		return typ
+	}
+}
+
+// Delay returns the delay before the retry of the attempt, from 1: BaseDelay doubled for each attempt, up to MaxDelay, reduced by the jitter.
+func (p *RetryPolicy) Delay(attempt int) time.Duration {
+	delay := p.BaseDelay
+	for i := 1; i < attempt && (p.MaxDelay <= 0 || delay < p.MaxDelay); i++ {
+		delay = delay * 2
+	}
+	if p.MaxDelay > 0 && delay > p.MaxDelay {
+		delay = p.MaxDelay
+	}
+	if p.Jitter > 0 && delay > 0 {
+		delay = delay - time.Duration(rand.Float64()*p.Jitter*float64(delay))
+	}
+	return delay
+}
@@ -0,0 +1,110 @@
+package cassandra_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/apache/cassandra-gocql-driver"
+	c "github.com/core-go/cassandra"
+	"github.com/core-go/cassandra/cqltest"
+)
+
+func TestClassifyError(t *testing.T) {
+	tests := []struct {
+		name  string
+		err   error
+		class c.ErrorClass
+	}{
+		{"nil", nil, c.PermanentError},
+		{"unavailable", &gocql.RequestErrUnavailable{}, c.UnavailableError},
+		{"wrapped unavailable", fmt.Errorf("insert: %w", &gocql.RequestErrUnavailable{}), c.UnavailableError},
+		{"read timeout", &gocql.RequestErrReadTimeout{}, c.TimeoutError},
+		{"write timeout of a simple write", &gocql.RequestErrWriteTimeout{WriteType: "SIMPLE"}, c.TimeoutError},
+		{"write timeout of a logged batch", &gocql.RequestErrWriteTimeout{WriteType: "BATCH"}, c.TimeoutError},
+		{"write timeout of an unlogged batch", &gocql.RequestErrWriteTimeout{WriteType: "UNLOGGED_BATCH"}, c.TimeoutError},
+		{"write timeout of the batch log", &gocql.RequestErrWriteTimeout{WriteType: "batch_log"}, c.TimeoutError},
+		{"write timeout of a lightweight transaction", &gocql.RequestErrWriteTimeout{WriteType: "CAS"}, c.PermanentError},
+		{"write timeout of a counter", &gocql.RequestErrWriteTimeout{WriteType: "COUNTER"}, c.PermanentError},
+		{"write timeout of a view", &gocql.RequestErrWriteTimeout{WriteType: "VIEW"}, c.PermanentError},
+		{"write timeout of an unknown write", &gocql.RequestErrWriteTimeout{}, c.PermanentError},
+		{"read failure", &gocql.RequestErrReadFailure{}, c.PermanentError},
+		{"no connections", gocql.ErrNoConnections, c.UnavailableError},
+		{"no response", gocql.ErrTimeoutNoResponse, c.TimeoutError},
+		{"connection closed", fmt.Errorf("select: %w", gocql.ErrConnectionClosed), c.TimeoutError},
+		{"canceled", context.Canceled, c.PermanentError},
+		{"deadline exceeded", context.DeadlineExceeded, c.PermanentError},
+		{"other", errors.New("syntax error"), c.PermanentError},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if class := c.ClassifyError(tt.err); class != tt.class {
+				t.Fatalf("ClassifyError(%v) = %v, want %v", tt.err, class, tt.class)
+			}
+		})
+	}
+}
+
+func TestRetryPolicyDelay(t *testing.T) {
+	tests := []struct {
+		name    string
+		policy  c.RetryPolicy
+		attempt int
+		min     time.Duration
+		max     time.Duration
+	}{
+		{"first attempt", c.RetryPolicy{BaseDelay: 100 * time.Millisecond}, 1, 100 * time.Millisecond, 100 * time.Millisecond},
+		{"doubled", c.RetryPolicy{BaseDelay: 100 * time.Millisecond}, 3, 400 * time.Millisecond, 400 * time.Millisecond},
+		{"max delay", c.RetryPolicy{BaseDelay: 100 * time.Millisecond, MaxDelay: time.Second}, 10, time.Second, time.Second},
+		{"jitter", c.RetryPolicy{BaseDelay: 100 * time.Millisecond, Jitter: 0.5}, 2, 100 * time.Millisecond, 200 * time.Millisecond},
+		{"jitter of the max delay", c.RetryPolicy{BaseDelay: 100 * time.Millisecond, MaxDelay: time.Second, Jitter: 0.25}, 10, 750 * time.Millisecond, time.Second},
+		{"full jitter", c.RetryPolicy{BaseDelay: 100 * time.Millisecond, Jitter: 1}, 1, 0, 100 * time.Millisecond},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for i := 0; i < 1000; i++ {
+				if d := tt.policy.Delay(tt.attempt); d < tt.min || d > tt.max {
+					t.Fatalf("Delay(%d) = %v, want from %v to %v", tt.attempt, d, tt.min, tt.max)
+				}
+			}
+		})
+	}
+}
+
+func TestRetryPolicyBackoff(t *testing.T) {
+	tests := []struct {
+		name     string
+		err      error
+		delay    time.Duration
+		timeout  time.Duration
+		executed int
+		min      time.Duration
+		max      time.Duration
+	}{
+		{"permanent error", errors.New("syntax error"), time.Hour, time.Minute, 1, 0, time.Second},
+		{"timeout", gocql.ErrTimeoutNoResponse, 20 * time.Millisecond, time.Minute, 3, 60 * time.Millisecond, time.Minute},
+		{"context done during the backoff", gocql.ErrTimeoutNoResponse, time.Hour, 50 * time.Millisecond, 1, 50 * time.Millisecond, time.Minute},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ses := cqltest.NewSession().Fail("from users", tt.err)
+			policy := &c.RetryPolicy{MaxRetries: 2, BaseDelay: tt.delay}
+			ctx, cancel := context.WithTimeout(context.Background(), tt.timeout)
+			defer cancel()
+			start := time.Now()
+			err := c.ExecContext(c.WithQueryOptions(ctx, c.WithRetryPolicy(policy)), ses, "select * from users where id = ?", "1")
+			elapsed := time.Since(start)
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("ExecContext() error = %v, want %v", err, tt.err)
+			}
+			if n := len(ses.Executed()); n != tt.executed {
+				t.Fatalf("executed %d times, want %d", n, tt.executed)
+			}
+			if elapsed < tt.min || elapsed > tt.max {
+				t.Fatalf("ExecContext() returned after %v, want from %v to %v", elapsed, tt.min, tt.max)
+			}
+		})
+	}
+}
@@ -7,61 +7,49 @@ import (
 	"strings"
 )
 
-func ScanIter(iter *gocql.Iter, results interface{}, options ...map[string]int) error {
+func ScanIter(iter Iter, results interface{}, options ...map[string]int) error {
 	modelType := reflect.TypeOf(results).Elem().Elem()
-
-	tb, er2 := Scan(iter, modelType, options...)
-	if er2 != nil {
-		return er2
-	}
-	for _, element := range tb {
-		appendToArray(results, element)
+	var fieldsIndex map[string]int
+	if len(options) > 0 && options[0] != nil {
+		fieldsIndex = options[0]
+	} else {
+		var err error
+		fieldsIndex, err = GetColumnIndexes(modelType)
+		if err != nil {
+			return err
+		}
 	}
-	return nil
-}
-func appendToArray(arr interface{}, item interface{}) interface{} {
-	arrValue := reflect.ValueOf(arr)
-	elemValue := reflect.Indirect(arrValue)
-
-	itemValue := reflect.ValueOf(item)
-	if itemValue.Kind() == reflect.Ptr {
-		itemValue = reflect.Indirect(itemValue)
+	columns := GetColumns(iter.Columns())
+	arr := reflect.Indirect(reflect.ValueOf(results))
+	for {
+		model := reflect.New(modelType)
+		r := StructScan(model.Interface(), columns, fieldsIndex, -1)
+		if !iter.Scan(r...) {
+			return nil
+		}
+		arr.Set(reflect.Append(arr, model.Elem()))
 	}
-	elemValue.Set(reflect.Append(elemValue, itemValue))
-	return arr
 }
 func GetColumnIndexes(modelType reflect.Type) (map[string]int, error) {
 	ma := make(map[string]int, 0)
 	if modelType.Kind() != reflect.Struct {
 		return ma, errors.New("bad type")
 	}
-	for i := 0; i < modelType.NumField(); i++ {
-		field := modelType.Field(i)
-		ormTag := field.Tag.Get("gorm")
-		column, ok := FindTag(ormTag, "column")
-		column = strings.ToLower(column)
-		if ok {
-			ma[column] = i
-		}
+	for _, f := range GetSchema(modelType).Columns {
+		ma[strings.ToLower(f.Column)] = f.Index
 	}
 	return ma, nil
 }
 func FindTag(tag string, key string) (string, bool) {
-	if has := strings.Contains(tag, key); has {
-		str1 := strings.Split(tag, ";")
-		num := len(str1)
-		for i := 0; i < num; i++ {
-			str2 := strings.Split(str1[i], ":")
-			for j := 0; j < len(str2); j++ {
-				if str2[j] == key {
-					return str2[j+1], true
-				}
-			}
+	for _, item := range SplitTag(tag, ';') {
+		kv := strings.SplitN(item, ":", 2)
+		if len(kv) == 2 && strings.TrimSpace(kv[0]) == key {
+			return strings.TrimSpace(kv[1]), true
 		}
 	}
 	return "", false
 }
-func Scan(iter *gocql.Iter, modelType reflect.Type, options ...map[string]int) (t []interface{}, err error) {
+func Scan(iter Iter, modelType reflect.Type, options ...map[string]int) (t []interface{}, err error) {
 	var fieldsIndex map[string]int
 	if len(options) > 0 && options[0] != nil {
 		fieldsIndex = options[0]
@@ -86,9 +74,10 @@ func StructScan(s interface{}, columns []string, fieldsIndex map[string]int, ind
 	if s != nil {
 		modelType := reflect.TypeOf(s).Elem()
 		maps := reflect.Indirect(reflect.ValueOf(s))
+		nested := GetSchema(modelType).Nested
 		if columns == nil {
 			for i := 0; i < maps.NumField(); i++ {
-				r = append(r, maps.Field(i).Addr().Interface())
+				r = append(r, scanField(maps.Field(i), nested[i]))
 			}
 			return
 		}
@@ -106,6 +95,9 @@ func StructScan(s interface{}, columns []string, fieldsIndex map[string]int, ind
 					continue
 				}
 				valueField = maps.FieldByName(columnsName)
+				if f, ok := modelType.FieldByName(columnsName); ok && len(f.Index) == 1 {
+					index = f.Index[0]
+				}
 			} else {
 				if index, ok = fieldsIndex[columnsName]; !ok {
 					var t interface{}
@@ -114,12 +106,19 @@ func StructScan(s interface{}, columns []string, fieldsIndex map[string]int, ind
 				}
 				valueField = maps.Field(index)
 			}
-			x := valueField.Addr().Interface()
-			r = append(r, x)
+			r = append(r, scanField(valueField, nested[index]))
 		}
 	}
 	return
 }
+
+// scanField returns the destination of the column, the UDT and tuple columns are scanned to the nested structs by FromCQL.
+func scanField(valueField reflect.Value, f *FieldDB) interface{} {
+	if f != nil {
+		return &nestedScanner{dst: valueField, tuple: f.Tuple}
+	}
+	return valueField.Addr().Interface()
+}
 func GetColumns(cols []gocql.ColumnInfo) []string {
 	c2 := make([]string, 0)
 	if cols == nil {
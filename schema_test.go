@@ -0,0 +1,70 @@
+package cassandra_test
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	c "github.com/core-go/cassandra"
+)
+
+type message struct {
+	Tenant  string    `cql:"tenant,partition_key"`
+	Room    string    `cql:"room,partition_key"`
+	Sent    time.Time `cql:"sent,clustering_key=desc"`
+	Id      string    `cql:"id,clustering_key"`
+	Topic   string    `cql:"topic,static"`
+	Content string    `cql:"content"`
+}
+type legacyMessage struct {
+	Room    string `gorm:"column:room;primary_key"`
+	Id      string `gorm:"column:id;primary_key"`
+	Content string `gorm:"column:content"`
+}
+
+func columnsOf(fields []*c.FieldDB) string {
+	columns := make([]string, 0)
+	for _, f := range fields {
+		column := f.Column
+		if len(f.Order) > 0 {
+			column = column + " " + f.Order
+		}
+		columns = append(columns, column)
+	}
+	return strings.Join(columns, ",")
+}
+
+func TestSchemaKeys(t *testing.T) {
+	tests := []struct {
+		name       string
+		model      interface{}
+		keys       string
+		partition  string
+		clustering string
+		set        string
+	}{
+		{"partition and clustering keys", message{}, "tenant,room,sent desc,id asc", "tenant,room", "sent desc,id asc", "set content=?"},
+		{"primary keys", legacyMessage{}, "room,id asc", "room", "id asc", "set content=?"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			schema := c.CreateSchema(reflect.TypeOf(tt.model))
+			if keys := columnsOf(schema.Keys); keys != tt.keys {
+				t.Fatalf("Keys = %s, want %s", keys, tt.keys)
+			}
+			if partition := columnsOf(schema.PartitionKeys); partition != tt.partition {
+				t.Fatalf("PartitionKeys = %s, want %s", partition, tt.partition)
+			}
+			if clustering := columnsOf(schema.ClusteringKeys); clustering != tt.clustering {
+				t.Fatalf("ClusteringKeys = %s, want %s", clustering, tt.clustering)
+			}
+			model := reflect.New(reflect.TypeOf(tt.model))
+			model.Elem().FieldByName("Content").SetString("hello")
+			query, _ := c.BuildToUpdate("messages", model.Interface(), schema)
+			if !strings.Contains(query, " "+tt.set+" where ") {
+				t.Fatalf("BuildToUpdate() = %q, want %q by key", query, tt.set)
+			}
+		})
+	}
+}
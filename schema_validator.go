@@ -0,0 +1,385 @@
+package cassandra
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultSchemaTimeout is the timeout of the validation of CheckSchema, if the options of the provider have no timeout.
+const DefaultSchemaTimeout = 10 * time.Second
+
+type IssueKind string
+
+const (
+	MissingTable  IssueKind = "missing table"
+	MissingColumn IssueKind = "missing column"
+	ExtraColumn   IssueKind = "extra column"
+	TypeMismatch  IssueKind = "type mismatch"
+	KeyMismatch   IssueKind = "key mismatch"
+)
+
+// SchemaIssue is a difference between the schema of the model and the table. Expected is of the model, Actual is of the table.
+type SchemaIssue struct {
+	Table    string
+	Column   string
+	Kind     IssueKind
+	Expected string
+	Actual   string
+}
+
+func (i SchemaIssue) String() string {
+	s := fmt.Sprintf("%s: %s", i.Table, i.Kind)
+	if len(i.Column) > 0 {
+		s += " " + i.Column
+	}
+	if len(i.Expected) > 0 {
+		s += fmt.Sprintf(" (expected %s, actual %s)", i.Expected, i.Actual)
+	} else if len(i.Actual) > 0 {
+		s += fmt.Sprintf(" (%s)", i.Actual)
+	}
+	return s
+}
+
+// IsError checks if the issue breaks the mapping: an extra column of the table is not loaded, but does not break anything.
+func (i SchemaIssue) IsError() bool {
+	return i.Kind != ExtraColumn
+}
+
+type SchemaError struct {
+	Issues []SchemaIssue
+}
+
+func (e *SchemaError) Error() string {
+	items := make([]string, 0, len(e.Issues))
+	for _, i := range e.Issues {
+		items = append(items, i.String())
+	}
+	return "schema mismatch: " + strings.Join(items, "; ")
+}
+
+// TableColumn is a row of system_schema.columns. Kind is partition_key, clustering, regular or static.
+type TableColumn struct {
+	Name            string
+	Kind            string
+	Type            string
+	Position        int
+	ClusteringOrder string
+}
+
+// LoadTableColumns reads the columns of the table from system_schema; the result is nil if the table does not exist.
+func LoadTableColumns(ctx context.Context, ses Session, keyspace string, table string) ([]TableColumn, error) {
+	tables, err := QueryMapContext(ctx, ses, nil, "select table_name from system_schema.tables where keyspace_name = ? and table_name = ?", keyspace, table)
+	if err != nil {
+		return nil, err
+	}
+	if len(tables) == 0 {
+		return nil, nil
+	}
+	q, cancel := ApplyOptions(ctx, ses.Query("select column_name, kind, type, position, clustering_order from system_schema.columns where keyspace_name = ? and table_name = ?", keyspace, table))
+	defer cancel()
+	iter := q.Iter()
+	columns := make([]TableColumn, 0)
+	var c TableColumn
+	for iter.Scan(&c.Name, &c.Kind, &c.Type, &c.Position, &c.ClusteringOrder) {
+		columns = append(columns, c)
+	}
+	return columns, iter.Close()
+}
+
+// ValidateSchema compares the model with the table of the keyspace. If the table name is "keyspace.table", keyspace is ignored.
+func ValidateSchema(ctx context.Context, ses Session, keyspace string, table string, modelType reflect.Type) ([]SchemaIssue, error) {
+	keyspace, name := splitTableName(keyspace, table)
+	columns, err := LoadTableColumns(ctx, ses, keyspace, name)
+	if err != nil {
+		return nil, err
+	}
+	if columns == nil {
+		return []SchemaIssue{{Table: table, Kind: MissingTable}}, nil
+	}
+	return CompareSchema(table, modelType, columns), nil
+}
+
+// CompareSchema compares the schema of the model with the columns of the table: the missing and extra columns, the types, the partition keys,
+// the clustering keys with their order, and the static columns. The types are compatible if gocql can scan one to the other, such as int64 and timestamp.
+func CompareSchema(table string, modelType reflect.Type, columns []TableColumn) []SchemaIssue {
+	m := modelType
+	if m.Kind() == reflect.Ptr {
+		m = m.Elem()
+	}
+	schema := GetSchema(m)
+	issues := make([]SchemaIssue, 0)
+	actual := make(map[string]TableColumn, len(columns))
+	partitionKeys := make([]TableColumn, 0)
+	clusteringKeys := make([]TableColumn, 0)
+	for _, c := range columns {
+		actual[c.Name] = c
+		switch c.Kind {
+		case "partition_key":
+			partitionKeys = append(partitionKeys, c)
+		case "clustering":
+			clusteringKeys = append(clusteringKeys, c)
+		}
+	}
+	sortByPosition(partitionKeys)
+	sortByPosition(clusteringKeys)
+	expected := make(map[string]bool)
+	for _, f := range schema.Columns {
+		if IsMetadataColumn(f.Column) {
+			continue
+		}
+		expected[f.Column] = true
+		c, ok := actual[f.Column]
+		if !ok {
+			issues = append(issues, SchemaIssue{Table: table, Column: f.Column, Kind: MissingColumn})
+			continue
+		}
+		if t, err := getColumnType(f, m.Field(f.Index).Type); err == nil && !IsCompatibleType(t, c.Type) {
+			issues = append(issues, SchemaIssue{Table: table, Column: f.Column, Kind: TypeMismatch, Expected: t, Actual: c.Type})
+		}
+		if f.Static != (c.Kind == "static") {
+			issues = append(issues, SchemaIssue{Table: table, Column: f.Column, Kind: KeyMismatch, Expected: getKind(f), Actual: c.Kind})
+		}
+	}
+	for _, c := range columns {
+		if !expected[c.Name] {
+			issues = append(issues, SchemaIssue{Table: table, Column: c.Name, Kind: ExtraColumn, Actual: c.Type})
+		}
+	}
+	if e, a := joinColumns(schema.PartitionKeys, false), joinTableColumns(partitionKeys, false); e != a {
+		issues = append(issues, SchemaIssue{Table: table, Kind: KeyMismatch, Expected: "partition key (" + e + ")", Actual: "partition key (" + a + ")"})
+	}
+	if e, a := joinColumns(schema.ClusteringKeys, true), joinTableColumns(clusteringKeys, true); e != a {
+		issues = append(issues, SchemaIssue{Table: table, Kind: KeyMismatch, Expected: "clustering key (" + e + ")", Actual: "clustering key (" + a + ")"})
+	}
+	return issues
+}
+func getKind(f *FieldDB) string {
+	switch {
+	case f.Partition:
+		return "partition_key"
+	case f.Clustering:
+		return "clustering"
+	case f.Static:
+		return "static"
+	default:
+		return "regular"
+	}
+}
+func sortByPosition(columns []TableColumn) {
+	sort.SliceStable(columns, func(i, j int) bool {
+		return columns[i].Position < columns[j].Position
+	})
+}
+func joinColumns(keys []*FieldDB, order bool) string {
+	items := make([]string, 0, len(keys))
+	for _, k := range keys {
+		if order && k.Order == desc {
+			items = append(items, k.Column+" "+desc)
+		} else {
+			items = append(items, k.Column)
+		}
+	}
+	return strings.Join(items, ", ")
+}
+func joinTableColumns(columns []TableColumn, order bool) string {
+	items := make([]string, 0, len(columns))
+	for _, c := range columns {
+		if order && strings.ToLower(c.ClusteringOrder) == desc {
+			items = append(items, c.Name+" "+desc)
+		} else {
+			items = append(items, c.Name)
+		}
+	}
+	return strings.Join(items, ", ")
+}
+
+var compatibleTypes = map[string][]string{
+	"text":      {"varchar", "ascii", "inet", "uuid", "timeuuid"},
+	"bigint":    {"tinyint", "smallint", "int", "varint", "counter", "timestamp", "time"},
+	"int":       {"tinyint", "smallint", "bigint", "varint", "counter"},
+	"smallint":  {"tinyint", "int", "bigint", "varint"},
+	"tinyint":   {"smallint", "int", "bigint", "varint"},
+	"varint":    {"tinyint", "smallint", "int", "bigint", "counter"},
+	"decimal":   {"double", "float"},
+	"uuid":      {"timeuuid"},
+	"timestamp": {"date"},
+	"list":      {"set"},
+	"duration":  {"bigint"},
+}
+var nativeTypes = map[string]bool{
+	"ascii": true, "bigint": true, "blob": true, "boolean": true, "counter": true, "date": true, "decimal": true, "double": true, "duration": true,
+	"float": true, "inet": true, "int": true, "smallint": true, "text": true, "time": true, "timestamp": true, "timeuuid": true, "tinyint": true,
+	"uuid": true, "varchar": true, "varint": true, "list": true, "set": true, "map": true, "tuple": true,
+}
+
+// IsCompatibleType checks if the column of the actual type can be scanned to the field of the expected type.
+// "frozen" is ignored, and the user-defined types are compatible with each other, because their names may not be inferred from the struct names.
+func IsCompatibleType(expected string, actual string) bool {
+	en, eargs := parseType(expected)
+	an, aargs := parseType(actual)
+	if !nativeTypes[en] && !nativeTypes[an] {
+		return true
+	}
+	if en != an {
+		ok := false
+		for _, t := range compatibleTypes[en] {
+			if t == an {
+				ok = true
+				break
+			}
+		}
+		if !ok {
+			return false
+		}
+	}
+	if len(eargs) != len(aargs) {
+		return false
+	}
+	for i := range eargs {
+		if !IsCompatibleType(eargs[i], aargs[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+// parseType parses "map<text, frozen<list<int>>>" into "map" and ["text", "list<int>"], without "frozen" and the keyspace of the user-defined types.
+func parseType(t string) (string, []string) {
+	t = strings.ToLower(strings.ReplaceAll(t, " ", ""))
+	for strings.HasPrefix(t, "frozen<") && strings.HasSuffix(t, ">") {
+		t = t[len("frozen<") : len(t)-1]
+	}
+	i := strings.Index(t, "<")
+	if i < 0 || !strings.HasSuffix(t, ">") {
+		if j := strings.LastIndex(t, "."); j >= 0 {
+			t = t[j+1:]
+		}
+		return strings.Trim(t, "\""), nil
+	}
+	return t[:i], SplitTag(t[i+1:len(t)-1], ',')
+}
+
+var (
+	modelsMu sync.Mutex
+	models   = make(map[string]reflect.Type)
+)
+
+// RegisterModel registers the model of the table to be validated by ValidateModels. The loaders and writers register their models.
+func RegisterModel(table string, modelType reflect.Type) {
+	if modelType.Kind() == reflect.Ptr {
+		modelType = modelType.Elem()
+	}
+	modelsMu.Lock()
+	defer modelsMu.Unlock()
+	models[table] = modelType
+}
+
+// ValidateModels validates all registered models, it returns a SchemaError of the issues, or nil if there is no issue.
+// The extra columns are reported only if includeExtra is true.
+func ValidateModels(ctx context.Context, ses Session, keyspace string, includeExtra bool) error {
+	modelsMu.Lock()
+	tables := sortedKeys(models)
+	types := make([]reflect.Type, 0, len(tables))
+	for _, t := range tables {
+		types = append(types, models[t])
+	}
+	modelsMu.Unlock()
+	all := make([]SchemaIssue, 0)
+	for i, table := range tables {
+		issues, err := ValidateSchema(ctx, ses, keyspace, table, types[i])
+		if err != nil {
+			return err
+		}
+		for _, issue := range issues {
+			if includeExtra || issue.IsError() {
+				all = append(all, issue)
+			}
+		}
+	}
+	if len(all) > 0 {
+		return &SchemaError{Issues: all}
+	}
+	return nil
+}
+
+// CheckSchema registers the model, and validates it if the options of the provider have StrictSchema, it is called by the constructors of the loaders and writers.
+func CheckSchema(db SessionProvider, table string, modelType reflect.Type) error {
+	RegisterModel(table, modelType)
+	o := GetDefaultOptions(db)
+	if o == nil || !o.StrictSchema {
+		return nil
+	}
+	var keyspace string
+	if !strings.Contains(table, ".") {
+		ks, err := GetKeyspace(db)
+		if err != nil {
+			return err
+		}
+		keyspace = ks
+	}
+	ses, err := db.Session()
+	if err != nil {
+		return err
+	}
+	timeout := DefaultSchemaTimeout
+	if o.Timeout > 0 {
+		timeout = o.Timeout
+	}
+	ctx, cancel := context.WithTimeout(WithDefaultOptions(context.Background(), o), timeout)
+	defer cancel()
+	issues, err := ValidateSchema(ctx, ses, keyspace, table, modelType)
+	if err != nil {
+		return err
+	}
+	errs := make([]SchemaIssue, 0)
+	for _, issue := range issues {
+		if issue.IsError() {
+			errs = append(errs, issue)
+		}
+	}
+	if len(errs) > 0 {
+		return &SchemaError{Issues: errs}
+	}
+	return nil
+}
+
+// KeyspaceProvider is a SessionProvider which knows the keyspace of its sessions.
+type KeyspaceProvider interface {
+	Keyspace() string
+}
+
+// GetKeyspace returns the keyspace of the sessions of the provider: the keyspace of the cluster config of a SessionManager, or of a KeyspaceProvider.
+// It fails if the keyspace is unknown, then the tables must be qualified as "keyspace.table".
+func GetKeyspace(db SessionProvider) (string, error) {
+	for {
+		var keyspace string
+		switch p := db.(type) {
+		case *OptionsProvider:
+			db = p.SessionProvider
+			continue
+		case *SessionManager:
+			if p.Cluster != nil {
+				keyspace = p.Cluster.Keyspace
+			}
+		case KeyspaceProvider:
+			keyspace = p.Keyspace()
+		default:
+			return "", fmt.Errorf("cannot get the keyspace of the sessions of %T, it is not a SessionManager or a KeyspaceProvider: qualify the table as keyspace.table", db)
+		}
+		if len(keyspace) == 0 {
+			return "", fmt.Errorf("the sessions of %T have no keyspace: set the keyspace of the cluster config, or qualify the table as keyspace.table", db)
+		}
+		return keyspace, nil
+	}
+}
+func splitTableName(keyspace string, table string) (string, string) {
+	if i := strings.Index(table, "."); i >= 0 {
+		return table[:i], table[i+1:]
+	}
+	return keyspace, table
+}
@@ -0,0 +1,73 @@
+package cassandra_test
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	c "github.com/core-go/cassandra"
+	"github.com/core-go/cassandra/cqltest"
+)
+
+type profile struct {
+	Id   string `cql:"id,partition_key"`
+	Name string `cql:"name"`
+}
+
+func TestCheckSchema(t *testing.T) {
+	columns := []string{"column_name", "kind", "type", "position", "clustering_order"}
+	tests := []struct {
+		name     string
+		strict   bool
+		keyspace string
+		table    string
+		tables   bool
+		rows     [][]interface{}
+		err      string
+		queries  int
+	}{
+		{"not strict", false, "", "profiles", false, nil, "", 0},
+		{"no keyspace", true, "", "profiles", true, nil, "no keyspace", 0},
+		{"qualified table", true, "", "app.profiles", true, [][]interface{}{{"id", "partition_key", "text", 0, "none"}, {"name", "regular", "text", -1, "none"}}, "", 2},
+		{"missing table", true, "app", "profiles", false, nil, "missing table", 1},
+		{"missing column", true, "app", "profiles", true, [][]interface{}{{"id", "partition_key", "text", 0, "none"}}, "missing column name", 2},
+		{"type mismatch", true, "app", "profiles", true, [][]interface{}{{"id", "partition_key", "int", 0, "none"}, {"name", "regular", "text", -1, "none"}}, "type mismatch id", 2},
+		{"extra column", true, "app", "profiles", true, [][]interface{}{{"id", "partition_key", "text", 0, "none"}, {"name", "regular", "text", -1, "none"}, {"age", "regular", "int", -1, "none"}}, "", 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ses := cqltest.NewSession().UseKeyspace(tt.keyspace)
+			if tt.tables {
+				ses.Rows("from system_schema.tables", []string{"table_name"}, []interface{}{"profiles"})
+			}
+			ses.Rows("from system_schema.columns", columns, tt.rows...)
+			var opts []c.QueryOption
+			if tt.strict {
+				opts = append(opts, c.WithStrictSchema())
+			}
+			_, err := c.NewLoaderWithProvider(c.NewOptionsProvider(ses.Provider(), opts...), tt.table, reflect.TypeOf(profile{}))
+			if len(tt.err) == 0 && err != nil || len(tt.err) > 0 && (err == nil || !strings.Contains(err.Error(), tt.err)) {
+				t.Fatalf("NewLoaderWithProvider() error = %v, want %q", err, tt.err)
+			}
+			var schemaError *c.SchemaError
+			if strings.Contains(tt.err, "mismatch") && !errors.As(err, &schemaError) {
+				t.Fatalf("NewLoaderWithProvider() error = %T, want a SchemaError", err)
+			}
+			if n := len(ses.Executed()); n != tt.queries {
+				t.Fatalf("%d statements executed, want %d", n, tt.queries)
+			}
+		})
+	}
+}
+
+func TestGetKeyspace(t *testing.T) {
+	ses := cqltest.NewSession().UseKeyspace("app")
+	keyspace, err := c.GetKeyspace(c.NewOptionsProvider(ses.Provider()))
+	if err != nil || keyspace != "app" {
+		t.Fatalf("GetKeyspace() = %q, %v", keyspace, err)
+	}
+	if _, err := c.GetKeyspace(cqltest.NewSession().Provider()); err == nil {
+		t.Fatal("GetKeyspace() of a session without keyspace must fail")
+	}
+}
@@ -2,7 +2,6 @@ package cassandra
 
 import (
 	"context"
-	"encoding/hex"
 	"reflect"
 	"strings"
 
@@ -15,7 +14,8 @@ const (
 )
 
 type SearchBuilder struct {
-	DB          *gocql.ClusterConfig
+	DB          SessionProvider
+	Options     *QueryOptions
 	BuildQuery  func(sm interface{}) (string, []interface{})
 	ModelType   reflect.Type
 	Map         func(ctx context.Context, model interface{}) (interface{}, error)
@@ -26,6 +26,9 @@ func NewSearchQuery(db *gocql.ClusterConfig, modelType reflect.Type, buildQuery
 	return NewSearchBuilder(db, modelType, buildQuery, options...)
 }
 func NewSearchBuilder(db *gocql.ClusterConfig, modelType reflect.Type, buildQuery func(interface{}) (string, []interface{}), options ...func(context.Context, interface{}) (interface{}, error)) (*SearchBuilder, error) {
+	return NewSearchBuilderWithProvider(GetSessionProvider(db), modelType, buildQuery, options...)
+}
+func NewSearchBuilderWithProvider(db SessionProvider, modelType reflect.Type, buildQuery func(interface{}) (string, []interface{}), options ...func(context.Context, interface{}) (interface{}, error)) (*SearchBuilder, error) {
 	var mp func(context.Context, interface{}) (interface{}, error)
 	if len(options) >= 1 {
 		mp = options[0]
@@ -34,41 +37,57 @@ func NewSearchBuilder(db *gocql.ClusterConfig, modelType reflect.Type, buildQuer
 	if err != nil {
 		return nil, err
 	}
-	builder := &SearchBuilder{DB: db, fieldsIndex: fieldsIndex, BuildQuery: buildQuery, ModelType: modelType, Map: mp}
+	builder := &SearchBuilder{DB: db, Options: GetDefaultOptions(db), fieldsIndex: fieldsIndex, BuildQuery: buildQuery, ModelType: modelType, Map: mp}
 	return builder, nil
 }
 
+// Search loads one page of limit rows. refId is the token of the page returned by the previous call, empty for the first page;
+// it is rejected if it was issued for another filter, or is expired. The returned token is empty if there is no next page.
 func (b *SearchBuilder) Search(ctx context.Context, m interface{}, results interface{}, limit int64, refId string) (string, error) {
 	sql, params := b.BuildQuery(m)
-	ses, err := b.DB.CreateSession()
-	defer ses.Close()
-
+	ses, err := b.DB.Session()
 	if err != nil {
 		return "", err
 	}
-	nextPageToken, er2 := QueryWithMap(ses, b.fieldsIndex, results, sql, params, limit, refId, b.Map)
+	nextPageToken, er2 := QueryWithToken(WithDefaultOptions(ctx, b.Options), ses, b.fieldsIndex, results, sql, params, limit, refId, b.Map)
 	return nextPageToken, er2
 }
-func QueryWithMap(ses *gocql.Session, fieldsIndex map[string]int, results interface{}, sql string, values []interface{}, max int64, refId string, options ...func(context.Context, interface{}) (interface{}, error)) (string, error) {
+
+// Each calls fn for each row of the filter, fn receives the pointer of the model; the rows are scanned one by one with the driver paging.
+func (b *SearchBuilder) Each(ctx context.Context, m interface{}, fn func(interface{}) error) error {
+	sql, params := b.BuildQuery(m)
+	ses, err := b.DB.Session()
+	if err != nil {
+		return err
+	}
+	ctx = WithDefaultOptions(ctx, b.Options)
+	return QueryEachModel(ctx, ses, b.ModelType, b.fieldsIndex, func(model interface{}) error {
+		if b.Map != nil {
+			r, err := b.Map(ctx, model)
+			if err != nil {
+				return err
+			}
+			if r != nil {
+				model = r
+			}
+		}
+		return fn(model)
+	}, sql, params...)
+}
+func QueryWithMap(ses Session, fieldsIndex map[string]int, results interface{}, sql string, values []interface{}, max int64, refId string, options ...func(context.Context, interface{}) (interface{}, error)) (string, error) {
+	return QueryWithMapContext(context.Background(), ses, fieldsIndex, results, sql, values, max, refId, options...)
+}
+func QueryWithMapContext(ctx context.Context, ses Session, fieldsIndex map[string]int, results interface{}, sql string, values []interface{}, max int64, refId string, options ...func(context.Context, interface{}) (interface{}, error)) (string, error) {
 	var mp func(context.Context, interface{}) (interface{}, error)
 	if len(options) > 0 && options[0] != nil {
 		mp = options[0]
 	}
-	next, er0 := hex.DecodeString(refId)
-	if er0 != nil {
-		return "", er0
-	}
-	query := ses.Query(sql, values...).PageState(next).PageSize(int(max))
-	if query.Exec() != nil {
-		return "", query.Exec()
-	}
-	err := ScanIter(query.Iter(), results, fieldsIndex)
+	nextPageToken, err := QueryWithPageContext(ctx, ses, fieldsIndex, results, max, refId, sql, values...)
 	if err != nil {
 		return "", err
 	}
-	nextPageToken := hex.EncodeToString(query.Iter().PageState())
 	if mp != nil {
-		_, err := MapModels(context.Background(), results, mp)
+		_, err := MapModels(ctx, results, mp)
 		return nextPageToken, err
 	}
 	return nextPageToken, nil
@@ -119,27 +138,14 @@ func GetSortType(sortType string) string {
 	}
 }
 func GetFieldByJson(modelType reflect.Type, jsonName string) (int, string, string) {
-	numField := modelType.NumField()
-	for i := 0; i < numField; i++ {
-		field := modelType.Field(i)
-		tag1, ok1 := field.Tag.Lookup("json")
-		if ok1 && strings.Split(tag1, ",")[0] == jsonName {
-			if tag2, ok2 := field.Tag.Lookup("gorm"); ok2 {
-				if has := strings.Contains(tag2, "column"); has {
-					str1 := strings.Split(tag2, ";")
-					num := len(str1)
-					for k := 0; k < num; k++ {
-						str2 := strings.Split(str1[k], ":")
-						for j := 0; j < len(str2); j++ {
-							if str2[j] == "column" {
-								return i, field.Name, str2[j+1]
-							}
-						}
-					}
-				}
-			}
-			return i, field.Name, ""
+	for _, f := range GetSchema(modelType).Columns {
+		if f.JSON == jsonName {
+			return f.Index, f.Field, f.Column
 		}
 	}
+	i := GetIndexByTag("json", jsonName, modelType)
+	if i > -1 {
+		return i, modelType.Field(i).Name, ""
+	}
 	return -1, jsonName, jsonName
 }
@@ -18,7 +18,10 @@ func (s *Searcher) Search(ctx context.Context, m interface{}, results interface{
 	return s.search(ctx, m, results, limit, nextPageToken)
 }
 func NewSearcherWithQuery(db *gocql.ClusterConfig, modelType reflect.Type, buildQuery func(interface{}) (string, []interface{}), options ...func(context.Context, interface{}) (interface{}, error)) (*Searcher, error) {
-	builder, err := NewSearchBuilder(db, modelType, buildQuery, options...)
+	return NewSearcherWithProvider(GetSessionProvider(db), modelType, buildQuery, options...)
+}
+func NewSearcherWithProvider(db SessionProvider, modelType reflect.Type, buildQuery func(interface{}) (string, []interface{}), options ...func(context.Context, interface{}) (interface{}, error)) (*Searcher, error) {
+	builder, err := NewSearchBuilderWithProvider(db, modelType, buildQuery, options...)
 	if err != nil {
 		return nil, err
 	}
@@ -0,0 +1,91 @@
+package cassandra
+
+import (
+	"sync"
+
+	"github.com/apache/cassandra-gocql-driver"
+)
+
+// SessionProvider hands out a long-lived session which is shared by all queries.
+// The session must not be closed by the caller; close the provider instead.
+type SessionProvider interface {
+	Session() (Session, error)
+	Close()
+}
+
+// SessionManager creates the session of the cluster config on the first call, and shares it until it is closed.
+// Connect creates the session, it can be replaced by a fake session in the unit tests.
+type SessionManager struct {
+	Cluster *gocql.ClusterConfig
+	Connect func() (Session, error)
+	mu      sync.Mutex
+	session Session
+}
+
+func NewSessionManager(cluster *gocql.ClusterConfig) *SessionManager {
+	return &SessionManager{Cluster: cluster, Connect: func() (Session, error) {
+		ses, err := cluster.CreateSession()
+		if err != nil {
+			return nil, err
+		}
+		return NewSession(ses), nil
+	}}
+}
+
+// Session creates the session on the first call, and creates a new one if the previous session was closed or could not be created.
+func (m *SessionManager) Session() (Session, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.session != nil && !m.session.Closed() {
+		return m.session, nil
+	}
+	ses, err := m.Connect()
+	if err != nil {
+		return nil, err
+	}
+	m.session = ses
+	return ses, nil
+}
+func (m *SessionManager) Close() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.session != nil {
+		m.session.Close()
+		m.session = nil
+	}
+}
+
+var (
+	sessionsMu sync.Mutex
+	sessions   = make(map[*gocql.ClusterConfig]*SessionManager)
+)
+
+// GetSessionProvider returns the shared provider of the cluster config, so that all loaders and writers built from the same config use one session.
+func GetSessionProvider(cluster *gocql.ClusterConfig) SessionProvider {
+	sessionsMu.Lock()
+	defer sessionsMu.Unlock()
+	m, ok := sessions[cluster]
+	if !ok {
+		m = NewSessionManager(cluster)
+		sessions[cluster] = m
+	}
+	return m
+}
+func CloseSession(cluster *gocql.ClusterConfig) {
+	sessionsMu.Lock()
+	m, ok := sessions[cluster]
+	delete(sessions, cluster)
+	sessionsMu.Unlock()
+	if ok {
+		m.Close()
+	}
+}
+func CloseSessions() {
+	sessionsMu.Lock()
+	ms := sessions
+	sessions = make(map[*gocql.ClusterConfig]*SessionManager)
+	sessionsMu.Unlock()
+	for _, m := range ms {
+		m.Close()
+	}
+}
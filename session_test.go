@@ -0,0 +1,93 @@
+package cassandra_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/apache/cassandra-gocql-driver"
+	c "github.com/core-go/cassandra"
+	"github.com/core-go/cassandra/cqltest"
+)
+
+func TestSessionManager(t *testing.T) {
+	failure := errors.New("no hosts")
+	tests := []struct {
+		name     string
+		fails    int
+		steps    func(m *c.SessionManager) error
+		connects int
+		closed   int
+	}{
+		{"reused", 0, func(m *c.SessionManager) error {
+			s1, _ := m.Session()
+			s2, err := m.Session()
+			if s1 != s2 {
+				return errors.New("the session is not reused")
+			}
+			return err
+		}, 1, 0},
+		{"closed by the provider", 0, func(m *c.SessionManager) error {
+			m.Session()
+			m.Close()
+			m.Close()
+			_, err := m.Session()
+			return err
+		}, 2, 1},
+		{"closed outside", 0, func(m *c.SessionManager) error {
+			s, _ := m.Session()
+			s.Close()
+			_, err := m.Session()
+			return err
+		}, 2, 1},
+		{"failed connection is not kept", 1, func(m *c.SessionManager) error {
+			if _, err := m.Session(); !errors.Is(err, failure) {
+				return errors.New("the error of the connection is not returned")
+			}
+			_, err := m.Session()
+			return err
+		}, 2, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var sessions []*cqltest.Session
+			connects := 0
+			m := &c.SessionManager{Connect: func() (c.Session, error) {
+				connects++
+				if connects <= tt.fails {
+					return nil, failure
+				}
+				ses := cqltest.NewSession()
+				sessions = append(sessions, ses)
+				return ses, nil
+			}}
+			if err := tt.steps(m); err != nil {
+				t.Fatal(err)
+			}
+			closed := 0
+			for _, ses := range sessions {
+				if ses.Closed() {
+					closed++
+				}
+			}
+			if connects != tt.connects || closed != tt.closed {
+				t.Fatalf("connected %d times, closed %d sessions, want %d and %d", connects, closed, tt.connects, tt.closed)
+			}
+		})
+	}
+}
+
+func TestGetSessionProvider(t *testing.T) {
+	cluster := &gocql.ClusterConfig{Keyspace: "shop"}
+	p := c.GetSessionProvider(cluster)
+	if c.GetSessionProvider(cluster) != p {
+		t.Fatal("GetSessionProvider() returns another provider for the same cluster config")
+	}
+	if c.GetSessionProvider(&gocql.ClusterConfig{Keyspace: "shop"}) == p {
+		t.Fatal("GetSessionProvider() shares the provider of another cluster config")
+	}
+	c.CloseSession(cluster)
+	if c.GetSessionProvider(cluster) == p {
+		t.Fatal("GetSessionProvider() returns the provider closed by CloseSession")
+	}
+	c.CloseSessions()
+}
@@ -0,0 +1,186 @@
+package cassandra
+
+import (
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Tag is the mapping of a struct field, parsed from the cql tag, such as:
+//
+//	ID      string    `cql:"id,partition_key"`
+//	Created time.Time `cql:"created,clustering_key=desc"`
+//	Tags    []string  `cql:"tags,type=set<text>,omitempty"`
+//	Address *Address  `cql:"address,udt"`
+//	Point   Point     `cql:"point,type=tuple<int,int>"`
+//	_       struct{}  `cql:",ttl=3600"`
+//
+// If the field has no cql tag, the gorm tag is used, such as `gorm:"column:id;primary_key"`.
+// The readonly columns are neither inserted nor updated; the gorm "insert:false" and "update:false" exclude the column from one of them only.
+type Tag struct {
+	Column     string
+	Ignore     bool
+	Key        bool
+	Partition  bool
+	Clustering bool
+	Order      string
+	Static     bool
+	Counter    bool
+	OmitEmpty  bool
+	ReadOnly   bool
+	NoInsert   bool
+	NoUpdate   bool
+	UDT        bool
+	Tuple      bool
+	Type       string
+	Scale      int8
+	TTL        int
+}
+
+// SplitTag splits the tag by the separator, except the separators inside <>, such as "tags,type=map<text,int>".
+func SplitTag(tag string, sep byte) []string {
+	items := make([]string, 0)
+	depth := 0
+	start := 0
+	for i := 0; i < len(tag); i++ {
+		switch tag[i] {
+		case '<', '(':
+			depth++
+		case '>', ')':
+			depth--
+		case sep:
+			if depth == 0 {
+				items = append(items, strings.TrimSpace(tag[start:i]))
+				start = i + 1
+			}
+		}
+	}
+	return append(items, strings.TrimSpace(tag[start:]))
+}
+func ParseTag(field reflect.StructField) (*Tag, bool) {
+	if tag, ok := field.Tag.Lookup("cql"); ok {
+		return ParseCqlTag(field, tag), true
+	}
+	if tag, ok := field.Tag.Lookup("gorm"); ok {
+		return ParseGormTag(field, tag)
+	}
+	return nil, false
+}
+func ParseCqlTag(field reflect.StructField, tag string) *Tag {
+	t := &Tag{Scale: -1}
+	if tag == IgnoreReadWrite {
+		t.Ignore = true
+		return t
+	}
+	items := SplitTag(tag, ',')
+	t.Column = items[0]
+	if len(t.Column) == 0 {
+		t.Column = strings.ToLower(field.Name)
+	}
+	for _, item := range items[1:] {
+		kv := strings.SplitN(item, "=", 2)
+		value := ""
+		if len(kv) > 1 {
+			value = strings.TrimSpace(kv[1])
+		}
+		setTagOption(t, strings.TrimSpace(kv[0]), value)
+	}
+	setScale(t, field)
+	return t
+}
+func ParseGormTag(field reflect.StructField, tag string) (*Tag, bool) {
+	t := &Tag{Scale: -1}
+	items := SplitTag(tag, ';')
+	for _, item := range items {
+		kv := strings.SplitN(item, ":", 2)
+		key := strings.TrimSpace(kv[0])
+		value := ""
+		if len(kv) > 1 {
+			value = strings.TrimSpace(kv[1])
+		}
+		switch key {
+		case IgnoreReadWrite:
+			t.Ignore = true
+		case "column":
+			t.Column = value
+		case "insert":
+			t.NoInsert = value == "false"
+		case "update":
+			t.NoUpdate = value == "false"
+		default:
+			setTagOption(t, key, value)
+		}
+	}
+	setScale(t, field)
+	return t, len(t.Column) > 0 || t.TTL > 0
+}
+func setTagOption(t *Tag, key string, value string) {
+	switch key {
+	case "primary_key":
+		t.Key = true
+	case "partition_key":
+		t.Key = true
+		t.Partition = true
+	case "clustering_key":
+		t.Key = true
+		t.Clustering = true
+		t.Order = asc
+		if strings.ToLower(value) == desc {
+			t.Order = desc
+		}
+	case "static":
+		t.Static = true
+	case "counter":
+		t.Counter = true
+	case "omitempty":
+		t.OmitEmpty = true
+	case "readonly":
+		t.ReadOnly = true
+	case "udt":
+		t.UDT = true
+	case "tuple":
+		t.Tuple = true
+	case "type":
+		t.Type = value
+		tp := strings.ToLower(value)
+		if strings.HasPrefix(tp, "tuple<") || strings.HasPrefix(tp, "frozen<tuple<") {
+			t.Tuple = true
+		}
+	case "scale":
+		if scale, err := strconv.Atoi(value); err == nil {
+			t.Scale = int8(scale)
+		}
+	case "ttl":
+		if ttl, err := strconv.Atoi(value); err == nil {
+			t.TTL = ttl
+		}
+	}
+}
+func setScale(t *Tag, field reflect.StructField) {
+	if tScale, ok := field.Tag.Lookup("scale"); ok {
+		if scale, err := strconv.Atoi(tScale); err == nil {
+			t.Scale = int8(scale)
+		}
+	}
+	if tTTL, ok := field.Tag.Lookup("ttl"); ok {
+		if ttl, err := strconv.Atoi(tTTL); err == nil {
+			t.TTL = ttl
+		}
+	}
+}
+
+var schemas sync.Map
+
+// GetSchema returns the cached schema of the model type. The schema is shared, so it must not be modified.
+func GetSchema(modelType reflect.Type) *Schema {
+	m := modelType
+	if m.Kind() == reflect.Ptr {
+		m = m.Elem()
+	}
+	if s, ok := schemas.Load(m); ok {
+		return s.(*Schema)
+	}
+	s, _ := schemas.LoadOrStore(m, CreateSchema(m))
+	return s.(*Schema)
+}
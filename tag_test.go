@@ -0,0 +1,104 @@
+package cassandra_test
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	c "github.com/core-go/cassandra"
+)
+
+type auditedUser struct {
+	Id        string    `gorm:"column:id;primary_key"`
+	Name      string    `gorm:"column:name"`
+	CreatedAt time.Time `gorm:"column:createdat;update:false"`
+	UpdatedAt time.Time `gorm:"column:updatedat;insert:false"`
+	Revision  int       `cql:"revision,readonly"`
+	Secret    string    `gorm:"-"`
+}
+
+func TestParseTag(t *testing.T) {
+	tests := []struct {
+		name string
+		tag  reflect.StructTag
+		want *c.Tag
+	}{
+		{"cql column", `cql:"name"`, &c.Tag{Column: "name", Scale: -1}},
+		{"cql default column", `cql:",omitempty"`, &c.Tag{Column: "field", OmitEmpty: true, Scale: -1}},
+		{"cql partition key", `cql:"id,partition_key"`, &c.Tag{Column: "id", Key: true, Partition: true, Scale: -1}},
+		{"cql clustering key", `cql:"created,clustering_key=desc"`, &c.Tag{Column: "created", Key: true, Clustering: true, Order: "desc", Scale: -1}},
+		{"cql type with separators", `cql:"tags,type=map<text,int>,static"`, &c.Tag{Column: "tags", Type: "map<text,int>", Static: true, Scale: -1}},
+		{"cql tuple", `cql:"point,type=frozen<tuple<int,int>>"`, &c.Tag{Column: "point", Type: "frozen<tuple<int,int>>", Tuple: true, Scale: -1}},
+		{"cql scale and ttl", `cql:"balance,scale=2,ttl=60"`, &c.Tag{Column: "balance", Scale: 2, TTL: 60}},
+		{"cql readonly", `cql:"revision,readonly"`, &c.Tag{Column: "revision", ReadOnly: true, Scale: -1}},
+		{"cql ignored", `cql:"-"`, &c.Tag{Ignore: true, Scale: -1}},
+		{"gorm primary key", `gorm:"column:id;primary_key"`, &c.Tag{Column: "id", Key: true, Scale: -1}},
+		{"gorm no update", `gorm:"column:createdat;update:false"`, &c.Tag{Column: "createdat", NoUpdate: true, Scale: -1}},
+		{"gorm no insert", `gorm:"column:updatedat;insert:false"`, &c.Tag{Column: "updatedat", NoInsert: true, Scale: -1}},
+		{"gorm insert and update", `gorm:"column:name;insert:true;update:true"`, &c.Tag{Column: "name", Scale: -1}},
+		{"gorm scale", `gorm:"column:amount" scale:"3"`, &c.Tag{Column: "amount", Scale: 3}},
+		{"cql over gorm", `cql:"name" gorm:"column:other"`, &c.Tag{Column: "name", Scale: -1}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tag, ok := c.ParseTag(reflect.StructField{Name: "Field", Tag: tt.tag})
+			if !ok || !reflect.DeepEqual(tag, tt.want) {
+				t.Fatalf("ParseTag(%s) = %+v, %v, want %+v", tt.tag, tag, ok, tt.want)
+			}
+		})
+	}
+	if _, ok := c.ParseTag(reflect.StructField{Name: "Field", Tag: `json:"field"`}); ok {
+		t.Fatal("ParseTag() of a field without cql or gorm tag must return false")
+	}
+}
+
+func TestInsertAndUpdateColumns(t *testing.T) {
+	schema := c.GetSchema(reflect.TypeOf(auditedUser{}))
+	tests := []struct {
+		column string
+		insert bool
+		update bool
+	}{
+		{"name", true, true},
+		{"createdat", true, false},
+		{"updatedat", false, true},
+		{"revision", false, false},
+	}
+	now := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	user := &auditedUser{Id: "1", Name: "Peter", CreatedAt: now, UpdatedAt: now, Revision: 2, Secret: "x"}
+	insert, _ := c.BuildToInsert("users", user, schema)
+	update, _ := c.BuildToUpdate("users", user, schema)
+	_, setAndWhere, _ := strings.Cut(update, " set ")
+	set, _, _ := strings.Cut(setAndWhere, " where ")
+	for _, tt := range tests {
+		t.Run(tt.column, func(t *testing.T) {
+			f := schema.Fields[tt.column]
+			if f == nil || f.Insert != tt.insert || f.Update != tt.update {
+				t.Fatalf("Fields[%s] = %+v, want Insert %v and Update %v", tt.column, f, tt.insert, tt.update)
+			}
+			if inserted := strings.Contains(insert, tt.column); inserted != tt.insert {
+				t.Fatalf("BuildToInsert() = %q, %s inserted %v, want %v", insert, tt.column, inserted, tt.insert)
+			}
+			if updated := strings.Contains(set, tt.column+"="); updated != tt.update {
+				t.Fatalf("BuildToUpdate() = %q, %s updated %v, want %v", update, tt.column, updated, tt.update)
+			}
+		})
+	}
+	if _, ok := schema.Fields["secret"]; ok || strings.Contains(insert, "secret") {
+		t.Fatalf("the ignored field is mapped: %q", insert)
+	}
+}
+
+func TestGetSchema(t *testing.T) {
+	schema := c.GetSchema(reflect.TypeOf(auditedUser{}))
+	if c.GetSchema(reflect.TypeOf(&auditedUser{})) != schema {
+		t.Fatal("GetSchema() of the pointer type is not the cached schema of the struct")
+	}
+	if c.CreateSchema(reflect.TypeOf(auditedUser{})) == schema {
+		t.Fatal("CreateSchema() returns the cached schema")
+	}
+	if got := strings.Join(schema.SColumns, ","); got != "id,name,createdat,updatedat,revision" {
+		t.Fatalf("SColumns = %s", got)
+	}
+}
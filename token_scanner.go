@@ -0,0 +1,256 @@
+package cassandra
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/apache/cassandra-gocql-driver"
+)
+
+// TokenRange is the range of the partition tokens "token(pk) > Start and token(pk) <= End" of the Murmur3 partitioner.
+type TokenRange struct {
+	Start int64
+	End   int64
+}
+
+// SplitTokenRanges splits the whole ring into n ranges of the same size.
+func SplitTokenRanges(n int) []TokenRange {
+	if n < 1 {
+		n = 1
+	}
+	step := math.MaxUint64 / uint64(n)
+	ranges := make([]TokenRange, 0, n)
+	start := int64(math.MinInt64)
+	for i := 0; i < n; i++ {
+		end := int64(uint64(start) + step)
+		if i == n-1 {
+			end = math.MaxInt64
+		}
+		ranges = append(ranges, TokenRange{Start: start, End: end})
+		start = end
+	}
+	return ranges
+}
+
+// TokenScanner reads the whole table by token ranges, so that no query scans more than one range.
+// The ranges are scanned with bounded concurrency, a failed range is retried from the last token it has read:
+// the rows of this token which are already passed to the callback are skipped, because the rows of a token are always read in the same order.
+type TokenScanner struct {
+	DB            SessionProvider
+	Options       *QueryOptions
+	Table         string
+	ModelType     reflect.Type
+	Fields        string
+	PartitionKeys []string
+	Splits        int
+	// Concurrency is the number of the ranges scanned at the same time, GetConcurrency of the context if it is not set.
+	Concurrency int
+	Retries     int
+	RetryDelay  time.Duration
+	fieldsIndex map[string]int
+}
+
+func NewTokenScanner(db *gocql.ClusterConfig, table string, modelType reflect.Type) (*TokenScanner, error) {
+	return NewTokenScannerWithProvider(GetSessionProvider(db), table, modelType)
+}
+func NewTokenScannerWithProvider(db SessionProvider, table string, modelType reflect.Type) (*TokenScanner, error) {
+	if modelType.Kind() == reflect.Ptr {
+		modelType = modelType.Elem()
+	}
+	fieldsIndex, err := GetColumnIndexes(modelType)
+	if err != nil {
+		return nil, err
+	}
+	schema := GetSchema(modelType)
+	if len(schema.PartitionKeys) == 0 {
+		return nil, fmt.Errorf("%v has no partition key", modelType.Name())
+	}
+	partitionKeys := make([]string, 0)
+	for _, k := range schema.PartitionKeys {
+		partitionKeys = append(partitionKeys, k.Column)
+	}
+	return &TokenScanner{DB: db, Options: GetDefaultOptions(db), Table: table, ModelType: modelType, Fields: BuildFieldsBySchema(schema), PartitionKeys: partitionKeys,
+		Splits: 256, Retries: 3, RetryDelay: 500 * time.Millisecond, fieldsIndex: fieldsIndex}, nil
+}
+
+// Scan calls fn for each row of the table. fn is called from multiple goroutines, so it must be safe for concurrent use.
+func (s *TokenScanner) Scan(ctx context.Context, fn func(model interface{}) error) error {
+	return s.ScanRanges(ctx, func(_ int, model interface{}) error {
+		return fn(model)
+	})
+}
+
+// ScanRanges calls fn with the index of the token range and the row, the rows of one range are passed in token order by one goroutine.
+// The first error cancels the other ranges.
+func (s *TokenScanner) ScanRanges(ctx context.Context, fn func(r int, model interface{}) error) error {
+	ses, err := s.DB.Session()
+	if err != nil {
+		return err
+	}
+	ctx = WithDefaultOptions(ctx, s.Options)
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	ranges := SplitTokenRanges(s.Splits)
+	concurrency := s.Concurrency
+	if concurrency <= 0 {
+		concurrency = GetConcurrency(ctx)
+	}
+	indexes := make(chan int)
+	var wg sync.WaitGroup
+	var once sync.Once
+	var first error
+	for w := 0; w < concurrency; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indexes {
+				if err := s.scanRange(ctx, ses, ranges[i], func(model interface{}) error {
+					return fn(i, model)
+				}); err != nil {
+					once.Do(func() {
+						first = err
+						cancel()
+					})
+				}
+			}
+		}()
+	}
+	for i := range ranges {
+		select {
+		case indexes <- i:
+		case <-ctx.Done():
+		}
+	}
+	close(indexes)
+	wg.Wait()
+	if first != nil {
+		return first
+	}
+	return ctx.Err()
+}
+func (s *TokenScanner) scanRange(ctx context.Context, ses Session, r TokenRange, fn func(model interface{}) error) error {
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+	token := "token(" + strings.Join(s.PartitionKeys, ",") + ")"
+	query := fmt.Sprintf("select %s,%s from %s where %s > ? and %s <= ?", token, s.Fields, s.Table, token, token)
+	resume := fmt.Sprintf("select %s,%s from %s where %s >= ? and %s <= ?", token, s.Fields, s.Table, token, token)
+	p := &scanPosition{token: r.Start}
+	var err error
+	for attempt := 0; attempt <= s.Retries; attempt++ {
+		if attempt > 0 {
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(s.RetryDelay * time.Duration(attempt)):
+			}
+		}
+		if p.rows > 0 {
+			err = s.scanFrom(ctx, ses, resume, p, r.End, fn)
+		} else {
+			err = s.scanFrom(ctx, ses, query, p, r.End, fn)
+		}
+		if err == nil {
+			return nil
+		}
+		var fnErr *callbackError
+		if errors.As(err, &fnErr) {
+			return fnErr.err
+		}
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+	}
+	return err
+}
+
+// scanPosition is the last token passed to the callback, and the number of the rows of this token passed to the callback.
+type scanPosition struct {
+	token int64
+	rows  int
+}
+
+// scanFrom reads the rows from the position, and moves the position for each row passed to fn, so that the retry does not pass the same rows again.
+// If the position has rows, the query reads from the token included, and these rows are skipped.
+func (s *TokenScanner) scanFrom(ctx context.Context, ses Session, query string, p *scanPosition, end int64, fn func(model interface{}) error) error {
+	q, cancel := ApplyOptions(ctx, ses.Query(query, p.token, end))
+	defer cancel()
+	iter := q.Iter()
+	columns := GetColumns(iter.Columns())
+	skip := p.rows
+	for {
+		var token int64
+		model := reflect.New(s.ModelType).Interface()
+		r := append([]interface{}{&token}, StructScan(model, columns, s.fieldsIndex, 0)...)
+		if !iter.Scan(r...) {
+			return iter.Close()
+		}
+		if skip > 0 && token == p.token {
+			skip--
+			continue
+		}
+		skip = 0
+		if err := fn(model); err != nil {
+			iter.Close()
+			return &callbackError{err: err}
+		}
+		if token == p.token {
+			p.rows++
+		} else {
+			p.token = token
+			p.rows = 1
+		}
+	}
+}
+
+// callbackError is the error of the callback, which is returned as is and not retried.
+type callbackError struct {
+	err error
+}
+
+func (e *callbackError) Error() string {
+	return e.err.Error()
+}
+
+// ScanTable calls fn for each row of the table with the token scanner, fn is called from multiple goroutines.
+func ScanTable[T any](ctx context.Context, s *TokenScanner, fn func(*T) error) error {
+	return s.Scan(ctx, func(model interface{}) error {
+		return fn(model.(*T))
+	})
+}
+
+// ScanAll loads all rows of the table with the token scanner, in token order.
+func (s *TokenScanner) ScanAll(ctx context.Context) (interface{}, error) {
+	ranges := make([]reflect.Value, s.splits())
+	err := s.ScanRanges(ctx, func(r int, model interface{}) error {
+		if !ranges[r].IsValid() {
+			ranges[r] = reflect.MakeSlice(reflect.SliceOf(s.ModelType), 0, 0)
+		}
+		ranges[r] = reflect.Append(ranges[r], reflect.ValueOf(model).Elem())
+		return nil
+	})
+	result := reflect.New(reflect.SliceOf(s.ModelType))
+	if err != nil {
+		return result.Interface(), err
+	}
+	all := result.Elem()
+	for _, r := range ranges {
+		if r.IsValid() {
+			all = reflect.AppendSlice(all, r)
+		}
+	}
+	result.Elem().Set(all)
+	return result.Interface(), nil
+}
+func (s *TokenScanner) splits() int {
+	if s.Splits < 1 {
+		return 1
+	}
+	return s.Splits
+}
@@ -0,0 +1,165 @@
+package cassandra_test
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/apache/cassandra-gocql-driver"
+
+	c "github.com/core-go/cassandra"
+	"github.com/core-go/cassandra/cqltest"
+)
+
+type visit struct {
+	Site string `cql:"site,partition_key"`
+	Page int    `cql:"page,clustering_key"`
+}
+
+func TestSplitTokenRanges(t *testing.T) {
+	for _, n := range []int{1, 2, 7, 256} {
+		ranges := c.SplitTokenRanges(n)
+		if len(ranges) != n || ranges[0].Start != -1<<63 || ranges[n-1].End != 1<<63-1 {
+			t.Fatalf("SplitTokenRanges(%d) = %v", n, ranges)
+		}
+		for i := 1; i < n; i++ {
+			if ranges[i].Start != ranges[i-1].End || ranges[i].Start >= ranges[i].End {
+				t.Fatalf("SplitTokenRanges(%d) has a gap or an overlap at %d: %v", n, i, ranges)
+			}
+		}
+	}
+}
+
+func TestTokenScannerResume(t *testing.T) {
+	columns := []string{"token", "site", "page"}
+	tests := []struct {
+		name   string
+		first  [][]interface{}
+		resume [][]interface{}
+		want   []string
+		token  int64
+	}{
+		{
+			name:   "fails in the middle of a partition",
+			first:  [][]interface{}{{int64(1), "a", 1}, {int64(5), "b", 1}, {int64(5), "b", 2}},
+			resume: [][]interface{}{{int64(5), "b", 1}, {int64(5), "b", 2}, {int64(5), "b", 3}, {int64(9), "c", 1}},
+			want:   []string{"a1", "b1", "b2", "b3", "c1"},
+			token:  5,
+		},
+		{
+			name:   "fails after the first row",
+			first:  [][]interface{}{{int64(1), "a", 1}},
+			resume: [][]interface{}{{int64(1), "a", 1}, {int64(1), "a", 2}},
+			want:   []string{"a1", "a2"},
+			token:  1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ses := cqltest.NewSession()
+			ses.On("token(site) > ?", cqltest.Result{Columns: columns, Rows: tt.first, Err: gocql.ErrTimeoutNoResponse})
+			ses.Rows("token(site) >= ?", columns, tt.resume...)
+			scanner, err := c.NewTokenScannerWithProvider(ses.Provider(), "visits", reflect.TypeOf(visit{}))
+			if err != nil {
+				t.Fatal(err)
+			}
+			scanner.Splits = 1
+			scanner.RetryDelay = 0
+			var got []string
+			err = c.ScanTable[visit](context.Background(), scanner, func(v *visit) error {
+				got = append(got, v.Site+string(rune('0'+v.Page)))
+				return nil
+			})
+			if err != nil || !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("Scan() = %v, %v, want %v", got, err, tt.want)
+			}
+			executed := ses.Executed()
+			if len(executed) != 2 || executed[1].Params[0] != tt.token {
+				t.Fatalf("executed %v, want a retry from the token %d", executed, tt.token)
+			}
+		})
+	}
+}
+
+func TestTokenScannerCallbackError(t *testing.T) {
+	ses := cqltest.NewSession()
+	ses.Rows("from visits", []string{"token", "site", "page"}, []interface{}{int64(1), "a", 1})
+	scanner, _ := c.NewTokenScannerWithProvider(ses.Provider(), "visits", reflect.TypeOf(visit{}))
+	scanner.Splits = 4
+	stop := errors.New("stop")
+	err := scanner.Scan(context.Background(), func(model interface{}) error { return stop })
+	if !errors.Is(err, stop) {
+		t.Fatalf("Scan() = %v, want the error of the callback", err)
+	}
+}
+
+func TestTokenScannerConcurrency(t *testing.T) {
+	tests := []struct {
+		name        string
+		concurrency int
+		options     []c.QueryOption
+		want        int
+	}{
+		{"concurrency of the options", 0, []c.QueryOption{c.WithConcurrency(2)}, 2},
+		{"concurrency of the scanner", 3, []c.QueryOption{c.WithConcurrency(2)}, 3},
+		{"default concurrency", 0, nil, c.DefaultConcurrency},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ses := cqltest.NewSession()
+			ses.Rows("from visits", []string{"token", "site", "page"}, []interface{}{int64(1), "a", 1})
+			scanner, _ := c.NewTokenScannerWithProvider(ses.Provider(), "visits", reflect.TypeOf(visit{}))
+			scanner.Splits = 32
+			scanner.Concurrency = tt.concurrency
+			var mu sync.Mutex
+			running, max := 0, 0
+			release := make(chan struct{})
+			var once sync.Once
+			ctx := c.WithQueryOptions(context.Background(), tt.options...)
+			done := make(chan error)
+			go func() {
+				done <- scanner.Scan(ctx, func(model interface{}) error {
+					mu.Lock()
+					running++
+					if running > max {
+						max = running
+					}
+					full := running == tt.want
+					mu.Unlock()
+					if full {
+						once.Do(func() { close(release) })
+					}
+					<-release
+					mu.Lock()
+					running--
+					mu.Unlock()
+					return nil
+				})
+			}()
+			if err := <-done; err != nil || max != tt.want {
+				t.Fatalf("Scan() = %v, %d ranges at the same time, want %d", err, max, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoaderAll(t *testing.T) {
+	ses := cqltest.NewSession()
+	ses.Rows("from visits", []string{"site", "page"}, []interface{}{"a", 1}, []interface{}{"b", 2})
+	loader, err := c.NewLoaderWithProvider(ses.Provider(), "visits", reflect.TypeOf(visit{}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	result, err := loader.All(context.Background())
+	if err != nil || len(*result.(*[]visit)) != 2 {
+		t.Fatalf("All() = %v, %v", result, err)
+	}
+	for _, e := range ses.Executed() {
+		if strings.Contains(e.Query, "token(") {
+			t.Fatalf("All() executed %q, want a select of the whole table", e.Query)
+		}
+	}
+}
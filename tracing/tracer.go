@@ -0,0 +1,228 @@
+package tracing
+
+import (
+	"context"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/apache/cassandra-gocql-driver"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+
+	c "github.com/core-go/cassandra"
+)
+
+const instrumentationName = "github.com/core-go/cassandra/tracing"
+
+// The attributes follow the semantic conventions of the database client spans.
+const (
+	DbSystem          = attribute.Key("db.system")
+	DbName            = attribute.Key("db.name")
+	DbStatement       = attribute.Key("db.statement")
+	DbOperation       = attribute.Key("db.operation")
+	DbTable           = attribute.Key("db.cassandra.table")
+	DbConsistency     = attribute.Key("db.cassandra.consistency_level")
+	DbPageSize        = attribute.Key("db.cassandra.page_size")
+	DbIdempotence     = attribute.Key("db.cassandra.idempotence")
+	DbCoordinatorId   = attribute.Key("db.cassandra.coordinator.id")
+	DbCoordinatorDc   = attribute.Key("db.cassandra.coordinator.dc")
+	DbRows            = attribute.Key("db.cassandra.rows_returned")
+	DbAttempt         = attribute.Key("db.cassandra.attempt")
+	DbBatchType       = attribute.Key("db.cassandra.batch.type")
+	DbBatchSize       = attribute.Key("db.cassandra.batch.size")
+	ServerAddress     = attribute.Key("server.address")
+	cassandraSystemId = "cassandra"
+)
+
+// Tracer is a cassandra.Observer which records a span for each attempt of a statement, as a child of the span of the context of the statement.
+// The values of the statements are never recorded, the statements are recorded unless Statement is false.
+//
+//	cassandra.DefaultObserver = tracing.NewTracer()
+//	provider := cassandra.NewOptionsProvider(cassandra.GetSessionProvider(cluster), cassandra.WithObserver(tracing.NewTracer()))
+type Tracer struct {
+	tracer    trace.Tracer
+	Statement bool
+}
+
+// NewTracer returns the tracer of the tracer provider, the global tracer provider if it is not set.
+func NewTracer(options ...trace.TracerProvider) *Tracer {
+	var provider trace.TracerProvider
+	if len(options) > 0 && options[0] != nil {
+		provider = options[0]
+	} else {
+		provider = otel.GetTracerProvider()
+	}
+	return &Tracer{tracer: provider.Tracer(instrumentationName), Statement: true}
+}
+func (t *Tracer) QueryObserver(info c.QueryInfo) gocql.QueryObserver {
+	return &queryObserver{tracer: t, info: info}
+}
+func (t *Tracer) BatchObserver(info c.BatchInfo) gocql.BatchObserver {
+	return &batchObserver{tracer: t, info: info}
+}
+
+type queryObserver struct {
+	tracer *Tracer
+	info   c.QueryInfo
+}
+
+func (o *queryObserver) ObserveQuery(ctx context.Context, q gocql.ObservedQuery) {
+	operation, table := ParseStatement(q.Statement)
+	attrs := []attribute.KeyValue{
+		DbSystem.String(cassandraSystemId),
+		DbOperation.String(operation),
+		DbIdempotence.Bool(o.info.Idempotent),
+		DbRows.Int(q.Rows),
+		DbAttempt.Int(q.Attempt),
+	}
+	if len(table) > 0 {
+		attrs = append(attrs, DbTable.String(table))
+	}
+	if o.info.PageSize > 0 {
+		attrs = append(attrs, DbPageSize.Int(o.info.PageSize))
+	}
+	attrs = appendCommon(attrs, o.tracer, q.Keyspace, q.Statement, o.info.Consistency, q.Host)
+	span := o.tracer.start(ctx, spanName(operation, q.Keyspace, table), q.Start, attrs)
+	end(span, q.Err, q.End)
+}
+
+type batchObserver struct {
+	tracer *Tracer
+	info   c.BatchInfo
+}
+
+func (o *batchObserver) ObserveBatch(ctx context.Context, b gocql.ObservedBatch) {
+	attrs := []attribute.KeyValue{
+		DbSystem.String(cassandraSystemId),
+		DbOperation.String("BATCH"),
+		DbBatchType.String(batchType(o.info.Type)),
+		DbBatchSize.Int(len(b.Statements)),
+		DbAttempt.Int(b.Attempt),
+	}
+	var table string
+	for i, stmt := range b.Statements {
+		_, t := ParseStatement(stmt)
+		if i == 0 {
+			table = t
+		} else if t != table {
+			table = ""
+			break
+		}
+	}
+	if len(table) > 0 {
+		attrs = append(attrs, DbTable.String(table))
+	}
+	attrs = appendCommon(attrs, o.tracer, b.Keyspace, strings.Join(b.Statements, "; "), o.info.Consistency, b.Host)
+	span := o.tracer.start(ctx, spanName("BATCH", b.Keyspace, table), b.Start, attrs)
+	end(span, b.Err, b.End)
+}
+func appendCommon(attrs []attribute.KeyValue, t *Tracer, keyspace string, stmt string, consistency *gocql.Consistency, host *gocql.HostInfo) []attribute.KeyValue {
+	if len(keyspace) > 0 {
+		attrs = append(attrs, DbName.String(keyspace))
+	}
+	if t.Statement && len(stmt) > 0 {
+		attrs = append(attrs, DbStatement.String(stmt))
+	}
+	if consistency != nil {
+		attrs = append(attrs, DbConsistency.String(consistency.String()))
+	}
+	if host != nil {
+		if ip := host.ConnectAddress(); ip != nil {
+			attrs = append(attrs, ServerAddress.String(ip.String()))
+		}
+		attrs = append(attrs, DbCoordinatorId.String(host.HostID()), DbCoordinatorDc.String(host.DataCenter()))
+	}
+	return attrs
+}
+func (t *Tracer) start(ctx context.Context, name string, start time.Time, attrs []attribute.KeyValue) trace.Span {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	options := []trace.SpanStartOption{trace.WithSpanKind(trace.SpanKindClient), trace.WithAttributes(attrs...)}
+	if !start.IsZero() {
+		options = append(options, trace.WithTimestamp(start))
+	}
+	_, span := t.tracer.Start(ctx, name, options...)
+	return span
+}
+func end(span trace.Span, err error, end time.Time) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	if end.IsZero() {
+		span.End()
+	} else {
+		span.End(trace.WithTimestamp(end))
+	}
+}
+
+// spanName returns "<operation> <keyspace>.<table>", or the operation if the table is unknown.
+func spanName(operation string, keyspace string, table string) string {
+	if len(table) == 0 {
+		return operation
+	}
+	if len(keyspace) > 0 && !strings.Contains(table, ".") {
+		return operation + " " + keyspace + "." + table
+	}
+	return operation + " " + table
+}
+func batchType(t gocql.BatchType) string {
+	switch t {
+	case gocql.LoggedBatch:
+		return "logged"
+	case gocql.UnloggedBatch:
+		return "unlogged"
+	case gocql.CounterBatch:
+		return "counter"
+	}
+	return strconv.Itoa(int(t))
+}
+
+// ParseStatement returns the operation of the statement in upper case, such as SELECT or INSERT, and the table, which is empty if it is not found.
+func ParseStatement(stmt string) (string, string) {
+	words := strings.Fields(stmt)
+	if len(words) == 0 {
+		return "", ""
+	}
+	operation := strings.ToUpper(words[0])
+	var after string
+	switch operation {
+	case "SELECT", "DELETE":
+		after = "FROM"
+	case "INSERT":
+		after = "INTO"
+	case "UPDATE":
+		return operation, tableName(words, 1)
+	case "CREATE", "ALTER", "DROP", "TRUNCATE":
+		for i := 1; i < len(words); i++ {
+			switch strings.ToUpper(words[i]) {
+			case "TABLE", "TYPE", "INDEX", "KEYSPACE", "IF", "NOT", "EXISTS":
+				continue
+			}
+			return operation, tableName(words, i)
+		}
+		return operation, ""
+	default:
+		return operation, ""
+	}
+	for i := 1; i < len(words)-1; i++ {
+		if strings.EqualFold(words[i], after) {
+			return operation, tableName(words, i+1)
+		}
+	}
+	return operation, ""
+}
+func tableName(words []string, i int) string {
+	if i >= len(words) {
+		return ""
+	}
+	name := words[i]
+	if j := strings.IndexAny(name, "( ;"); j >= 0 {
+		name = name[:j]
+	}
+	return strings.Trim(name, `"`)
+}
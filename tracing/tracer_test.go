@@ -0,0 +1,145 @@
+package tracing_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/apache/cassandra-gocql-driver"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+	"go.opentelemetry.io/otel/trace/embedded"
+	"go.opentelemetry.io/otel/trace/noop"
+
+	c "github.com/core-go/cassandra"
+	"github.com/core-go/cassandra/cqltest"
+	"github.com/core-go/cassandra/tracing"
+)
+
+// recorder is a trace.TracerProvider which records the spans which are ended.
+type recorder struct {
+	embedded.TracerProvider
+	mu    sync.Mutex
+	spans []*span
+}
+type tracer struct {
+	embedded.Tracer
+	recorder *recorder
+}
+type span struct {
+	noop.Span
+	recorder *recorder
+	name     string
+	attrs    map[attribute.Key]attribute.Value
+	status   codes.Code
+}
+
+func (r *recorder) Tracer(string, ...trace.TracerOption) trace.Tracer {
+	return &tracer{recorder: r}
+}
+func (t *tracer) Start(ctx context.Context, name string, options ...trace.SpanStartOption) (context.Context, trace.Span) {
+	config := trace.NewSpanStartConfig(options...)
+	s := &span{recorder: t.recorder, name: name, attrs: make(map[attribute.Key]attribute.Value)}
+	for _, kv := range config.Attributes() {
+		s.attrs[kv.Key] = kv.Value
+	}
+	return trace.ContextWithSpan(ctx, s), s
+}
+func (s *span) SetStatus(code codes.Code, _ string) {
+	s.status = code
+}
+func (s *span) End(...trace.SpanEndOption) {
+	s.recorder.mu.Lock()
+	defer s.recorder.mu.Unlock()
+	s.recorder.spans = append(s.recorder.spans, s)
+}
+
+func TestParseStatement(t *testing.T) {
+	tests := []struct {
+		stmt      string
+		operation string
+		table     string
+	}{
+		{"select id, name from users where id = ?", "SELECT", "users"},
+		{"SELECT * FROM shop.orders", "SELECT", "shop.orders"},
+		{"insert into users(id, name) values (?, ?)", "INSERT", "users"},
+		{"update users using ttl 10 set name = ? where id = ?", "UPDATE", "users"},
+		{"delete name from users where id = ?", "DELETE", "users"},
+		{`create table if not exists "Users" (id text primary key)`, "CREATE", "Users"},
+		{"truncate events;", "TRUNCATE", "events"},
+		{"begin batch", "BEGIN", ""},
+		{"", "", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.stmt, func(t *testing.T) {
+			operation, table := tracing.ParseStatement(tt.stmt)
+			if operation != tt.operation || table != tt.table {
+				t.Fatalf("ParseStatement(%q) = %q, %q, want %q, %q", tt.stmt, operation, table, tt.operation, tt.table)
+			}
+		})
+	}
+}
+
+func TestTracer(t *testing.T) {
+	failure := errors.New("write failed")
+	tests := []struct {
+		name      string
+		statement bool
+		execute   func(ctx context.Context, ses *cqltest.Session) error
+		span      string
+		attrs     map[attribute.Key]attribute.Value
+		status    codes.Code
+	}{
+		{"query", true, func(ctx context.Context, ses *cqltest.Session) error {
+			return c.ExecContext(ctx, ses, "update users set name = ? where id = ?", "Peter", "1")
+		}, "UPDATE users", map[attribute.Key]attribute.Value{
+			tracing.DbSystem:    attribute.StringValue("cassandra"),
+			tracing.DbOperation: attribute.StringValue("UPDATE"),
+			tracing.DbTable:     attribute.StringValue("users"),
+			tracing.DbStatement: attribute.StringValue("update users set name = ? where id = ?"),
+			tracing.DbAttempt:   attribute.IntValue(0),
+		}, codes.Unset},
+		{"query without statement", false, func(ctx context.Context, ses *cqltest.Session) error {
+			return c.ExecContext(ctx, ses, "update users set name = ? where id = ?", "Peter", "1")
+		}, "UPDATE users", map[attribute.Key]attribute.Value{
+			tracing.DbOperation: attribute.StringValue("UPDATE"),
+			tracing.DbStatement: {},
+		}, codes.Unset},
+		{"failed batch", true, func(ctx context.Context, ses *cqltest.Session) error {
+			ses.Fail("where id = '2'", failure)
+			stmts := []c.Statement{{Query: "delete from users where id = '1'"}, {Query: "delete from users where id = '2'"}}
+			_, err := c.ExecuteAllWithConfig(ctx, ses, gocql.LoggedBatch, c.BatchConfig{}, stmts, nil)
+			return err
+		}, "BATCH users", map[attribute.Key]attribute.Value{
+			tracing.DbOperation: attribute.StringValue("BATCH"),
+			tracing.DbBatchType: attribute.StringValue("logged"),
+			tracing.DbBatchSize: attribute.IntValue(2),
+			tracing.DbTable:     attribute.StringValue("users"),
+		}, codes.Error},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := &recorder{}
+			tracer := tracing.NewTracer(r)
+			tracer.Statement = tt.statement
+			ses := cqltest.NewSession()
+			if err := tt.execute(c.WithQueryOptions(context.Background(), c.WithObserver(tracer)), ses); (err != nil) != (tt.status == codes.Error) {
+				t.Fatalf("execute() error = %v", err)
+			}
+			if len(r.spans) != 1 {
+				t.Fatalf("recorded %d spans, want 1", len(r.spans))
+			}
+			s := r.spans[0]
+			if s.name != tt.span || s.status != tt.status {
+				t.Fatalf("span = %q, status %v, want %q, status %v", s.name, s.status, tt.span, tt.status)
+			}
+			for k, v := range tt.attrs {
+				if got, ok := s.attrs[k]; ok != (v.Type() != attribute.INVALID) || got != v {
+					t.Fatalf("attribute %s = %v, want %v", k, got.Emit(), v.Emit())
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,274 @@
+package cassandra
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/apache/cassandra-gocql-driver"
+)
+
+var (
+	timeType        = reflect.TypeOf(time.Time{})
+	marshalerType   = reflect.TypeOf((*gocql.Marshaler)(nil)).Elem()
+	unmarshalerType = reflect.TypeOf((*gocql.Unmarshaler)(nil)).Elem()
+)
+
+// IsUDTType checks if the type is a struct, or a slice, array, map or pointer of structs, which can be mapped to a user-defined type:
+// the struct must have mapped fields, and must not marshal itself.
+func IsUDTType(t reflect.Type) bool {
+	t = nestedType(t)
+	if t.Kind() != reflect.Struct || t == timeType {
+		return false
+	}
+	if reflect.PtrTo(t).Implements(marshalerType) || reflect.PtrTo(t).Implements(unmarshalerType) {
+		return false
+	}
+	for i := 0; i < t.NumField(); i++ {
+		if tag, ok := ParseTag(t.Field(i)); ok && !tag.Ignore {
+			return true
+		}
+	}
+	return false
+}
+func nestedType(t reflect.Type) reflect.Type {
+	for {
+		switch t.Kind() {
+		case reflect.Ptr, reflect.Map:
+			t = t.Elem()
+		case reflect.Slice, reflect.Array:
+			if t.Elem().Kind() == reflect.Uint8 {
+				return t
+			}
+			t = t.Elem()
+		default:
+			return t
+		}
+	}
+}
+
+type nestedField struct {
+	Index int
+	Name  string
+	Tuple bool
+}
+
+// getNestedFields returns the fields of the UDT by column name, or the fields of the tuple in order.
+func getNestedFields(t reflect.Type, tuple bool) []nestedField {
+	fields := make([]nestedField, 0)
+	if !tuple {
+		for _, f := range GetSchema(t).Columns {
+			fields = append(fields, nestedField{Index: f.Index, Name: f.Column, Tuple: f.Tuple})
+		}
+		if len(fields) > 0 {
+			return fields
+		}
+	}
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if len(field.PkgPath) > 0 || field.Name == "_" {
+			continue
+		}
+		tag, ok := ParseTag(field)
+		if ok && tag.Ignore {
+			continue
+		}
+		nf := nestedField{Index: i, Name: strings.ToLower(field.Name)}
+		if ok {
+			nf.Name = tag.Column
+			nf.Tuple = tag.Tuple
+		}
+		fields = append(fields, nf)
+	}
+	return fields
+}
+
+// ToCQL converts the nested structs of the value to map[string]interface{} for the UDT, or []interface{} for the tuple, which gocql can marshal.
+// The slices, arrays and maps of structs are converted element by element.
+func ToCQL(value interface{}, tuple bool) interface{} {
+	if value == nil {
+		return nil
+	}
+	v := toCQL(reflect.ValueOf(value), tuple)
+	if !v.IsValid() {
+		return nil
+	}
+	return v.Interface()
+}
+func toCQL(v reflect.Value, tuple bool) reflect.Value {
+	switch v.Kind() {
+	case reflect.Ptr, reflect.Interface:
+		if v.IsNil() {
+			return reflect.Value{}
+		}
+		return toCQL(v.Elem(), tuple)
+	case reflect.Struct:
+		if v.Type() == timeType || v.Type().Implements(marshalerType) || reflect.PtrTo(v.Type()).Implements(marshalerType) {
+			return v
+		}
+		fields := getNestedFields(v.Type(), tuple)
+		if tuple {
+			r := make([]interface{}, 0, len(fields))
+			for _, f := range fields {
+				r = append(r, valueOf(toCQL(v.Field(f.Index), f.Tuple)))
+			}
+			return reflect.ValueOf(r)
+		}
+		r := make(map[string]interface{}, len(fields))
+		for _, f := range fields {
+			r[f.Name] = valueOf(toCQL(v.Field(f.Index), f.Tuple))
+		}
+		return reflect.ValueOf(r)
+	case reflect.Slice, reflect.Array:
+		if v.Type().Elem().Kind() == reflect.Uint8 {
+			return v
+		}
+		if v.Kind() == reflect.Slice && v.IsNil() {
+			return reflect.Value{}
+		}
+		r := make([]interface{}, 0, v.Len())
+		for i := 0; i < v.Len(); i++ {
+			r = append(r, valueOf(toCQL(v.Index(i), tuple)))
+		}
+		return reflect.ValueOf(r)
+	case reflect.Map:
+		if v.IsNil() {
+			return reflect.Value{}
+		}
+		r := reflect.MakeMapWithSize(reflect.MapOf(v.Type().Key(), reflect.TypeOf((*interface{})(nil)).Elem()), v.Len())
+		iter := v.MapRange()
+		for iter.Next() {
+			e := toCQL(iter.Value(), tuple)
+			if !e.IsValid() {
+				e = reflect.Zero(r.Type().Elem())
+			}
+			r.SetMapIndex(iter.Key(), e)
+		}
+		return r
+	}
+	return v
+}
+func valueOf(v reflect.Value) interface{} {
+	if !v.IsValid() || !v.CanInterface() {
+		return nil
+	}
+	return v.Interface()
+}
+
+// FromCQL sets the value unmarshalled by gocql, such as map[string]interface{} of the UDT or []interface{} of the tuple, to the nested struct.
+func FromCQL(src interface{}, dst reflect.Value, tuple bool) error {
+	if src == nil {
+		dst.Set(reflect.Zero(dst.Type()))
+		return nil
+	}
+	sv := reflect.ValueOf(src)
+	for sv.Kind() == reflect.Ptr || sv.Kind() == reflect.Interface {
+		if sv.IsNil() {
+			dst.Set(reflect.Zero(dst.Type()))
+			return nil
+		}
+		sv = sv.Elem()
+	}
+	if (sv.Kind() == reflect.Slice || sv.Kind() == reflect.Map) && sv.IsNil() {
+		dst.Set(reflect.Zero(dst.Type()))
+		return nil
+	}
+	if dst.Kind() == reflect.Ptr {
+		v := reflect.New(dst.Type().Elem())
+		if err := FromCQL(sv.Interface(), v.Elem(), tuple); err != nil {
+			return err
+		}
+		dst.Set(v)
+		return nil
+	}
+	switch dst.Kind() {
+	case reflect.Struct:
+		if dst.Type() == timeType {
+			break
+		}
+		fields := getNestedFields(dst.Type(), tuple)
+		switch m := sv.Interface().(type) {
+		case map[string]interface{}:
+			for _, f := range fields {
+				if err := FromCQL(m[f.Name], dst.Field(f.Index), f.Tuple); err != nil {
+					return fmt.Errorf("%v: %w", f.Name, err)
+				}
+			}
+			return nil
+		case []interface{}:
+			for i, f := range fields {
+				if i >= len(m) {
+					break
+				}
+				if err := FromCQL(m[i], dst.Field(f.Index), f.Tuple); err != nil {
+					return fmt.Errorf("%v: %w", f.Name, err)
+				}
+			}
+			return nil
+		}
+	case reflect.Slice:
+		if sv.Kind() != reflect.Slice && sv.Kind() != reflect.Array || dst.Type().Elem().Kind() == reflect.Uint8 {
+			break
+		}
+		r := reflect.MakeSlice(dst.Type(), sv.Len(), sv.Len())
+		for i := 0; i < sv.Len(); i++ {
+			if err := FromCQL(sv.Index(i).Interface(), r.Index(i), tuple); err != nil {
+				return err
+			}
+		}
+		dst.Set(r)
+		return nil
+	case reflect.Map:
+		if sv.Kind() != reflect.Map {
+			break
+		}
+		r := reflect.MakeMapWithSize(dst.Type(), sv.Len())
+		iter := sv.MapRange()
+		for iter.Next() {
+			k := reflect.New(dst.Type().Key()).Elem()
+			if err := FromCQL(iter.Key().Interface(), k, false); err != nil {
+				return err
+			}
+			e := reflect.New(dst.Type().Elem()).Elem()
+			if err := FromCQL(iter.Value().Interface(), e, tuple); err != nil {
+				return err
+			}
+			r.SetMapIndex(k, e)
+		}
+		dst.Set(r)
+		return nil
+	}
+	if sv.Type().AssignableTo(dst.Type()) {
+		dst.Set(sv)
+		return nil
+	}
+	if sv.Type().ConvertibleTo(dst.Type()) {
+		dst.Set(sv.Convert(dst.Type()))
+		return nil
+	}
+	return fmt.Errorf("cannot set %v to %v", sv.Type(), dst.Type())
+}
+
+// nestedScanner scans the UDT or tuple column to the nested struct of the model.
+type nestedScanner struct {
+	dst   reflect.Value
+	tuple bool
+}
+
+func (s *nestedScanner) UnmarshalCQL(info gocql.TypeInfo, data []byte) error {
+	if data == nil {
+		s.dst.Set(reflect.Zero(s.dst.Type()))
+		return nil
+	}
+	v := info.New()
+	if err := gocql.Unmarshal(info, data, v); err != nil {
+		return err
+	}
+	return FromCQL(reflect.ValueOf(v).Elem().Interface(), s.dst, s.tuple)
+}
+
+// SetValue sets the decoded value, such as the map[string]interface{} of the UDT, for the sessions which do not marshal the values.
+func (s *nestedScanner) SetValue(v interface{}) error {
+	return FromCQL(v, s.dst, s.tuple)
+}
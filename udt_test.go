@@ -0,0 +1,111 @@
+package cassandra_test
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	c "github.com/core-go/cassandra"
+	"github.com/core-go/cassandra/cqltest"
+)
+
+type address struct {
+	Street string `cql:"street"`
+	City   string `cql:"city"`
+	Geo    *point `cql:"geo,type=frozen<tuple<double,double>>"`
+}
+type point struct {
+	Lat float64
+	Lng float64
+}
+type customer struct {
+	Id        string             `cql:"id,partition_key"`
+	Home      address            `cql:"home"`
+	Work      *address           `cql:"work"`
+	Location  point              `cql:"location,type=tuple<double,double>"`
+	Addresses []address          `cql:"addresses"`
+	ByName    map[string]address `cql:"by_name"`
+}
+
+func TestUDTRoundTrip(t *testing.T) {
+	home := address{Street: "1 Main St", City: "Springfield", Geo: &point{Lat: 1.5, Lng: 2.5}}
+	tests := []struct {
+		name  string
+		value interface{}
+		tuple bool
+		cql   interface{}
+	}{
+		{"nested udt", home, false, map[string]interface{}{"street": "1 Main St", "city": "Springfield", "geo": []interface{}{1.5, 2.5}}},
+		{"nil nested tuple", address{City: "Springfield"}, false, map[string]interface{}{"street": "", "city": "Springfield", "geo": nil}},
+		{"tuple", point{Lat: 1, Lng: 2}, true, []interface{}{1.0, 2.0}},
+		{"pointer", &home, false, map[string]interface{}{"street": "1 Main St", "city": "Springfield", "geo": []interface{}{1.5, 2.5}}},
+		{"nil pointer", (*address)(nil), false, nil},
+		{"slice of udt", []address{home, {City: "Shelbyville"}}, false, []interface{}{
+			map[string]interface{}{"street": "1 Main St", "city": "Springfield", "geo": []interface{}{1.5, 2.5}},
+			map[string]interface{}{"street": "", "city": "Shelbyville", "geo": nil},
+		}},
+		{"nil slice", []address(nil), false, nil},
+		{"nil map", map[string]address(nil), false, nil},
+		{"map of udt", map[string]address{"home": home}, false, map[string]interface{}{
+			"home": map[string]interface{}{"street": "1 Main St", "city": "Springfield", "geo": []interface{}{1.5, 2.5}},
+		}},
+		{"map of pointers", map[string]*address{"home": &home, "none": nil}, false, map[string]interface{}{
+			"home": map[string]interface{}{"street": "1 Main St", "city": "Springfield", "geo": []interface{}{1.5, 2.5}},
+			"none": nil,
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cql := c.ToCQL(tt.value, tt.tuple)
+			if !reflect.DeepEqual(cql, tt.cql) {
+				t.Fatalf("ToCQL() = %#v, want %#v", cql, tt.cql)
+			}
+			dst := reflect.New(reflect.TypeOf(tt.value)).Elem()
+			if err := c.FromCQL(cql, dst, tt.tuple); err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(dst.Interface(), tt.value) {
+				t.Fatalf("FromCQL() = %#v, want %#v", dst.Interface(), tt.value)
+			}
+		})
+	}
+}
+
+func TestUDTWriteAndLoad(t *testing.T) {
+	home := address{Street: "1 Main St", City: "Springfield", Geo: &point{Lat: 1.5, Lng: 2.5}}
+	tests := []struct {
+		name  string
+		model customer
+	}{
+		{"all nested values", customer{Id: "1", Home: home, Work: &address{City: "Shelbyville"}, Location: point{Lat: 1, Lng: 2},
+			Addresses: []address{home}, ByName: map[string]address{"home": home}}},
+		{"nil values", customer{Id: "2", Home: home}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ses := cqltest.NewSession()
+			writer, err := c.NewWriterWithProvider(c.NewOptionsProvider(ses.Provider(), c.WithPrepared(true)), "customers", reflect.TypeOf(customer{}))
+			if err != nil {
+				t.Fatal(err)
+			}
+			model := tt.model
+			if _, err := writer.Save(context.Background(), &model); err != nil {
+				t.Fatal(err)
+			}
+			// the bound values are the ones which gocql marshals, and also the ones which it unmarshals
+			row := ses.Executed()[0].Params
+			ses.Rows("from customers", []string{"id", "home", "work", "location", "addresses", "by_name"}, row)
+			loader, err := c.NewLoaderWithProvider(ses.Provider(), "customers", reflect.TypeOf(customer{}))
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got customer
+			if ok, err := loader.LoadAndDecode(context.Background(), model.Id, &got); !ok || err != nil {
+				t.Fatalf("LoadAndDecode() = %v, %v", ok, err)
+			}
+			if !reflect.DeepEqual(got, tt.model) {
+				t.Fatalf("LoadAndDecode() = %#v, want %#v", got, tt.model)
+			}
+		})
+	}
+}
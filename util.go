@@ -23,21 +23,41 @@ const (
 )
 
 type FieldDB struct {
-	JSON   string
-	Column string
-	Field  string
-	Index  int
-	Key    bool
-	Update bool
-	Insert bool
-	Scale  int8
+	JSON       string
+	Column     string
+	Field      string
+	Index      int
+	Key        bool
+	Partition  bool
+	Clustering bool
+	Order      string
+	Static     bool
+	Counter    bool
+	OmitEmpty  bool
+	UDT        bool
+	Tuple      bool
+	Type       string
+	Update     bool
+	Insert     bool
+	Scale      int8
 }
 type Schema struct {
-	SKeys    []string
-	SColumns []string
-	Keys     []*FieldDB
-	Columns  []*FieldDB
-	Fields   map[string]*FieldDB
+	SKeys          []string
+	SColumns       []string
+	Keys           []*FieldDB
+	PartitionKeys  []*FieldDB
+	ClusteringKeys []*FieldDB
+	Counters       []*FieldDB
+	Columns        []*FieldDB
+	Fields         map[string]*FieldDB
+	Nested         map[int]*FieldDB
+	TTL            int
+}
+
+// IsMetadataColumn checks if the column is ttl(col) or writetime(col), which can be loaded, but not inserted or updated.
+func IsMetadataColumn(column string) bool {
+	c := strings.ToLower(strings.TrimSpace(column))
+	return (strings.HasPrefix(c, "ttl(") || strings.HasPrefix(c, "writetime(")) && strings.HasSuffix(c, ")")
 }
 
 func BuildFieldsBySchema(schema *Schema) string {
@@ -59,34 +79,7 @@ func BuildFields(modelType reflect.Type) string {
 	return strings.Join(columns, ",")
 }
 func GetFields(modelType reflect.Type) []string {
-	m := modelType
-	if m.Kind() == reflect.Ptr {
-		m = m.Elem()
-	}
-	numField := m.NumField()
-	columns := make([]string, 0)
-	for idx := 0; idx < numField; idx++ {
-		field := m.Field(idx)
-		tag, _ := field.Tag.Lookup("gorm")
-		if !strings.Contains(tag, IgnoreReadWrite) {
-			if has := strings.Contains(tag, "column"); has {
-				json := field.Name
-				col := json
-				str1 := strings.Split(tag, ";")
-				num := len(str1)
-				for i := 0; i < num; i++ {
-					str2 := strings.Split(str1[i], ":")
-					for j := 0; j < len(str2); j++ {
-						if str2[j] == "column" {
-							col = str2[j+1]
-							columns = append(columns, col)
-						}
-					}
-				}
-			}
-		}
-	}
-	return columns
+	return append([]string{}, GetSchema(modelType).SColumns...)
 }
 func BuildQuery(table string, modelType reflect.Type) string {
 	columns := GetFields(modelType)
@@ -102,63 +95,104 @@ func CreateSchema(modelType reflect.Type) *Schema {
 	skeys := make([]string, 0)
 	columns := make([]*FieldDB, 0)
 	keys := make([]*FieldDB, 0)
+	counters := make([]*FieldDB, 0)
+	nested := make(map[int]*FieldDB)
 	schema := make(map[string]*FieldDB, 0)
+	ttl := 0
 	for idx := 0; idx < numField; idx++ {
 		field := m.Field(idx)
-		tag, _ := field.Tag.Lookup("gorm")
-		if !strings.Contains(tag, IgnoreReadWrite) {
-			update := !strings.Contains(tag, "update:false")
-			insert := !strings.Contains(tag, "insert:false")
-			if has := strings.Contains(tag, "column"); has {
-				json := field.Name
-				col := json
-				str1 := strings.Split(tag, ";")
-				num := len(str1)
-				for i := 0; i < num; i++ {
-					str2 := strings.Split(str1[i], ":")
-					for j := 0; j < len(str2); j++ {
-						if str2[j] == "column" {
-							isKey := strings.Contains(tag, "primary_key")
-							col = str2[j+1]
-							scolumns = append(scolumns, col)
-							jTag, jOk := field.Tag.Lookup("json")
-							if jOk {
-								tagJsons := strings.Split(jTag, ",")
-								json = tagJsons[0]
-							}
-							f := &FieldDB{
-								JSON:   json,
-								Column: col,
-								Index:  idx,
-								Scale:  -1,
-								Key:    isKey,
-								Update: update,
-								Insert: insert,
-							}
-							if isKey {
-								skeys = append(skeys, col)
-								keys = append(keys, f)
-							}
-							tScale, sOk := field.Tag.Lookup("scale")
-							if sOk {
-								scale, err := strconv.Atoi(tScale)
-								if err == nil {
-									f.Scale = int8(scale)
-								}
-							}
-							columns = append(columns, f)
-							schema[col] = f
-						}
-					}
+		tag, ok := ParseTag(field)
+		if !ok {
+			if tTTL, tOk := field.Tag.Lookup("ttl"); tOk {
+				if v, err := strconv.Atoi(tTTL); err == nil {
+					ttl = v
 				}
 			}
+			continue
 		}
+		if tag.TTL > 0 {
+			ttl = tag.TTL
+		}
+		if tag.Ignore || field.Name == "_" || len(tag.Column) == 0 {
+			continue
+		}
+		col := tag.Column
+		json := field.Name
+		jTag, jOk := field.Tag.Lookup("json")
+		if jOk {
+			tagJsons := strings.Split(jTag, ",")
+			if len(tagJsons[0]) > 0 {
+				json = tagJsons[0]
+			}
+		}
+		writable := !tag.ReadOnly && !IsMetadataColumn(col)
+		f := &FieldDB{
+			JSON:       json,
+			Column:     col,
+			Field:      field.Name,
+			Index:      idx,
+			Scale:      tag.Scale,
+			Key:        tag.Key,
+			Partition:  tag.Partition,
+			Clustering: tag.Clustering,
+			Order:      tag.Order,
+			Static:     tag.Static,
+			Counter:    tag.Counter,
+			OmitEmpty:  tag.OmitEmpty,
+			Tuple:      tag.Tuple,
+			UDT:        tag.UDT || (!tag.Tuple && IsUDTType(field.Type)),
+			Type:       tag.Type,
+			Update:     writable && !tag.NoUpdate,
+			Insert:     writable && !tag.NoInsert,
+		}
+		scolumns = append(scolumns, col)
+		if f.Key {
+			skeys = append(skeys, col)
+			keys = append(keys, f)
+		}
+		if f.Counter {
+			counters = append(counters, f)
+		}
+		if f.UDT || f.Tuple {
+			nested[idx] = f
+		}
+		columns = append(columns, f)
+		schema[col] = f
 	}
-	s := &Schema{SColumns: scolumns, SKeys: skeys, Columns: columns, Keys: keys, Fields: schema}
+	partitionKeys, clusteringKeys := splitKeys(keys)
+	s := &Schema{SColumns: scolumns, SKeys: skeys, Columns: columns, Keys: keys, PartitionKeys: partitionKeys, ClusteringKeys: clusteringKeys, Counters: counters, Fields: schema, Nested: nested, TTL: ttl}
 	return s
 }
+
+// splitKeys works as "primary key (a, b, c)" for the keys tagged by primary_key only: the first key is the partition key, the others are clustering keys.
+func splitKeys(keys []*FieldDB) ([]*FieldDB, []*FieldDB) {
+	partitionKeys := make([]*FieldDB, 0)
+	clusteringKeys := make([]*FieldDB, 0)
+	hasPartition := false
+	for _, k := range keys {
+		if k.Partition {
+			hasPartition = true
+		}
+	}
+	for _, k := range keys {
+		if !hasPartition && !k.Clustering {
+			k.Partition = true
+			hasPartition = true
+		}
+		if k.Partition {
+			partitionKeys = append(partitionKeys, k)
+		} else {
+			if !k.Clustering {
+				k.Clustering = true
+				k.Order = asc
+			}
+			clusteringKeys = append(clusteringKeys, k)
+		}
+	}
+	return partitionKeys, clusteringKeys
+}
 func MakeSchema(modelType reflect.Type) ([]*FieldDB, []*FieldDB) {
-	m := CreateSchema(modelType)
+	m := GetSchema(modelType)
 	return m.Columns, m.Keys
 }
 func GetDBValue(v interface{}, scale int8) (string, bool) {
@@ -250,7 +284,6 @@ func GetDBValue(v interface{}, scale int8) (string, bool) {
 			return "", false
 		}
 	}
-	return "", false
 }
 func ParseDates(args []interface{}, dates []int) []interface{} {
 	if args == nil || len(args) == 0 {
@@ -2,6 +2,8 @@ package cassandra
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"github.com/apache/cassandra-gocql-driver"
 	"reflect"
@@ -14,7 +16,7 @@ func Init(modelType reflect.Type) (map[string]int, *Schema, map[string]string, [
 	if err != nil {
 		return nil, nil, nil, nil, nil, "", err
 	}
-	schema := CreateSchema(modelType)
+	schema := GetSchema(modelType)
 	fields := BuildFieldsBySchema(schema)
 	jsonColumnMap := MakeJsonColumnMap(modelType)
 	keys, arr := FindPrimaryKeys(modelType)
@@ -28,13 +30,18 @@ type Writer struct {
 	versionField   string
 	versionIndex   int
 	versionDBField string
-	schema         *Schema
 }
 
 func NewWriter(db *gocql.ClusterConfig, tableName string, modelType reflect.Type, options ...Mapper) (*Writer, error) {
 	return NewWriterWithVersion(db, tableName, modelType, "", options...)
 }
 func NewWriterWithVersion(db *gocql.ClusterConfig, tableName string, modelType reflect.Type, versionField string, options ...Mapper) (*Writer, error) {
+	return NewWriterWithVersionAndProvider(GetSessionProvider(db), tableName, modelType, versionField, options...)
+}
+func NewWriterWithProvider(db SessionProvider, tableName string, modelType reflect.Type, options ...Mapper) (*Writer, error) {
+	return NewWriterWithVersionAndProvider(db, tableName, modelType, "", options...)
+}
+func NewWriterWithVersionAndProvider(db SessionProvider, tableName string, modelType reflect.Type, versionField string, options ...Mapper) (*Writer, error) {
 	var mapper Mapper
 	if len(options) > 0 {
 		mapper = options[0]
@@ -42,14 +49,13 @@ func NewWriterWithVersion(db *gocql.ClusterConfig, tableName string, modelType r
 	var loader *Loader
 	var err error
 	if mapper != nil {
-		loader, err = NewLoader(db, tableName, modelType, mapper.DbToModel)
+		loader, err = NewLoaderWithProvider(db, tableName, modelType, mapper.DbToModel)
 	} else {
-		loader, err = NewLoader(db, tableName, modelType, nil)
+		loader, err = NewLoaderWithProvider(db, tableName, modelType, nil)
 	}
 	if err != nil {
 		return nil, err
 	}
-	schema := CreateSchema(modelType)
 	jsonColumnMap := MakeJsonColumnMap(modelType)
 	if len(versionField) > 0 {
 		index := FindFieldIndex(modelType, versionField)
@@ -58,10 +64,10 @@ func NewWriterWithVersion(db *gocql.ClusterConfig, tableName string, modelType r
 			if !exist {
 				dbFieldName = strings.ToLower(versionField)
 			}
-			return &Writer{Loader: loader, schema: schema, Mapper: mapper, jsonColumnMap: jsonColumnMap, versionField: versionField, versionIndex: index, versionDBField: dbFieldName}, nil
+			return &Writer{Loader: loader, Mapper: mapper, jsonColumnMap: jsonColumnMap, versionField: versionField, versionIndex: index, versionDBField: dbFieldName}, nil
 		}
 	}
-	return &Writer{Loader: loader, schema: schema, Mapper: mapper, jsonColumnMap: jsonColumnMap, versionField: versionField, versionIndex: -1}, nil
+	return &Writer{Loader: loader, Mapper: mapper, jsonColumnMap: jsonColumnMap, versionField: versionField, versionIndex: -1}, nil
 }
 func (s *Writer) Insert(ctx context.Context, model interface{}) (int64, error) {
 	var m interface{}
@@ -74,13 +80,20 @@ func (s *Writer) Insert(ctx context.Context, model interface{}) (int64, error) {
 	} else {
 		m = model
 	}
-	query, values := BuildToInsertWithVersion(s.table, m, s.versionIndex, false, s.schema)
-	ses, err := s.DB.CreateSession()
+	ctx = WithDefaultOptions(ctx, s.Options)
+	query, values := BuildToInsertWithUsing(s.table, m, s.versionIndex, false, GetUsing(ctx, s.schema), s.schema)
+	if len(query) == 0 {
+		// the model of a counter table has no delta, BuildToIncrement returns no query
+		return 0, nil
+	}
+	ses, err := s.DB.Session()
 	if err != nil {
 		return -1, err
 	}
-	defer ses.Close()
-	er2 := Exec(ses, query, values...)
+	if s.versionIndex >= 0 {
+		return ExecuteWithVersion(ctx, ses, query, values...)
+	}
+	er2 := ExecContext(ctx, ses, query, values...)
 	if er2 == nil {
 		return 1, er2
 	}
@@ -97,13 +110,24 @@ func (s *Writer) Update(ctx context.Context, model interface{}) (int64, error) {
 	} else {
 		m = model
 	}
-	query, values := BuildToUpdateWithVersion(s.table, m, s.versionIndex, s.schema)
-	ses, err := s.DB.CreateSession()
+	if s.versionIndex >= 0 {
+		if _, err := GetModelVersion(m, s.versionIndex); err != nil {
+			return -1, err
+		}
+	}
+	ctx = WithDefaultOptions(ctx, s.Options)
+	query, values := BuildToUpdateWithUsing(s.table, m, s.versionIndex, GetUsing(ctx, s.schema), s.schema)
+	if len(query) == 0 {
+		return 0, nil
+	}
+	ses, err := s.DB.Session()
 	if err != nil {
 		return -1, err
 	}
-	defer ses.Close()
-	er2 := Exec(ses, query, values...)
+	if s.versionIndex >= 0 {
+		return ExecuteWithVersion(ctx, ses, query, values...)
+	}
+	er2 := ExecContext(ctx, ses, query, values...)
 	if er2 == nil {
 		return 1, er2
 	}
@@ -120,18 +144,23 @@ func (s *Writer) Save(ctx context.Context, model interface{}) (int64, error) {
 	} else {
 		m = model
 	}
-	query, values := BuildToSave(s.table, m, s.schema)
-	ses, err := s.DB.CreateSession()
+	ctx = WithDefaultOptions(ctx, s.Options)
+	query, values := BuildToInsertWithUsing(s.table, m, -1, true, GetUsing(ctx, s.schema), s.schema)
+	if len(query) == 0 {
+		return 0, nil
+	}
+	ses, err := s.DB.Session()
 	if err != nil {
 		return -1, err
 	}
-	defer ses.Close()
-	er2 := Exec(ses, query, values...)
+	er2 := ExecContext(ctx, ses, query, values...)
 	if er2 == nil {
 		return 1, er2
 	}
 	return 0, er2
 }
+
+// Patch updates the columns of the model. The list, set and map columns can be patched by the operators $append, $prepend, $remove, $put and $delete, see CollectionOp.
 func (s *Writer) Patch(ctx context.Context, model map[string]interface{}) (int64, error) {
 	if s.Mapper != nil {
 		_, err := s.Mapper.ModelToDb(ctx, &model)
@@ -141,17 +170,13 @@ func (s *Writer) Patch(ctx context.Context, model map[string]interface{}) (int64
 	}
 	MapToDB(&model, s.modelType)
 	dbColumnMap := JSONToColumns(model, s.jsonColumnMap)
-	query, values := BuildToPatchWithVersion(s.table, dbColumnMap, s.schema.SKeys, s.versionDBField)
-	ses, err := s.DB.CreateSession()
+	ctx = WithDefaultOptions(ctx, s.Options)
+	stmts := BuildToPatchStatements(s.table, dbColumnMap, s.schema.SKeys, s.versionDBField, GetUsing(ctx, s.schema))
+	ses, err := s.DB.Session()
 	if err != nil {
 		return -1, err
 	}
-	defer ses.Close()
-	er2 := Exec(ses, query, values...)
-	if er2 == nil {
-		return 1, er2
-	}
-	return 0, er2
+	return ExecutePatch(ctx, ses, HasVersion(dbColumnMap, s.versionDBField), stmts...)
 }
 func MapToDB(model *map[string]interface{}, modelType reflect.Type) {
 	for colName, value := range *model {
@@ -171,15 +196,42 @@ func MapToDB(model *map[string]interface{}, modelType reflect.Type) {
 		(*model)[colName] = value
 	}
 }
+
+// Delete deletes the row of the id, which is the value of the single key, or a map or a struct of all key parts.
 func (s *Writer) Delete(ctx context.Context, id interface{}) (int64, error) {
-	query := BuildQueryById(id, s.modelType, s.keys[0])
-	sql, values := BuildToDelete(s.table, query)
-	ses, err := s.DB.CreateSession()
+	return s.DeleteColumns(ctx, id)
+}
+
+// DeleteColumns sets the columns of the row to null, by json names or column names. If no column is passed, the row is deleted.
+func (s *Writer) DeleteColumns(ctx context.Context, id interface{}, columns ...string) (int64, error) {
+	ctx = WithDefaultOptions(ctx, s.Options)
+	sql, values, err := BuildToDeleteById(s.table, id, s.schema, GetUsing(ctx, s.schema), columns...)
 	if err != nil {
 		return -1, err
 	}
-	defer ses.Close()
-	er2 := Exec(ses, sql, values...)
+	return s.execDelete(ctx, sql, values)
+}
+
+// DeleteByPartition deletes all rows of the partition. If the partition key has one column, partition can be the value, otherwise it must be a map or a struct.
+func (s *Writer) DeleteByPartition(ctx context.Context, partition interface{}) (int64, error) {
+	return s.DeleteRange(ctx, partition)
+}
+
+// DeleteRange deletes the rows of the partition in the range of the clustering columns, such as Condition{"created", ">=", from}.
+func (s *Writer) DeleteRange(ctx context.Context, partition interface{}, conditions ...Condition) (int64, error) {
+	ctx = WithDefaultOptions(ctx, s.Options)
+	sql, values, err := BuildToDeleteByPartition(s.table, partition, s.schema, GetUsing(ctx, s.schema), conditions...)
+	if err != nil {
+		return -1, err
+	}
+	return s.execDelete(ctx, sql, values)
+}
+func (s *Writer) execDelete(ctx context.Context, sql string, values []interface{}) (int64, error) {
+	ses, err := s.DB.Session()
+	if err != nil {
+		return -1, err
+	}
+	er2 := ExecContext(ctx, ses, sql, values...)
 	if er2 == nil {
 		return 1, er2
 	}
@@ -196,28 +248,23 @@ func BuildQueryById(id interface{}, modelType reflect.Type, idName string) (quer
 	return map[string]interface{}{columnName: id}
 }
 func GetColumnName(modelType reflect.Type, jsonName string) (col string, colExist bool) {
+	for _, f := range GetSchema(modelType).Columns {
+		if f.JSON == jsonName {
+			return f.Column, true
+		}
+	}
 	index := GetIndexByTag("json", jsonName, modelType)
 	if index == -1 {
 		return jsonName, false
 	}
 	field := modelType.Field(index)
-	ormTag, ok2 := field.Tag.Lookup("gorm")
-	if !ok2 {
-		return "", true
-	}
-	if has := strings.Contains(ormTag, "column"); has {
-		str1 := strings.Split(ormTag, ";")
-		num := len(str1)
-		for i := 0; i < num; i++ {
-			str2 := strings.Split(str1[i], ":")
-			for j := 0; j < len(str2); j++ {
-				if str2[j] == "column" {
-					return str2[j+1], true
-				}
-			}
-		}
+	if _, ok := field.Tag.Lookup("cql"); ok {
+		return jsonName, false
+	}
+	if _, ok := field.Tag.Lookup("gorm"); ok {
+		return jsonName, false
 	}
-	return jsonName, false
+	return "", true
 }
 func GetIndexByTag(tag, key string, modelType reflect.Type) (index int) {
 	for i := 0; i < modelType.NumField(); i++ {
@@ -230,19 +277,9 @@ func GetIndexByTag(tag, key string, modelType reflect.Type) (index int) {
 	return -1
 }
 func MakeJsonColumnMap(modelType reflect.Type) map[string]string {
-	numField := modelType.NumField()
 	mapJsonColumn := make(map[string]string)
-	for i := 0; i < numField; i++ {
-		field := modelType.Field(i)
-		ormTag := field.Tag.Get("gorm")
-		column, ok := findTag(ormTag, "column")
-		if ok {
-			tag1, ok1 := field.Tag.Lookup("json")
-			tagJsons := strings.Split(tag1, ",")
-			if ok1 && len(tagJsons) > 0 {
-				mapJsonColumn[tagJsons[0]] = column
-			}
-		}
+	for _, f := range GetSchema(modelType).Columns {
+		mapJsonColumn[f.JSON] = f.Column
 	}
 	return mapJsonColumn
 }
@@ -257,25 +294,9 @@ func FindFieldIndex(modelType reflect.Type, fieldName string) int {
 	return -1
 }
 func GetFieldByIndex(ModelType reflect.Type, index int) (json string, col string, colExist bool) {
-	fields := ModelType.Field(index)
-	tag, _ := fields.Tag.Lookup("gorm")
-
-	if has := strings.Contains(tag, "column"); has {
-		str1 := strings.Split(tag, ";")
-		num := len(str1)
-		json = fields.Name
-		for i := 0; i < num; i++ {
-			str2 := strings.Split(str1[i], ":")
-			for j := 0; j < len(str2); j++ {
-				if str2[j] == "column" {
-					jTag, jOk := fields.Tag.Lookup("json")
-					if jOk {
-						tagJsons := strings.Split(jTag, ",")
-						json = tagJsons[0]
-					}
-					return json, str2[j+1], true
-				}
-			}
+	for _, f := range GetSchema(ModelType).Columns {
+		if f.Index == index {
+			return f.JSON, f.Column, true
 		}
 	}
 	return "", "", false
@@ -316,60 +337,131 @@ func Contains(s []string, str string) bool {
 
 	return false
 }
+
+// HasVersion returns true if the patch has a valid value of the version column, so that it is executed as a lightweight transaction.
+func HasVersion(model map[string]interface{}, version string) bool {
+	if len(version) == 0 {
+		return false
+	}
+	v, ok := model[version]
+	if !ok {
+		return false
+	}
+	_, ok = GetVersion(v)
+	return ok
+}
+
+// GetVersion returns the value of a version of any integer or float type, a json.Number or a numeric string.
+func GetVersion(value interface{}) (int64, bool) {
+	if n, ok := value.(json.Number); ok {
+		i, err := n.Int64()
+		return i, err == nil
+	}
+	v := reflect.ValueOf(value)
+	for v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			return 0, false
+		}
+		v = v.Elem()
+	}
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return v.Int(), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return int64(v.Uint()), true
+	case reflect.Float32, reflect.Float64:
+		return int64(v.Float()), true
+	case reflect.String:
+		i, err := strconv.ParseInt(v.String(), 10, 64)
+		return i, err == nil
+	}
+	return 0, false
+}
+
+// ErrInvalidVersion is returned by the writers with a version field, when the version of the model is nil or not a number.
+var ErrInvalidVersion = errors.New("version is nil or not a number")
+
+// GetModelVersion returns the version of the field of the model, as GetVersion. It fails with ErrInvalidVersion if the version is nil or not a number.
+func GetModelVersion(model interface{}, versionIndex int) (int64, error) {
+	mv := reflect.Indirect(reflect.ValueOf(model))
+	if version, ok := GetVersion(mv.Field(versionIndex).Interface()); ok {
+		return version, nil
+	}
+	return 0, fmt.Errorf("%w: %s of %s", ErrInvalidVersion, mv.Type().Field(versionIndex).Name, mv.Type())
+}
 func BuildToPatch(table string, model map[string]interface{}, keyColumns []string) (string, []interface{}) {
 	return BuildToPatchWithVersion(table, model, keyColumns, "")
 }
 func BuildToPatchWithVersion(table string, model map[string]interface{}, keyColumns []string, version string) (string, []interface{}) { //version column name db
+	return BuildToPatchWithUsing(table, model, keyColumns, version, nil)
+}
+func BuildToPatchWithUsing(table string, model map[string]interface{}, keyColumns []string, version string, using *Using) (string, []interface{}) {
 	values := make([]string, 0)
 	where := make([]string, 0)
 	args := make([]interface{}, 0)
+	whereArgs := make([]interface{}, 0)
+	prepared := using != nil && using.Prepared
 	i := 1
-	for col, v := range model {
+	for _, col := range sortedKeys(model) {
+		v := model[col]
 		if !Contains(keyColumns, col) && col != version {
-			if v == nil {
+			if op, ok := GetCollectionOp(v); ok {
+				opValues, opArgs := buildCollectionOp(col, op)
+				values = append(values, opValues...)
+				args = append(args, opArgs...)
+				i = i + len(opArgs)
+			} else if v == nil && prepared {
+				values = append(values, col+"="+BuildParam(i))
+				i = i + 1
+				args = append(args, nil)
+			} else if v == nil {
 				values = append(values, col+"=null")
 			} else {
-				v2, ok2 := GetDBValue(v, -1)
+				v2, ok2 := GetParamValue(v, -1, prepared)
 				if ok2 {
 					values = append(values, col+"="+v2)
 				} else {
 					values = append(values, col+"="+BuildParam(i))
 					i = i + 1
-					args = append(args, v)
+					args = append(args, BindValue(v, -1))
+				}
+			}
+		}
+	}
+	cas := ""
+	var casArgs []interface{}
+	if len(version) > 0 {
+		v0, ok0 := model[version]
+		if ok0 {
+			current, ok1 := GetVersion(v0)
+			if ok1 {
+				if prepared {
+					values = append(values, version+"="+BuildParam(i))
+					i = i + 1
+					args = append(args, current+1)
+				} else {
+					values = append(values, version+"="+strconv.FormatInt(current+1, 10))
 				}
+				cas = " if " + version + "=" + BuildParam(0)
+				casArgs = append(casArgs, current)
 			}
 		}
 	}
 	for _, col := range keyColumns {
 		v0, ok0 := model[col]
 		if ok0 {
-			v, ok1 := GetDBValue(v0, -1)
+			v, ok1 := GetParamValue(v0, -1, prepared)
 			if ok1 {
 				where = append(where, col+"="+v)
 			} else {
 				where = append(where, col+"="+BuildParam(i))
 				i = i + 1
-				args = append(args, v0)
-			}
-		}
-	}
-	if len(version) > 0 {
-		v0, ok0 := model[version]
-		if ok0 {
-			switch v4 := v0.(type) {
-			case int:
-				values = append(values, version+"="+strconv.Itoa(v4+1))
-				where = append(where, version+"="+strconv.Itoa(v4))
-			case int32:
-				v5 := int64(v4)
-				values = append(values, version+"="+strconv.FormatInt(v5+1, 10))
-				where = append(where, version+"="+strconv.FormatInt(v5, 10))
-			case int64:
-				values = append(values, version+"="+strconv.FormatInt(v4+1, 10))
-				where = append(where, version+"="+strconv.FormatInt(v4, 10))
+				whereArgs = append(whereArgs, BindValue(v0, -1))
 			}
 		}
 	}
-	query := fmt.Sprintf("update %v set %v where %v", table, strings.Join(values, ","), strings.Join(where, " and "))
-	return query, args
+	u, uargs := BuildUsing(using)
+	query := fmt.Sprintf("update %v%v set %v where %v%v", table, u, strings.Join(values, ","), strings.Join(where, " and "), cas)
+	args = append(append(args, whereArgs...), casArgs...)
+	return query, append(uargs, args...)
 }
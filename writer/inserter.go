@@ -9,7 +9,7 @@ import (
 )
 
 type Inserter[T any] struct {
-	db           *gocql.ClusterConfig
+	db           c.SessionProvider
 	table        string
 	Map          func(T)
 	schema       *c.Schema
@@ -17,6 +17,9 @@ type Inserter[T any] struct {
 }
 
 func NewInserterWithMap[T any](db *gocql.ClusterConfig, table string, mp func(T), options ...int) *Inserter[T] {
+	return NewInserterWithProvider[T](c.GetSessionProvider(db), table, mp, options...)
+}
+func NewInserterWithProvider[T any](db c.SessionProvider, table string, mp func(T), options ...int) *Inserter[T] {
 	versionIndex := -1
 	if len(options) > 0 && options[0] >= 0 {
 		versionIndex = options[0]
@@ -26,7 +29,7 @@ func NewInserterWithMap[T any](db *gocql.ClusterConfig, table string, mp func(T)
 	if modelType.Kind() == reflect.Ptr {
 		modelType = modelType.Elem()
 	}
-	schema := c.CreateSchema(modelType)
+	schema := c.GetSchema(modelType)
 	return &Inserter[T]{db: db, table: table, Map: mp, schema: schema, VersionIndex: versionIndex}
 }
 
@@ -42,10 +45,9 @@ func (w *Inserter[T]) Write(ctx context.Context, model T) error {
 	if w.Map != nil {
 		w.Map(model)
 	}
-	session, er0 := w.db.CreateSession()
+	session, er0 := w.db.Session()
 	if er0 != nil {
 		return er0
 	}
-	defer session.Close()
-	return c.InsertWithVersion(session, w.table, model, w.VersionIndex, w.schema)
+	return c.InsertWithVersionContext(ctx, session, w.table, model, w.VersionIndex, w.schema)
 }
@@ -9,7 +9,7 @@ import (
 )
 
 type Updater[T any] struct {
-	db           *gocql.ClusterConfig
+	db           c.SessionProvider
 	table        string
 	Map          func(T)
 	VersionIndex int
@@ -24,6 +24,9 @@ func NewUpdater[T any](db *gocql.ClusterConfig, table string, options ...func(T)
 	return NewUpdaterWithVersion[T](db, table, mp)
 }
 func NewUpdaterWithVersion[T any](db *gocql.ClusterConfig, table string, mp func(T), options ...int) *Updater[T] {
+	return NewUpdaterWithProvider[T](c.GetSessionProvider(db), table, mp, options...)
+}
+func NewUpdaterWithProvider[T any](db c.SessionProvider, table string, mp func(T), options ...int) *Updater[T] {
 	version := -1
 	if len(options) > 0 && options[0] >= 0 {
 		version = options[0]
@@ -33,7 +36,7 @@ func NewUpdaterWithVersion[T any](db *gocql.ClusterConfig, table string, mp func
 	if modelType.Kind() == reflect.Ptr {
 		modelType = modelType.Elem()
 	}
-	schema := c.CreateSchema(modelType)
+	schema := c.GetSchema(modelType)
 	return &Updater[T]{db: db, table: table, VersionIndex: version, schema: schema, Map: mp}
 }
 
@@ -41,10 +44,9 @@ func (w *Updater[T]) Write(ctx context.Context, model T) error {
 	if w.Map != nil {
 		w.Map(model)
 	}
-	session, er0 := w.db.CreateSession()
+	session, er0 := w.db.Session()
 	if er0 != nil {
 		return er0
 	}
-	defer session.Close()
-	return c.UpdateWithVersion(session, w.table, model, w.VersionIndex, w.schema)
+	return c.UpdateWithVersionContext(ctx, session, w.table, model, w.VersionIndex, w.schema)
 }
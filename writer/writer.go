@@ -9,7 +9,7 @@ import (
 )
 
 type Writer[T any] struct {
-	db           *gocql.ClusterConfig
+	db           c.SessionProvider
 	table        string
 	Map          func(T)
 	schema       *c.Schema
@@ -17,21 +17,23 @@ type Writer[T any] struct {
 }
 
 func NewWriter[T any](session *gocql.ClusterConfig, table string, modelType reflect.Type, options ...func(T)) *Writer[T] {
+	return NewWriterWithProvider[T](c.GetSessionProvider(session), table, modelType, options...)
+}
+func NewWriterWithProvider[T any](db c.SessionProvider, table string, modelType reflect.Type, options ...func(T)) *Writer[T] {
 	var mp func(T)
 	if len(options) >= 1 {
 		mp = options[0]
 	}
-	schema := c.CreateSchema(modelType)
-	return &Writer[T]{db: session, table: table, Map: mp, schema: schema}
+	schema := c.GetSchema(modelType)
+	return &Writer[T]{db: db, table: table, Map: mp, schema: schema}
 }
 func (w *Writer[T]) Write(ctx context.Context, model T) error {
 	if w.Map != nil {
 		w.Map(model)
 	}
-	session, er0 := w.db.CreateSession()
+	session, er0 := w.db.Session()
 	if er0 != nil {
 		return er0
 	}
-	defer session.Close()
-	return c.Save(session, w.table, model, w.schema)
+	return c.SaveContext(ctx, session, w.table, model, w.schema)
 }
@@ -0,0 +1,141 @@
+package cassandra_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	c "github.com/core-go/cassandra"
+	"github.com/core-go/cassandra/cqltest"
+)
+
+type versionedUser struct {
+	Id      string            `json:"id" cql:"id,partition_key"`
+	Name    string            `json:"name" cql:"name"`
+	Props   map[string]string `json:"props" cql:"props"`
+	Version int               `json:"version" cql:"version"`
+}
+
+func newVersionedWriter(t *testing.T, ses *cqltest.Session) *c.Writer {
+	t.Helper()
+	writer, err := c.NewWriterWithVersionAndProvider(ses.Provider(), "users", reflect.TypeOf(versionedUser{}), "Version")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return writer
+}
+
+func TestPatchVersionTypes(t *testing.T) {
+	tests := []struct {
+		name    string
+		version interface{}
+		cas     bool
+	}{
+		{"int", 3, true},
+		{"int64", int64(3), true},
+		{"uint", uint(3), true},
+		{"float64", float64(3), true},
+		{"json number", json.Number("3"), true},
+		{"string", "3", true},
+		{"not a number", "x", false},
+		{"nil", nil, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ses := cqltest.NewSession()
+			res, err := newVersionedWriter(t, ses).Patch(context.Background(), map[string]interface{}{"id": "1", "name": "Peter", "version": tt.version})
+			if err != nil || res != 1 {
+				t.Fatalf("Patch() = %d, %v", res, err)
+			}
+			stmt := ses.Executed()[0]
+			if got := strings.Contains(stmt.Query, " if version="); got != tt.cas {
+				t.Fatalf("Patch() = %q, condition %v, want %v", stmt.Query, got, tt.cas)
+			}
+			if tt.cas && stmt.Params[len(stmt.Params)-1] != int64(3) {
+				t.Fatalf("Patch() params = %v, want the version 3 last", stmt.Params)
+			}
+		})
+	}
+}
+
+func TestVersionConflict(t *testing.T) {
+	tests := []struct {
+		name  string
+		match string
+		write func(w *c.Writer, u versionedUser) (int64, error)
+	}{
+		{"insert", "insert into users", func(w *c.Writer, u versionedUser) (int64, error) { return w.Insert(context.Background(), &u) }},
+		{"update", "update users", func(w *c.Writer, u versionedUser) (int64, error) { return w.Update(context.Background(), &u) }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ses := cqltest.NewSession()
+			writer := newVersionedWriter(t, ses)
+			u := versionedUser{Id: "1", Name: "Peter", Version: 1}
+			res, err := tt.write(writer, u)
+			if err != nil || res != 1 {
+				t.Fatalf("applied: got %d, %v", res, err)
+			}
+			ses.On(tt.match, cqltest.Result{NotApplied: true, Columns: []string{"id", "version"}, Rows: [][]interface{}{{"1", 5}}})
+			res, err = tt.write(writer, u)
+			var conflict *c.ConflictError
+			if res != 0 || !errors.As(err, &conflict) {
+				t.Fatalf("not applied: got %d, %v, want 0 and a ConflictError", res, err)
+			}
+			if conflict.Current["version"] != 5 {
+				t.Fatalf("current row = %v", conflict.Current)
+			}
+		})
+	}
+}
+
+type uintVersionUser struct {
+	Id      string `json:"id" cql:"id,partition_key"`
+	Name    string `json:"name" cql:"name"`
+	Version uint   `json:"version" cql:"version"`
+}
+type pointerVersionUser struct {
+	Id      string `json:"id" cql:"id,partition_key"`
+	Name    string `json:"name" cql:"name"`
+	Version *int   `json:"version" cql:"version"`
+}
+
+func TestUpdateVersionTypes(t *testing.T) {
+	three := 3
+	tests := []struct {
+		name  string
+		model interface{}
+		err   error
+	}{
+		{"int", &versionedUser{Id: "1", Name: "Peter", Version: 3}, nil},
+		{"uint", &uintVersionUser{Id: "1", Name: "Peter", Version: 3}, nil},
+		{"pointer", &pointerVersionUser{Id: "1", Name: "Peter", Version: &three}, nil},
+		{"nil pointer", &pointerVersionUser{Id: "1", Name: "Peter"}, c.ErrInvalidVersion},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ses := cqltest.NewSession()
+			writer, err := c.NewWriterWithVersionAndProvider(ses.Provider(), "users", reflect.TypeOf(tt.model).Elem(), "Version")
+			if err != nil {
+				t.Fatal(err)
+			}
+			res, err := writer.Update(context.Background(), tt.model)
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("Update() = %d, %v, want %v", res, err, tt.err)
+			}
+			if tt.err != nil {
+				if len(ses.Executed()) > 0 {
+					t.Fatalf("Update() executed %v", ses.Executed())
+				}
+				return
+			}
+			stmt := ses.Executed()[0]
+			if !strings.Contains(stmt.Query, "version=4") || !strings.HasSuffix(stmt.Query, " if version=?") || stmt.Params[len(stmt.Params)-1] != int64(3) {
+				t.Fatalf("Update() = %q %v, want the version 4 set if the version is 3", stmt.Query, stmt.Params)
+			}
+		})
+	}
+}